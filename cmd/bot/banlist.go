@@ -0,0 +1,137 @@
+package main
+
+import (
+	"admin-bot/internal/config"
+	"admin-bot/internal/service"
+	"admin-bot/internal/utils"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// banListUsage 黑名单子命令用法
+const banListUsage = `用法：
+  bot banlist export [-format csv|json] [-all] [-o 文件路径]
+  bot banlist import [-dry-run] 文件路径(.csv/.json)`
+
+// runBanListCommand 执行黑名单导入导出子命令，返回进程退出码
+func runBanListCommand(cfg *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Println(banListUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "export":
+		return runBanListExport(args[1:])
+	case "import":
+		return runBanListImport(cfg, args[1:])
+	default:
+		fmt.Println(banListUsage)
+		return 2
+	}
+}
+
+// runBanListExport 导出黑名单到文件
+func runBanListExport(args []string) int {
+	fs := flag.NewFlagSet("banlist export", flag.ContinueOnError)
+	format := fs.String("format", service.BanListFormatCSV, "导出格式：csv 或 json")
+	all := fs.Bool("all", false, "包含已解除和已过期的记录")
+	output := fs.String("o", "", "输出文件路径（默认 banlist_<时间>.<格式>）")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *output == "" {
+		*output = fmt.Sprintf("banlist_%s.%s", time.Now().Format("20060102_150405"), *format)
+	}
+
+	banListService := service.NewBanListService()
+	entries, err := banListService.Export(!*all)
+	if err != nil {
+		logrus.Errorf("❌ 读取黑名单失败: %v", err)
+		return 1
+	}
+
+	data, err := banListService.Encode(entries, *format)
+	if err != nil {
+		logrus.Errorf("❌ 编码失败: %v", err)
+		return 1
+	}
+
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		logrus.Errorf("❌ 写入文件失败: %v", err)
+		return 1
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"条目数": len(entries),
+		"文件":  *output,
+	}).Info("📤 黑名单导出完成")
+	return 0
+}
+
+// runBanListImport 从文件导入黑名单并在所有授权群组中执行拉黑
+func runBanListImport(cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("banlist import", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "只预览导入结果，不写入数据库")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Println(banListUsage)
+		return 2
+	}
+	path := fs.Arg(0)
+
+	format, err := service.BanListFormatFromFilename(path)
+	if err != nil {
+		logrus.Errorf("❌ %v", err)
+		return 1
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logrus.Errorf("❌ 读取文件失败: %v", err)
+		return 1
+	}
+
+	banListService := service.NewBanListService()
+	entries, err := banListService.Decode(data, format)
+	if err != nil {
+		logrus.Errorf("❌ %v", err)
+		return 1
+	}
+	plan, err := banListService.Plan(entries)
+	if err != nil {
+		logrus.Errorf("❌ 生成导入计划失败: %v", err)
+		return 1
+	}
+
+	fmt.Println(plan.Summary())
+	if *dryRun || len(plan.ToImport) == 0 {
+		return 0
+	}
+
+	api, err := tgbotapi.NewBotAPI(cfg.Telegram.BotToken)
+	if err != nil {
+		logrus.Errorf("❌ 连接 Telegram 失败: %v", err)
+		return 1
+	}
+	groups, err := service.NewGroupService().GetAuthorizedGroups()
+	if err != nil {
+		logrus.Errorf("❌ 获取授权群组失败: %v", err)
+		return 1
+	}
+
+	result := banListService.Apply(api, utils.NewRateLimiter(cfg.System.RateLimitPerGroup),
+		plan.ToImport, groups, 0, "命令行导入", nil)
+	fmt.Printf("已导入：%d\n失败：%d\n群组拉黑次数：%d\n", result.Imported, result.Failed, result.Kicked)
+	if result.Failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -64,6 +64,13 @@ func main() {
 	}
 	logrus.Info("✅ 表结构同步完成")
 
+	// 黑名单导入导出子命令（执行后退出，不启动机器人）
+	if len(os.Args) > 1 && os.Args[1] == "banlist" {
+		code := runBanListCommand(cfg, os.Args[2:])
+		database.Close()
+		os.Exit(code)
+	}
+
 	// 创建机器人
 	logrus.Info("🤖 正在初始化 Telegram 机器人...")
 	botInstance, err := bot.NewBot(cfg)
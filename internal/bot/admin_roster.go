@@ -0,0 +1,108 @@
+package bot
+
+import (
+	"admin-bot/internal/cache"
+	"admin-bot/internal/service"
+	"admin-bot/internal/utils"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// HandleChatMemberUpdate 处理 chat_member 更新：群成员被设为或撤销管理员时更新名单缓存
+func (h *Handler) HandleChatMemberUpdate(update *tgbotapi.ChatMemberUpdated) {
+	if !h.permissionChecker.IsGroupAuthorized(update.Chat.ID) {
+		return
+	}
+
+	wasAdmin := isAdminStatus(update.OldChatMember.Status)
+	isAdmin := isAdminStatus(update.NewChatMember.Status)
+	if !wasAdmin && !isAdmin {
+		return
+	}
+
+	cache.GetAdminCache().UpdateMember(update.Chat.ID, update.NewChatMember)
+
+	if wasAdmin != isAdmin && update.NewChatMember.User != nil {
+		_, fullName := GetUserInfo(update.NewChatMember.User)
+		logrus.WithFields(logrus.Fields{
+			"群组ID": update.Chat.ID,
+			"用户ID": update.NewChatMember.User.ID,
+			"用户名":  fullName,
+			"操作人":  update.From.ID,
+			"新状态":  update.NewChatMember.Status,
+		}).Info("👥 群管理员已变化")
+	}
+}
+
+// HandleMyChatMemberUpdate 处理机器人自身在群组中的状态变化（重新获取该群的管理员名单）
+func (h *Handler) HandleMyChatMemberUpdate(update *tgbotapi.ChatMemberUpdated) {
+	if update.Chat.IsPrivate() || !h.permissionChecker.IsGroupAuthorized(update.Chat.ID) {
+		return
+	}
+	if _, err := service.RefreshAdminRoster(h.bot, update.Chat.ID); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"群组ID": update.Chat.ID,
+			"错误":   err,
+		}).Warn("⚠️ 获取群管理员名单失败")
+	}
+}
+
+// isAdminStatus 成员状态是否为管理员
+func isAdminStatus(status string) bool {
+	return status == "creator" || status == "administrator"
+}
+
+// formatAdminRosterChanges 管理员名单同步结果（Markdown）
+func formatAdminRosterChanges(changes []service.AdminRosterChange) string {
+	var sb strings.Builder
+	sb.WriteString("🔄 *管理员名单已同步*\n")
+	if len(changes) == 0 {
+		sb.WriteString("\n暂无授权群组")
+		return sb.String()
+	}
+
+	var added, removed, failed int
+	for _, change := range changes {
+		groupName := change.GroupName
+		if groupName == "" {
+			groupName = strconv.FormatInt(change.GroupID, 10)
+		}
+		sb.WriteString(fmt.Sprintf("\n*%s*（`%d`）\n", utils.EscapeMarkdown(groupName), change.GroupID))
+
+		if change.Err != nil {
+			failed++
+			sb.WriteString("❌ 获取失败：" + utils.EscapeMarkdown(change.Err.Error()) + "\n")
+			continue
+		}
+		for _, member := range change.Added {
+			sb.WriteString("➕ " + formatRosterMember(member) + "\n")
+		}
+		for _, member := range change.Removed {
+			sb.WriteString("➖ " + formatRosterMember(member) + "\n")
+		}
+		if len(change.Added) == 0 && len(change.Removed) == 0 {
+			sb.WriteString(fmt.Sprintf("无变化（%d 名管理员）\n", change.Total))
+		}
+		added += len(change.Added)
+		removed += len(change.Removed)
+	}
+
+	sb.WriteString(fmt.Sprintf("\n共 %d 个群组：新增 %d 名、移除 %d 名管理员", len(changes), added, removed))
+	if failed > 0 {
+		sb.WriteString(fmt.Sprintf("，%d 个群组获取失败", failed))
+	}
+	return sb.String()
+}
+
+// formatRosterMember 名单中的管理员，如 "张三（`123`）"
+func formatRosterMember(member tgbotapi.ChatMember) string {
+	if member.User == nil {
+		return "未知用户"
+	}
+	_, fullName := GetUserInfo(member.User)
+	return fmt.Sprintf("%s（`%d`）", utils.EscapeMarkdown(fullName), member.User.ID)
+}
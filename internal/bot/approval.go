@@ -0,0 +1,350 @@
+package bot
+
+import (
+	"admin-bot/internal/models"
+	"admin-bot/internal/service"
+	"admin-bot/internal/utils"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultApprovalTimeout 未配置审批超时时使用的有效期
+const defaultApprovalTimeout = time.Hour
+
+// maxApprovalTargets 审批消息中最多列出的目标用户数
+const maxApprovalTargets = 10
+
+// approvalRequest 等待另一名有权限的用户批准的操作
+type approvalRequest struct {
+	ID        string
+	Message   *tgbotapi.Message      // 原始命令消息（黑名单导入为导入预览消息，发起人为确认导入的作者）
+	Params    *CommandParams         // 已确定理由和时长的命令参数
+	Reason    string                 // 需要审批的原因
+	Duration  int                    // 审批人需要被允许的拉黑或禁言时长（秒，0 表示永久）
+	Plan      *service.BanImportPlan // 待审批的黑名单导入（其他操作为空）
+	Text      string                 // 审批消息内容（Markdown）
+	StatusMsg *tgbotapi.Message      // 发起人看到的状态消息，批准后在这条消息上显示执行结果
+	Posted    map[int64]int          // 已发送的审批消息（聊天ID -> 消息ID）
+	ExpireAt  time.Time
+	timer     *time.Timer
+}
+
+var (
+	approvalRequests = make(map[string]*approvalRequest)
+	approvalSeq      int64
+	approvalMutex    sync.Mutex
+)
+
+// checkApproval 检查操作是否符合审批策略（永久拉黑、拉黑超过 N 天、一次操作超过 N 名用户）
+// 需要审批时提交审批请求并返回 false，批准后再执行
+func (h *Handler) checkApproval(message *tgbotapi.Message, params *CommandParams, menuMsg *tgbotapi.Message) bool {
+	if h.approvalExempt(message.From.ID) {
+		return true
+	}
+
+	reason := h.cfg.System.Approval.Reason(message.Command() == "lh", params.Duration, len(params.TargetUsers))
+	if reason == "" {
+		return true
+	}
+	h.requestApproval(&approvalRequest{
+		Message:  message,
+		Params:   params,
+		Reason:   reason,
+		Duration: params.Duration,
+	}, menuMsg)
+	return false
+}
+
+// approvalExempt 操作人是否免审批（配置了作者免审批时作者的操作不需要审批）
+func (h *Handler) approvalExempt(userID int64) bool {
+	return h.cfg.System.Approval.ExemptAuthors && h.cfg.Telegram.IsAuthor(userID)
+}
+
+// requestApproval 将操作发到通知频道等待审批，并告知发起人（req 由调用方填好操作内容和需要审批的原因）
+func (h *Handler) requestApproval(req *approvalRequest, menuMsg *tgbotapi.Message) {
+	message, params, reason := req.Message, req.Params, req.Reason
+	timeout := time.Duration(h.cfg.System.Approval.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultApprovalTimeout
+	}
+
+	approvalMutex.Lock()
+	approvalSeq++
+	req.ID = strconv.FormatInt(approvalSeq, 36)
+	req.ExpireAt = time.Now().Add(timeout)
+	approvalMutex.Unlock()
+
+	req.Text = h.formatApprovalRequest(req)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ 批准", fmt.Sprintf("approval:%s:approve", req.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("❌ 拒绝", fmt.Sprintf("approval:%s:reject", req.ID)),
+		),
+	)
+	req.Posted = h.notificationService.SendApprovalRequest(req.Text, keyboard)
+	if len(req.Posted) == 0 {
+		text := fmt.Sprintf("❌ 该操作需要审批（%s），但审批请求发送失败，请检查通知频道设置", reason)
+		if menuMsg != nil {
+			h.editMessage(menuMsg.Chat.ID, menuMsg.MessageID, text)
+		} else {
+			h.sendReply(message.Chat.ID, message.MessageID, text)
+		}
+		return
+	}
+
+	// 告知发起人，发起人可以撤回申请
+	statusText := fmt.Sprintf("🗳 该操作需要审批（%s）\n\n已提交到通知频道，等待其他有权限的用户批准\n有效期至：%s",
+		reason, utils.FormatTimestamp(req.ExpireAt))
+	statusKeyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("↩️ 撤回申请", fmt.Sprintf("approval:%s:cancel", req.ID)),
+		),
+	)
+	if menuMsg != nil {
+		h.editMessageWithKeyboard(menuMsg.Chat.ID, menuMsg.MessageID, statusText, &statusKeyboard)
+		req.StatusMsg = menuMsg
+	} else {
+		msg := tgbotapi.NewMessage(message.Chat.ID, statusText)
+		msg.ReplyToMessageID = message.MessageID
+		msg.ReplyMarkup = statusKeyboard
+		if sent, err := h.bot.Send(msg); err != nil {
+			logrus.Errorf("Failed to send approval status: %v", err)
+		} else {
+			req.StatusMsg = &sent
+		}
+	}
+
+	approvalMutex.Lock()
+	approvalRequests[req.ID] = req
+	req.timer = time.AfterFunc(timeout, func() { h.expireApproval(req.ID) })
+	approvalMutex.Unlock()
+
+	logrus.WithFields(logrus.Fields{
+		"审批ID": req.ID,
+		"操作人":  message.From.ID,
+		"群组ID": message.Chat.ID,
+		"命令":   message.Command(),
+		"目标数":  len(params.TargetUsers),
+		"原因":   reason,
+	}).Warn("🗳 操作已提交审批")
+}
+
+// formatApprovalRequest 审批消息内容（Markdown）
+func (h *Handler) formatApprovalRequest(req *approvalRequest) string {
+	message, params := req.Message, req.Params
+
+	action := "踢出"
+	switch {
+	case req.Plan != nil:
+		action = fmt.Sprintf("导入黑名单（%d 条，最长 %s）", len(req.Plan.ToImport), utils.FormatDuration(req.Duration))
+	case message.Command() == "lh":
+		action = "拉黑（" + utils.FormatDuration(params.Duration) + "）"
+	case message.Command() == "jy":
+		action = "禁言（" + utils.FormatDuration(params.Duration) + "）"
+	case message.Command() == "extend" || message.Command() == "shorten":
+		action = "修改拉黑时长（修改后剩余 " + utils.FormatDuration(req.Duration) + "）"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🗳 *待审批操作*\n\n")
+	sb.WriteString(fmt.Sprintf("操作：%s%s\n", action, scopeSuffix(params)))
+	sb.WriteString(fmt.Sprintf("目标：%d 名用户\n", len(params.TargetUsers)))
+	for i, userID := range params.TargetUsers {
+		if i == maxApprovalTargets {
+			sb.WriteString(fmt.Sprintf("… 仅列出前 %d 名\n", maxApprovalTargets))
+			break
+		}
+		_, fullName := h.resolveTargetUser(message.Chat.ID, userID)
+		sb.WriteString(fmt.Sprintf("• %s（`%d`）\n", utils.FormatUserMention(userID, fullName), userID))
+	}
+	if params.Reason != "" {
+		sb.WriteString("理由：" + utils.EscapeMarkdown(params.Reason) + "\n")
+	}
+
+	_, operatorName := GetUserInfo(message.From)
+	sb.WriteString(fmt.Sprintf("发起人：%s\n", utils.FormatUserMention(message.From.ID, operatorName)))
+	sb.WriteString("来源：" + utils.EscapeMarkdown(GetChatTitle(message.Chat)) + "\n")
+	sb.WriteString("需要审批的原因：" + utils.EscapeMarkdown(req.Reason) + "\n")
+	sb.WriteString("有效期至：" + utils.FormatTimestamp(req.ExpireAt) + "\n\n")
+	sb.WriteString("需要发起人以外的、有相同权限的用户批准后才会执行")
+	return sb.String()
+}
+
+// getApprovalRequest 获取待审批的操作（已处理或已超时返回 nil）
+func getApprovalRequest(id string) *approvalRequest {
+	approvalMutex.Lock()
+	defer approvalMutex.Unlock()
+	return approvalRequests[id]
+}
+
+// takeApprovalRequest 取出并移除待审批的操作，返回 nil 表示已被处理（用于防止重复点击重复执行）
+func takeApprovalRequest(id string) *approvalRequest {
+	approvalMutex.Lock()
+	defer approvalMutex.Unlock()
+
+	req, exists := approvalRequests[id]
+	if !exists {
+		return nil
+	}
+	delete(approvalRequests, id)
+	if req.timer != nil {
+		req.timer.Stop()
+	}
+	return req
+}
+
+// approvalPermission 审批操作需要的权限
+func approvalPermission(command string) string {
+	switch command {
+	case "lh":
+		return models.PermBan
+	case "jy":
+		return models.PermMute
+	case "extend", "shorten":
+		return models.PermModify
+	default:
+		return models.PermKick
+	}
+}
+
+// authorizeApprover 检查审批人在发起命令的聊天中是否有执行该操作的权限（包括角色允许的最长时长）
+func (h *Handler) authorizeApprover(approver *tgbotapi.User, req *approvalRequest) (bool, string) {
+	// 黑名单导入只有作者可以执行，也只能由其他作者批准
+	if req.Plan != nil {
+		if !h.cfg.Telegram.IsAuthor(approver.ID) {
+			return false, "黑名单导入只能由作者批准"
+		}
+		return true, ""
+	}
+	message := &tgbotapi.Message{From: approver, Chat: req.Message.Chat}
+	permission := approvalPermission(req.Message.Command())
+	switch permission {
+	case models.PermKick:
+		return h.permissionChecker.Authorize(message, permission)
+	case models.PermModify:
+		// 修改时长还需要允许修改后的拉黑时长
+		if ok, denial := h.permissionChecker.Authorize(message, permission); !ok {
+			return false, denial
+		}
+		return h.permissionChecker.AuthorizeDuration(message, models.PermBan, req.Duration)
+	}
+	return h.permissionChecker.AuthorizeDuration(message, permission, req.Duration)
+}
+
+// handleApprovalCallback 处理审批回调（approval:<审批ID>:approve|reject 位于通知频道，approval:<审批ID>:cancel 由发起人撤回）
+func (h *Handler) handleApprovalCallback(callback *tgbotapi.CallbackQuery) {
+	parts := strings.Split(callback.Data, ":")
+	if len(parts) != 3 {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 无效的操作", true)
+		return
+	}
+
+	req := getApprovalRequest(parts[1])
+	if req == nil {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 审批请求已处理或已超时", true)
+		return
+	}
+
+	requesterID := req.Message.From.ID
+	switch parts[2] {
+	case "cancel":
+		if callback.From.ID != requesterID {
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 只有发起人可以撤回申请", true)
+			return
+		}
+	case "approve", "reject":
+		if callback.From.ID == requesterID {
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 不能审批自己发起的操作", true)
+			return
+		}
+		if ok, denial := h.authorizeApprover(callback.From, req); !ok {
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 您没有审批该操作的权限\n"+denial, true)
+			return
+		}
+	default:
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 无效的操作", true)
+		return
+	}
+
+	if takeApprovalRequest(req.ID) == nil {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 审批请求已处理", true)
+		return
+	}
+
+	_, deciderName := GetUserInfo(callback.From)
+	logrus.WithFields(logrus.Fields{
+		"审批ID": req.ID,
+		"发起人":  requesterID,
+		"处理人":  callback.From.ID,
+		"结果":   parts[2],
+	}).Warn("🗳 审批请求已处理")
+
+	switch parts[2] {
+	case "cancel":
+		h.closeApproval(req, "↩️ 发起人已撤回申请", "↩️ 已撤回申请，操作未执行")
+		h.notificationService.AnswerCallbackQuery(callback.ID, "已撤回", false)
+	case "reject":
+		h.closeApproval(req, fmt.Sprintf("❌ 已由 %s 拒绝", utils.FormatUserMention(callback.From.ID, deciderName)),
+			fmt.Sprintf("❌ 审批未通过：已由 %s 拒绝，操作未执行", deciderName))
+		h.notificationService.AnswerCallbackQuery(callback.ID, "已拒绝", false)
+	case "approve":
+		h.closeApproval(req, fmt.Sprintf("✅ 已由 %s 批准", utils.FormatUserMention(callback.From.ID, deciderName)), "")
+		h.notificationService.AnswerCallbackQuery(callback.ID, "✅ 已批准，正在执行", false)
+		h.executeApproved(req, deciderName)
+	}
+}
+
+// executeApproved 执行已批准的操作，结果显示在发起人的状态消息上
+func (h *Handler) executeApproved(req *approvalRequest, approverName string) {
+	message, params, statusMsg := req.Message, req.Params, req.StatusMsg
+	if req.Plan != nil {
+		h.applyBanImport(req.Plan, message.From, statusMsg)
+		return
+	}
+	switch message.Command() {
+	case "t":
+		if statusMsg != nil {
+			h.editMessage(statusMsg.Chat.ID, statusMsg.MessageID, fmt.Sprintf("✅ %s 已批准，正在执行踢出操作", approverName))
+		}
+		h.executeKick(message, params)
+	case "jy":
+		h.executeMute(message, params, statusMsg)
+	case "extend", "shorten":
+		h.executeModifyDuration(message, params, message.Command() == "shorten", statusMsg)
+	default:
+		h.executeBan(message, params, statusMsg)
+	}
+}
+
+// expireApproval 审批超时，取消操作
+func (h *Handler) expireApproval(id string) {
+	req := takeApprovalRequest(id)
+	if req == nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"审批ID": req.ID,
+		"发起人":  req.Message.From.ID,
+	}).Info("⌛ 审批请求已超时")
+
+	h.closeApproval(req, "⌛ 审批已超时，操作未执行", "⌛ 审批已超时，操作未执行")
+}
+
+// closeApproval 在审批消息上追加处理结果并移除按钮，statusText 不为空时同时更新发起人的状态消息
+func (h *Handler) closeApproval(req *approvalRequest, note, statusText string) {
+	for chatID, messageID := range req.Posted {
+		if err := h.notificationService.EditMessage(chatID, messageID, req.Text+"\n\n"+note, nil); err != nil {
+			logrus.Errorf("Failed to update approval request: %v", err)
+		}
+	}
+	if statusText != "" && req.StatusMsg != nil {
+		h.editMessage(req.StatusMsg.Chat.ID, req.StatusMsg.MessageID, statusText)
+	}
+}
@@ -0,0 +1,236 @@
+package bot
+
+import (
+	"admin-bot/internal/models"
+	"admin-bot/internal/utils"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// handleAuthorsCallback 显示作者管理页面
+func (h *Handler) handleAuthorsCallback(callback *tgbotapi.CallbackQuery) {
+	authors, err := h.authorService.GetAuthors()
+	if err != nil {
+		logrus.Errorf("Failed to get authors: %v", err)
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 获取列表失败", true)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("👑 *作者管理*\n\n")
+	sb.WriteString("作者拥有全部权限并可以使用 /config；添加和移除都需要确认，并会通知所有作者\n\n")
+
+	sb.WriteString("*配置文件中的作者*（只能通过修改配置文件移除）\n")
+	if len(h.cfg.Telegram.AuthorIDs) == 0 {
+		sb.WriteString("暂无\n")
+	}
+	for _, authorID := range h.cfg.Telegram.AuthorIDs {
+		_, fullName := h.resolveTargetUser(callback.Message.Chat.ID, authorID)
+		sb.WriteString(fmt.Sprintf("• %s（`%d`）\n", utils.EscapeMarkdown(fullName), authorID))
+	}
+
+	sb.WriteString("\n*通过面板添加的作者*\n")
+	var rows [][]tgbotapi.InlineKeyboardButton
+	count := 0
+	for _, author := range authors {
+		if h.cfg.Telegram.IsConfigAuthor(author.UserID) {
+			continue
+		}
+		count++
+		name := recordName(author.FullName, author.Username)
+		sb.WriteString(fmt.Sprintf("• %s（`%d`）\n", utils.EscapeMarkdown(name), author.UserID))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🗑 %s", name), fmt.Sprintf("config:del_author_%d", author.UserID)),
+		))
+	}
+	if count == 0 {
+		sb.WriteString("暂无\n")
+	}
+
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("➕ 添加作者", "config:add_author"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ 返回", "config:back"),
+		),
+	)
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	h.notificationService.EditMessage(callback.Message.Chat.ID, callback.Message.MessageID, sb.String(), &keyboard)
+	h.notificationService.AnswerCallbackQuery(callback.ID, "", false)
+}
+
+// handleAddAuthorCallback 处理添加作者回调
+func (h *Handler) handleAddAuthorCallback(callback *tgbotapi.CallbackQuery) {
+	setUserState(callback.From.ID, "waiting_author", nil)
+
+	text := "请发送要添加为作者的用户ID\n\n格式示例：`123456789`\n\n⚠️ 作者拥有全部权限，添加前需要再次确认\n\n发送 /cancel 取消操作"
+	h.notificationService.EditMessage(callback.Message.Chat.ID, callback.Message.MessageID, text, nil)
+	h.notificationService.AnswerCallbackQuery(callback.ID, "请发送用户ID", false)
+}
+
+// handleWaitingAuthor 处理等待输入作者ID（仅私聊），输入后需要确认
+func (h *Handler) handleWaitingAuthor(message *tgbotapi.Message) {
+	userID, err := strconv.ParseInt(strings.TrimSpace(message.Text), 10, 64)
+	if err != nil || userID <= 0 {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 无效的用户ID格式，请重新输入或发送 /cancel 取消")
+		return
+	}
+	if h.cfg.Telegram.IsAuthor(userID) {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 该用户已是作者，请重新输入或发送 /cancel 取消")
+		return
+	}
+
+	clearUserState(message.From.ID)
+
+	_, fullName := h.resolveTargetUser(message.Chat.ID, userID)
+	text := fmt.Sprintf("👑 *确认添加作者*\n\n用户：%s（`%d`）\n\n作者拥有全部权限并可以管理其他作者，确定要添加吗？",
+		utils.EscapeMarkdown(fullName), userID)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ 确认添加", fmt.Sprintf("config:confirm_add_author_%d", userID)),
+			tgbotapi.NewInlineKeyboardButtonData("❌ 取消", "config:authors"),
+		),
+	)
+	h.notificationService.SendMessageWithButtons(message.Chat.ID, text, keyboard)
+}
+
+// handleAuthorChangeCallback 处理作者变更回调
+// config:del_author_<用户ID> 显示移除确认，config:confirm_del_author_<用户ID> 确认移除，config:confirm_add_author_<用户ID> 确认添加
+func (h *Handler) handleAuthorChangeCallback(callback *tgbotapi.CallbackQuery, action string) {
+	var prefix string
+	for _, p := range []string{"del_author_", "confirm_del_author_", "confirm_add_author_"} {
+		if strings.HasPrefix(action, p) {
+			prefix = p
+		}
+	}
+	userID, err := strconv.ParseInt(strings.TrimPrefix(action, prefix), 10, 64)
+	if err != nil {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 无效的用户ID", true)
+		return
+	}
+
+	switch prefix {
+	case "del_author_":
+		if denial := h.authorRemovalDenial(userID); denial != "" {
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ "+denial, true)
+			return
+		}
+		_, fullName := h.resolveTargetUser(callback.Message.Chat.ID, userID)
+		text := fmt.Sprintf("👑 *确认移除作者*\n\n用户：%s（`%d`）\n\n移除后该用户将失去作者权限，确定要移除吗？",
+			utils.EscapeMarkdown(fullName), userID)
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("✅ 确认移除", fmt.Sprintf("config:confirm_del_author_%d", userID)),
+				tgbotapi.NewInlineKeyboardButtonData("❌ 取消", "config:authors"),
+			),
+		)
+		h.notificationService.EditMessage(callback.Message.Chat.ID, callback.Message.MessageID, text, &keyboard)
+		h.notificationService.AnswerCallbackQuery(callback.ID, "", false)
+		return
+
+	case "confirm_del_author_":
+		if denial := h.authorRemovalDenial(userID); denial != "" {
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ "+denial, true)
+			return
+		}
+		removed, err := h.authorService.RemoveAuthor(userID)
+		if err != nil || !removed {
+			logrus.Errorf("Failed to remove author: %v", err)
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 移除失败", true)
+			return
+		}
+
+	case "confirm_add_author_":
+		if h.cfg.Telegram.IsAuthor(userID) {
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 该用户已是作者", true)
+			h.handleAuthorsCallback(callback)
+			return
+		}
+		username, fullName := h.resolveTargetUser(callback.Message.Chat.ID, userID)
+		if err := h.authorService.AddAuthor(userID, username, fullName, callback.From.ID); err != nil {
+			logrus.Errorf("Failed to add author: %v", err)
+			h.notificationService.AnswerCallbackQuery(callback.ID, fmt.Sprintf("❌ 添加失败：%v", err), true)
+			return
+		}
+	}
+
+	added := prefix == "confirm_add_author_"
+	h.reloadAuthors()
+	h.auditAuthorChange(callback.From, userID, added)
+
+	result := "✅ 已移除作者"
+	if added {
+		result = "✅ 已添加作者"
+	}
+	h.notificationService.AnswerCallbackQuery(callback.ID, result, false)
+	h.handleAuthorsCallback(callback)
+}
+
+// authorRemovalDenial 返回不能移除该作者的原因，可以移除时返回空字符串
+func (h *Handler) authorRemovalDenial(userID int64) string {
+	if h.cfg.Telegram.IsConfigAuthor(userID) {
+		return "该作者在配置文件中，请修改配置文件后重启"
+	}
+	if !h.cfg.Telegram.IsAuthor(userID) {
+		return "该用户不是作者"
+	}
+	if len(h.cfg.Telegram.GetAuthorIDs()) <= 1 {
+		return "不能移除最后一位作者"
+	}
+	return ""
+}
+
+// reloadAuthors 从数据库重新加载通过面板添加的作者
+func (h *Handler) reloadAuthors() {
+	authorIDs, err := h.authorService.GetAuthorIDs()
+	if err != nil {
+		logrus.Errorf("Failed to reload authors: %v", err)
+		return
+	}
+	h.cfg.Telegram.SetManagedAuthorIDs(authorIDs)
+}
+
+// auditAuthorChange 记录作者变更的审计日志并通知所有作者（移除时也通知被移除的用户）
+func (h *Handler) auditAuthorChange(operator *tgbotapi.User, userID int64, added bool) {
+	_, operatorName := GetUserInfo(operator)
+	username, fullName := h.resolveTargetUser(operator.ID, userID)
+
+	opType := models.OpTypeAuthorRemove
+	if added {
+		opType = models.OpTypeAuthorAdd
+	}
+	caseID := h.logCase(&models.OperationLog{
+		OperationType:  opType,
+		TargetUserID:   userID,
+		TargetUsername: username,
+		GroupID:        operator.ID,
+		GroupName:      PrivateConsoleName,
+		OperatorID:     operator.ID,
+		OperatorName:   operatorName,
+	})
+
+	logrus.WithFields(logrus.Fields{
+		"操作人":  operator.ID,
+		"用户ID": userID,
+		"操作":   opType,
+		"案件编号": caseID,
+	}).Warn("👑 作者已变更")
+
+	text := fmt.Sprintf("👑 *作者变更*\n\n操作：%s\n用户：%s（`%d`）\n操作人：%s\n时间：%s",
+		models.OperationName(opType), utils.FormatUserMention(userID, fullName), userID,
+		utils.FormatUserMention(operator.ID, operatorName), utils.FormatTimestamp(time.Now()))
+	if caseID != 0 {
+		text += fmt.Sprintf("\n案件编号：#%d", caseID)
+	}
+	h.notificationService.SendToAuthors(text)
+	if !added {
+		h.notificationService.SendTextMessage(userID, text)
+	}
+}
@@ -0,0 +1,274 @@
+package bot
+
+import (
+	"admin-bot/internal/models"
+	"admin-bot/internal/service"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// maxBanListFileSize 导入文件大小上限
+const maxBanListFileSize = 5 * 1024 * 1024
+
+// handleExportBans 处理 /exportbans [csv|json] [all] 命令（仅作者私聊）
+func (h *Handler) handleExportBans(message *tgbotapi.Message) {
+	if !h.cfg.Telegram.IsAuthor(message.From.ID) {
+		return // 不回复非作者用户
+	}
+	if !message.Chat.IsPrivate() {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 此命令只能在私聊中使用")
+		return
+	}
+
+	format := service.BanListFormatCSV
+	activeOnly := true
+	for _, arg := range strings.Fields(message.CommandArguments()) {
+		switch strings.ToLower(arg) {
+		case service.BanListFormatCSV, service.BanListFormatJSON:
+			format = strings.ToLower(arg)
+		case "all":
+			activeOnly = false
+		default:
+			h.sendReply(message.Chat.ID, message.MessageID, "❌ 用法：/exportbans [csv|json] [all]")
+			return
+		}
+	}
+
+	entries, err := h.banListService.Export(activeOnly)
+	if err != nil {
+		logrus.Errorf("Failed to export ban list: %v", err)
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 读取黑名单失败")
+		return
+	}
+	data, err := h.banListService.Encode(entries, format)
+	if err != nil {
+		logrus.Errorf("Failed to encode ban list: %v", err)
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 导出失败")
+		return
+	}
+
+	filename := fmt.Sprintf("banlist_%s.%s", time.Now().Format("20060102_150405"), format)
+	doc := tgbotapi.NewDocument(message.Chat.ID, tgbotapi.FileBytes{Name: filename, Bytes: data})
+	if activeOnly {
+		doc.Caption = fmt.Sprintf("📤 生效中的全局拉黑记录：%d 条", len(entries))
+	} else {
+		doc.Caption = fmt.Sprintf("📤 全部全局拉黑记录：%d 条", len(entries))
+	}
+	if _, err := h.bot.Send(doc); err != nil {
+		logrus.Errorf("Failed to send ban list document: %v", err)
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 发送文件失败")
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"操作人": message.From.ID,
+		"格式":  format,
+		"条目数": len(entries),
+	}).Info("📤 黑名单已导出")
+}
+
+// handleImportBans 处理 /importbans 命令（仅作者私聊），等待上传文件
+func (h *Handler) handleImportBans(message *tgbotapi.Message) {
+	if !h.cfg.Telegram.IsAuthor(message.From.ID) {
+		return // 不回复非作者用户
+	}
+	if !message.Chat.IsPrivate() {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 此命令只能在私聊中使用")
+		return
+	}
+
+	setUserState(message.From.ID, "waiting_ban_import", nil)
+	h.sendReply(message.Chat.ID, message.MessageID,
+		"📥 请发送要导入的黑名单文件（.csv 或 .json）\n\n"+
+			"CSV 需包含 user_id 列，可选 username、full_name、reason、expire_at（RFC3339，为空表示永久）、status\n\n"+
+			"发送后会先显示导入预览，确认后才会执行\n\n发送 /cancel 取消操作")
+}
+
+// handleWaitingBanImport 处理上传的黑名单文件，生成导入预览
+func (h *Handler) handleWaitingBanImport(message *tgbotapi.Message) {
+	doc := message.Document
+	if doc == nil {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 请发送 .csv 或 .json 文件，或发送 /cancel 取消")
+		return
+	}
+	if doc.FileSize > maxBanListFileSize {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 文件过大（最大 5MB）")
+		return
+	}
+
+	format, err := service.BanListFormatFromFilename(doc.FileName)
+	if err != nil {
+		h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("❌ %s", err.Error()))
+		return
+	}
+
+	data, err := h.downloadFile(doc.FileID)
+	if err != nil {
+		logrus.Errorf("Failed to download ban list file: %v", err)
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 下载文件失败，请重试")
+		return
+	}
+
+	entries, err := h.banListService.Decode(data, format)
+	if err != nil {
+		h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("❌ %s", err.Error()))
+		return
+	}
+	plan, err := h.banListService.Plan(entries)
+	if err != nil {
+		logrus.Errorf("Failed to plan ban import: %v", err)
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 生成导入预览失败")
+		return
+	}
+
+	if len(plan.ToImport) == 0 {
+		clearUserState(message.From.ID)
+		h.sendReply(message.Chat.ID, message.MessageID, "📋 导入预览\n\n"+plan.Summary()+"\n\n没有需要导入的记录")
+		return
+	}
+
+	// 保存导入计划，等待确认
+	setUserState(message.From.ID, "confirm_ban_import", map[string]interface{}{
+		"plan": plan,
+	})
+
+	groups, _ := h.groupService.GetAuthorizedGroups()
+	text := fmt.Sprintf("📋 导入预览\n\n%s\n\n确认后将写入黑名单并在 %d 个授权群组中执行拉黑",
+		plan.Summary(), len(groups))
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ 确认导入", "config:confirm_import"),
+			tgbotapi.NewInlineKeyboardButtonData("❌ 取消", "config:cancel_import"),
+		),
+	)
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	msg.ReplyToMessageID = message.MessageID
+	msg.ReplyMarkup = keyboard
+	if _, err := h.bot.Send(msg); err != nil {
+		logrus.Errorf("Failed to send import preview: %v", err)
+	}
+}
+
+// handleConfirmImportCallback 确认导入黑名单
+func (h *Handler) handleConfirmImportCallback(callback *tgbotapi.CallbackQuery) {
+	state := getUserState(callback.From.ID)
+	if state == nil || state.State != "confirm_ban_import" {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 导入已过期，请重新发送 /importbans", true)
+		return
+	}
+	plan, ok := state.Data["plan"].(*service.BanImportPlan)
+	if !ok {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 导入已过期，请重新发送 /importbans", true)
+		return
+	}
+	// 先清除状态，防止重复点击重复导入
+	clearUserState(callback.From.ID)
+
+	// 导入包含永久拉黑、超过审批天数的拉黑或条目数超过批量上限时需要其他作者批准
+	if reason, seconds := h.importApproval(callback.From.ID, plan); reason != "" {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "该导入需要审批", false)
+		h.requestApproval(&approvalRequest{
+			Message:  callbackMessage(callback),
+			Params:   &CommandParams{TargetUsers: importUserIDs(plan)},
+			Reason:   reason,
+			Duration: seconds,
+			Plan:     plan,
+		}, callback.Message)
+		return
+	}
+
+	h.notificationService.AnswerCallbackQuery(callback.ID, "开始导入", false)
+	h.applyBanImport(plan, callback.From, callback.Message)
+}
+
+// applyBanImport 执行黑名单导入，进度和结果显示在 statusMsg 上
+func (h *Handler) applyBanImport(plan *service.BanImportPlan, operator *tgbotapi.User, statusMsg *tgbotapi.Message) {
+	groups, err := h.groupService.GetAuthorizedGroups()
+	if err != nil {
+		logrus.Errorf("Failed to get authorized groups: %v", err)
+		if statusMsg != nil {
+			h.editMessage(statusMsg.Chat.ID, statusMsg.MessageID, "❌ 获取授权群组失败")
+		}
+		return
+	}
+
+	if statusMsg != nil {
+		h.editMessage(statusMsg.Chat.ID, statusMsg.MessageID, fmt.Sprintf("⏳ 正在导入 %d 条记录...", len(plan.ToImport)))
+	}
+
+	_, operatorName := GetUserInfo(operator)
+	go func() {
+		result := h.banListService.Apply(h.bot, h.rateLimiter, plan.ToImport, groups, operator.ID, operatorName, h.notifyImportedBan)
+		if statusMsg != nil {
+			h.editMessage(statusMsg.Chat.ID, statusMsg.MessageID, fmt.Sprintf("✅ 黑名单导入完成\n\n已导入：%d\n失败：%d\n群组拉黑次数：%d（%d 个群组）",
+				result.Imported, result.Failed, result.Kicked, len(groups)))
+		}
+	}()
+}
+
+// notifyImportedBan 为导入的拉黑记录发送频道通知，并记录通知消息以便撤销时同步编辑
+func (h *Handler) notifyImportedBan(ban *models.Blacklist) {
+	h.notificationService.SendBanNotification(ban, "", h.undoWindow() > 0, func(chatID int64, messageID int) {
+		h.banService.SetBanNotification(ban.ID, chatID, messageID)
+	})
+}
+
+// importApproval 检查黑名单导入是否需要审批，返回需要审批的原因和导入条目中最长的拉黑时长（0 表示永久）
+func (h *Handler) importApproval(operatorID int64, plan *service.BanImportPlan) (string, int) {
+	if h.approvalExempt(operatorID) {
+		return "", 0
+	}
+
+	longest := -1
+	for _, entry := range plan.ToImport {
+		if seconds := remainingSeconds(entry.ExpireAt); longer(seconds, longest) {
+			longest = seconds
+		}
+	}
+	if longest < 0 {
+		return "", 0
+	}
+	return h.cfg.System.Approval.Reason(true, longest, len(plan.ToImport)), longest
+}
+
+// importUserIDs 导入条目的用户ID（用于审批消息中列出目标用户）
+func importUserIDs(plan *service.BanImportPlan) []int64 {
+	userIDs := make([]int64, 0, len(plan.ToImport))
+	for _, entry := range plan.ToImport {
+		userIDs = append(userIDs, entry.UserID)
+	}
+	return userIDs
+}
+
+// handleCancelImportCallback 取消导入黑名单
+func (h *Handler) handleCancelImportCallback(callback *tgbotapi.CallbackQuery) {
+	clearUserState(callback.From.ID)
+	h.notificationService.AnswerCallbackQuery(callback.ID, "已取消", false)
+	h.editMessage(callback.Message.Chat.ID, callback.Message.MessageID, "已取消导入")
+}
+
+// downloadFile 下载用户上传的文件
+func (h *Handler) downloadFile(fileID string) ([]byte, error) {
+	url, err := h.bot.GetFileDirectURL(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxBanListFileSize+1))
+}
@@ -41,6 +41,12 @@ func NewBot(cfg *config.Config) (*Bot, error) {
 	logrus.Info("💾 正在初始化授权缓存...")
 	cache.InitAuthCache(30 * time.Minute)
 
+	// 初始化最近消息缓存（每群 500 条，机器人只能删除 48 小时内的消息）
+	cache.InitMessageCache(500, 48*time.Hour)
+
+	// 初始化群管理员名单缓存（定时同步和 chat_member 更新会保持名单最新，过期后在权限检查时重新获取）
+	cache.InitAdminCache(time.Hour)
+
 	// 创建服务
 	banService := service.NewBanService()
 	muteService := service.NewMuteService()
@@ -48,9 +54,15 @@ func NewBot(cfg *config.Config) (*Bot, error) {
 	adminService := service.NewAdminService()
 	logService := service.NewLogService()
 	userCacheService := service.NewUserCacheService()
+	banListService := service.NewBanListService()
+	reasonPresetService := service.NewReasonPresetService()
+	roleService := service.NewRoleService()
+	moderatorService := service.NewModeratorService()
+	authorService := service.NewAuthorService()
 	notificationService := service.NewNotificationService(bot,
 		cfg.Telegram.NotificationChannelID,
-		cfg.Telegram.AuthorIDs)
+		cfg.Telegram.GetAuthorIDs)
+	notificationService.SetEvidenceChatID(cfg.Telegram.EvidenceChatID)
 
 	// 预加载授权群组到缓存
 	logrus.Info("🔄 正在预加载授权群组...")
@@ -61,17 +73,34 @@ func NewBot(cfg *config.Config) (*Bot, error) {
 		logrus.Info("✅ 授权缓存预加载完成")
 	}
 
+	// 写入默认预设理由
+	if err := reasonPresetService.EnsureDefaults(); err != nil {
+		logrus.Warnf("⚠️  初始化预设理由失败: %v", err)
+	}
+
+	// 加载通过 /config 添加的作者
+	if authorIDs, err := authorService.GetAuthorIDs(); err != nil {
+		logrus.Warnf("⚠️  加载作者列表失败: %v（仅使用配置文件中的作者）", err)
+	} else {
+		cfg.Telegram.SetManagedAuthorIDs(authorIDs)
+	}
+
+	// 写入默认角色权限
+	if err := roleService.EnsureDefaults(); err != nil {
+		logrus.Warnf("⚠️  初始化角色权限失败: %v", err)
+	}
+
 	// 创建权限检查器
-	permissionChecker := NewPermissionChecker(cfg, adminService, groupService, bot)
+	permissionChecker := NewPermissionChecker(cfg, adminService, groupService, roleService, moderatorService, bot)
 
 	// 创建处理器
 	handler := NewHandler(bot, cfg, permissionChecker,
 		banService, muteService, groupService, adminService,
-		logService, notificationService, userCacheService)
+		logService, notificationService, userCacheService, banListService, reasonPresetService, roleService, moderatorService, authorService)
 
 	// 创建调度器
 	taskScheduler := scheduler.NewScheduler(banService, muteService,
-		groupService, notificationService, bot,
+		groupService, adminService, notificationService, bot,
 		cfg.System.RateLimitPerGroup)
 
 	return &Bot{
@@ -114,7 +143,7 @@ func preloadAuthCache(groupService *service.GroupService, notificationChannelID
 func (b *Bot) Start() error {
 	// 启动调度器
 	logrus.Info("⏰ 正在启动定时任务...")
-	err := b.scheduler.Start(b.cfg.Scheduler.CheckExpireInterval)
+	err := b.scheduler.Start(b.cfg.Scheduler.CheckExpireInterval, b.cfg.Scheduler.AdminSyncInterval)
 	if err != nil {
 		return err
 	}
@@ -123,6 +152,8 @@ func (b *Bot) Start() error {
 	// 配置更新 - 使用 -1 来只获取新消息，忽略历史消息
 	u := tgbotapi.NewUpdate(-1)
 	u.Timeout = 60
+	// chat_member 需要显式订阅，用于及时更新群管理员名单
+	u.AllowedUpdates = []string{"message", "callback_query", "chat_member", "my_chat_member"}
 
 	updates := b.api.GetUpdatesChan(u)
 
@@ -163,6 +194,11 @@ func (b *Bot) handleUpdate(update tgbotapi.Update) {
 			b.handler.CacheUserInfo(update.Message.From)
 		}
 
+		// 记录群组消息（用于清理目标用户最近的消息）
+		if update.Message.Chat.IsGroup() || update.Message.Chat.IsSuperGroup() {
+			b.handler.TrackMessage(update.Message)
+		}
+
 		// 检查新成员
 		if len(update.Message.NewChatMembers) > 0 {
 			// 检查是否有机器人自己被添加
@@ -180,6 +216,11 @@ func (b *Bot) handleUpdate(update tgbotapi.Update) {
 			b.handler.HandleTextMessage(update.Message)
 		}
 
+		// 执行"禁止链接"禁言模式
+		if update.Message.Chat.IsGroup() || update.Message.Chat.IsSuperGroup() {
+			b.handler.EnforceLinkMute(update.Message)
+		}
+
 		// 检查是否为未授权群组（在处理完命令后再检查，避免阻止命令执行）
 		if update.Message.Chat.IsGroup() || update.Message.Chat.IsSuperGroup() {
 			b.handler.CheckUnauthorizedGroup(update.Message)
@@ -190,6 +231,14 @@ func (b *Bot) handleUpdate(update tgbotapi.Update) {
 	if update.CallbackQuery != nil {
 		b.handler.HandleCallback(update.CallbackQuery)
 	}
+
+	// 处理群成员状态变化（更新群管理员名单）
+	if update.ChatMember != nil {
+		b.handler.HandleChatMemberUpdate(update.ChatMember)
+	}
+	if update.MyChatMember != nil {
+		b.handler.HandleMyChatMemberUpdate(update.MyChatMember)
+	}
 }
 
 // GetAPI 获取Bot API
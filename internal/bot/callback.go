@@ -1,11 +1,14 @@
 package bot
 
 import (
+	"admin-bot/internal/models"
+	"admin-bot/internal/service"
 	"admin-bot/internal/utils"
 	"fmt"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/sirupsen/logrus"
@@ -28,6 +31,54 @@ func (h *Handler) HandleCallback(callback *tgbotapi.CallbackQuery) {
 		return
 	}
 
+	// 禁言模式选择由发起命令的操作人处理
+	if strings.HasPrefix(callback.Data, "mute:") {
+		h.handleMuteModeCallback(callback)
+		return
+	}
+
+	// 预设理由和时长选择由发起命令的操作人处理
+	if strings.HasPrefix(callback.Data, "preset:") {
+		h.handlePresetCallback(callback)
+		return
+	}
+
+	// 受保护用户的确认由发起命令的作者处理
+	if strings.HasPrefix(callback.Data, "protect:") {
+		h.handleProtectCallback(callback)
+		return
+	}
+
+	// 频道通知上的按钮由作者和全局管理员处理
+	if strings.HasPrefix(callback.Data, "record:") {
+		h.handleRecordCallback(callback)
+		return
+	}
+
+	// 拉黑/禁言列表由作者和全局管理员处理
+	if strings.HasPrefix(callback.Data, "list:") {
+		h.handleRecordListCallback(callback)
+		return
+	}
+
+	// 用户档案翻页由有权限查看档案的人处理
+	if strings.HasPrefix(callback.Data, "info:") {
+		h.handleInfoCallback(callback)
+		return
+	}
+
+	// 撤销按钮由操作人本人、作者和全局管理员处理
+	if strings.HasPrefix(callback.Data, "undo:") {
+		h.handleUndoCallback(callback)
+		return
+	}
+
+	// 待审批的操作由发起人以外的有权限用户批准或拒绝，发起人可以撤回
+	if strings.HasPrefix(callback.Data, "approval:") {
+		h.handleApprovalCallback(callback)
+		return
+	}
+
 	// 只有作者可以使用配置功能
 	if !h.cfg.Telegram.IsAuthor(callback.From.ID) {
 		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 您没有权限", true)
@@ -73,18 +124,97 @@ func (h *Handler) HandleCallback(callback *tgbotapi.CallbackQuery) {
 
 	case "close":
 		h.handleCloseCallback(callback)
+	case "reason_presets":
+		h.handleReasonPresetsCallback(callback)
+	case "add_reason":
+		h.handleAddReasonCallback(callback)
+	case "roles":
+		h.handleRolesCallback(callback)
+	case "add_role":
+		h.handleAddRoleCallback(callback)
+	case "set_role_perm":
+		h.handleSetRolePermCallback(callback)
+	case "moderators":
+		h.handleModeratorsCallback(callback)
+	case "add_mod":
+		h.handleAddModCallback(callback)
+	case "group_settings":
+		h.handleGroupSettingsCallback(callback)
+	case "authors":
+		h.handleAuthorsCallback(callback)
+	case "add_author":
+		h.handleAddAuthorCallback(callback)
+	case "confirm_import":
+		h.handleConfirmImportCallback(callback)
+	case "cancel_import":
+		h.handleCancelImportCallback(callback)
 	default:
 		// 处理删除操作的回调
 		if strings.HasPrefix(action, "confirm_del_group_") {
 			h.handleConfirmDelGroup(callback, action)
 		} else if strings.HasPrefix(action, "confirm_del_admin_") {
 			h.handleConfirmDelAdmin(callback, action)
+		} else if strings.HasPrefix(action, "del_reason_") {
+			h.handleDelReasonCallback(callback, action)
+		} else if strings.HasPrefix(action, "del_role_") {
+			h.handleDelRoleCallback(callback, action)
+		} else if strings.HasPrefix(action, "del_mod_") {
+			h.handleDelModCallback(callback, action)
+		} else if strings.HasPrefix(action, "del_author_") || strings.HasPrefix(action, "confirm_del_author_") ||
+			strings.HasPrefix(action, "confirm_add_author_") {
+			h.handleAuthorChangeCallback(callback, action)
+		} else if strings.HasPrefix(action, "gs_") {
+			h.handleGroupSettingCallback(callback, action)
 		} else {
 			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 无效的操作", true)
 		}
 	}
 }
 
+// handleMuteModeCallback 处理禁言模式选择回调（mute:<操作ID>:<模式>）
+func (h *Handler) handleMuteModeCallback(callback *tgbotapi.CallbackQuery) {
+	parts := strings.Split(callback.Data, ":")
+	if len(parts) != 3 {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 无效的操作", true)
+		return
+	}
+	actionID, mode := parts[1], parts[2]
+
+	action := getPendingAction(actionID)
+	if action == nil {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 操作已过期，请重新发送命令", true)
+		return
+	}
+
+	// 只有发起命令的操作人可以选择
+	if action.Message.From.ID != callback.From.ID {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 只有发起命令的管理员可以选择", true)
+		return
+	}
+
+	if mode != "cancel" && !models.IsValidMuteMode(mode) {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 无效的禁言模式", true)
+		return
+	}
+
+	// 防止重复点击重复执行
+	if !removePendingAction(actionID) {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 操作已处理", true)
+		return
+	}
+
+	if mode == "cancel" {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "已取消", false)
+		h.editMessage(callback.Message.Chat.ID, callback.Message.MessageID, "已取消禁言操作")
+		return
+	}
+
+	h.notificationService.AnswerCallbackQuery(callback.ID, models.MuteModeName(mode), false)
+	action.Params.MuteMode = mode
+	action.Params.ChooseMode = false
+	h.nextModerationStep(action.Message, action.Params, callback.Message)
+}
+
 // handleAddGroupCallback 处理添加授权群组回调
 func (h *Handler) handleAddGroupCallback(callback *tgbotapi.CallbackQuery) {
 	// 设置用户状态
@@ -163,7 +293,7 @@ func (h *Handler) handleAddAdminCallback(callback *tgbotapi.CallbackQuery) {
 	// 设置用户状态
 	setUserState(callback.From.ID, "waiting_admin_id", nil)
 
-	text := "请发送要添加的管理员用户ID\n\n格式示例：`123456789`\n\n发送 /cancel 取消操作"
+	text := "请发送要添加的管理员用户ID，可以加上有效期和授权理由\n\n格式示例：\n`123456789` 永久\n`123456789 3d 活动期间协助管理` 3 天后自动移除\n\n发送 /cancel 取消操作"
 	h.notificationService.EditMessage(callback.Message.Chat.ID, callback.Message.MessageID, text, nil)
 	h.notificationService.AnswerCallbackQuery(callback.ID, "请发送用户ID", false)
 }
@@ -317,7 +447,24 @@ func (h *Handler) handleListAdminsCallback(callback *tgbotapi.CallbackQuery) {
 			if adminName == "" {
 				adminName = fmt.Sprintf("用户 %d", admin.UserID)
 			}
-			text.WriteString(fmt.Sprintf("%d\\. %s\n   ID: `%d`\n\n", i+1, utils.EscapeMarkdown(adminName), admin.UserID))
+			text.WriteString(fmt.Sprintf("%d\\. %s\n   ID: `%d`\n", i+1, utils.EscapeMarkdown(adminName), admin.UserID))
+
+			// 授权人、理由和有效期
+			grantor := admin.AddedByName
+			if grantor == "" {
+				grantor = fmt.Sprintf("用户 %d", admin.AddedBy)
+			}
+			text.WriteString(fmt.Sprintf("   授权人: %s（%s）\n", utils.EscapeMarkdown(grantor), utils.FormatTimestamp(admin.AddedAt)))
+			if admin.Reason != "" {
+				text.WriteString("   理由: " + utils.EscapeMarkdown(admin.Reason) + "\n")
+			}
+			if admin.ExpireAt == nil {
+				text.WriteString("   有效期: 永久\n\n")
+			} else if admin.IsExpired() {
+				text.WriteString("   有效期: 已到期，等待自动移除\n\n")
+			} else {
+				text.WriteString(fmt.Sprintf("   有效期至: %s（剩余 %s）\n\n", utils.FormatTimestamp(*admin.ExpireAt), utils.FormatRemainingTime(*admin.ExpireAt)))
+			}
 		}
 	}
 
@@ -331,24 +478,41 @@ func (h *Handler) handleListAdminsCallback(callback *tgbotapi.CallbackQuery) {
 	h.notificationService.AnswerCallbackQuery(callback.ID, "", false)
 }
 
-// handleSyncAdminsCallback 处理同步管理员权限回调
+// handleSyncAdminsCallback 处理同步管理员权限回调：重新获取所有授权群组的管理员名单并显示变化
 func (h *Handler) handleSyncAdminsCallback(callback *tgbotapi.CallbackQuery) {
-	// 这里可以实现重新同步所有群组管理员的逻辑
-	h.notificationService.AnswerCallbackQuery(callback.ID, "✅ 管理员权限已同步", true)
-	h.showConfigMenu(callback.Message.Chat.ID)
+	changes, err := service.RefreshAllAdminRosters(h.bot, h.groupService)
+	if err != nil {
+		logrus.Errorf("Failed to sync admin rosters: %v", err)
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 同步失败", true)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"操作人": callback.From.ID,
+		"群组数": len(changes),
+	}).Info("🔄 已同步群管理员名单")
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔄 再次同步", "config:sync_admins"),
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ 返回", "config:back"),
+		),
+	)
+	h.notificationService.EditMessage(callback.Message.Chat.ID, callback.Message.MessageID, formatAdminRosterChanges(changes), &keyboard)
+	h.notificationService.AnswerCallbackQuery(callback.ID, "✅ 管理员名单已同步", false)
 }
 
 // handleDisableAdminsCallback 处理关闭群管权限回调
 func (h *Handler) handleDisableAdminsCallback(callback *tgbotapi.CallbackQuery) {
 	h.cfg.System.AdminEnabled = false
-	h.notificationService.AnswerCallbackQuery(callback.ID, "✅ 已关闭群管权限", true)
+	h.notificationService.AnswerCallbackQuery(callback.ID, "✅ 已关闭群管权限（在群组设置中单独设置过的群组除外）", true)
 	h.showConfigMenu(callback.Message.Chat.ID)
 }
 
 // handleEnableAdminsCallback 处理开启群管权限回调
 func (h *Handler) handleEnableAdminsCallback(callback *tgbotapi.CallbackQuery) {
 	h.cfg.System.AdminEnabled = true
-	h.notificationService.AnswerCallbackQuery(callback.ID, "✅ 已开启群管权限", true)
+	h.notificationService.AnswerCallbackQuery(callback.ID, "✅ 已开启群管权限（在群组设置中单独设置过的群组除外）", true)
 	h.showConfigMenu(callback.Message.Chat.ID)
 }
 
@@ -385,6 +549,18 @@ func (h *Handler) HandleTextMessage(message *tgbotapi.Message) {
 		h.handleWaitingAdminID(message)
 	case "waiting_channel_id":
 		h.handleWaitingChannelID(message)
+	case "waiting_ban_import":
+		h.handleWaitingBanImport(message)
+	case "waiting_reason_preset":
+		h.handleWaitingReasonPreset(message)
+	case "waiting_role":
+		h.handleWaitingRole(message)
+	case "waiting_role_perm":
+		h.handleWaitingRolePerm(message)
+	case "waiting_moderator":
+		h.handleWaitingModerator(message)
+	case "waiting_author":
+		h.handleWaitingAuthor(message)
 	}
 }
 
@@ -480,19 +656,38 @@ func (h *Handler) handleWaitingAdminID(message *tgbotapi.Message) {
 		return
 	}
 
-	// 解析用户ID
-	userID, err := strconv.ParseInt(strings.TrimSpace(message.Text), 10, 64)
+	// 解析用户ID、可选的有效期和授权理由：用户ID [时长] [理由]
+	parts := strings.Fields(message.Text)
+	if len(parts) == 0 {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 请发送用户ID，或发送 /cancel 取消")
+		return
+	}
+	userID, err := strconv.ParseInt(parts[0], 10, 64)
 	if err != nil {
 		h.sendReply(message.Chat.ID, message.MessageID, "❌ 无效的用户ID格式，请重新输入或发送 /cancel 取消")
 		return
 	}
+	var expireAt *time.Time
+	parts = parts[1:]
+	if len(parts) > 0 && utils.IsPermanentKeyword(parts[0]) {
+		parts = parts[1:]
+	} else if len(parts) > 0 && utils.IsDurationString(parts[0]) {
+		seconds, err := utils.ParseDuration(parts[0])
+		if err != nil || seconds <= 0 {
+			h.sendReply(message.Chat.ID, message.MessageID, "❌ 无效的有效期，请使用如 12h、3d、1w 的格式")
+			return
+		}
+		expireAt = utils.CalculateExpireTime(seconds)
+		parts = parts[1:]
+	}
+	reason := strings.Join(parts, " ")
 
-	// 获取用户信息（尝试）
-	username := fmt.Sprintf("user_%d", userID)
-	fullName := fmt.Sprintf("用户 %d", userID)
+	// 获取用户信息（用户缓存或 getChat）
+	username, fullName := h.resolveTargetUser(message.Chat.ID, userID)
+	_, operatorName := GetUserInfo(message.From)
 
 	// 添加到数据库
-	err = h.adminService.AddGlobalAdmin(userID, username, fullName, message.From.ID)
+	err = h.adminService.AddGlobalAdmin(userID, username, fullName, message.From.ID, operatorName, reason, expireAt)
 	if err != nil {
 		logrus.Errorf("Failed to add global admin: %v", err)
 		h.sendReply(message.Chat.ID, message.MessageID, "❌ 添加失败，可能该用户已是管理员")
@@ -502,7 +697,18 @@ func (h *Handler) handleWaitingAdminID(message *tgbotapi.Message) {
 	// 清除用户状态
 	clearUserState(message.From.ID)
 
-	h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("✅ 已添加全局管理员\n\nID：`%d`", userID))
+	logrus.WithFields(logrus.Fields{
+		"操作人":  message.From.ID,
+		"用户ID": userID,
+		"到期时间": utils.FormatExpireAt(expireAt),
+		"理由":   reason,
+	}).Info("👤 已添加全局管理员")
+
+	text := fmt.Sprintf("✅ 已添加全局管理员\n\n用户：%s\nID：`%d`\n有效期至：%s", fullName, userID, utils.FormatExpireAt(expireAt))
+	if reason != "" {
+		text += "\n理由：" + reason
+	}
+	h.sendReply(message.Chat.ID, message.MessageID, text)
 }
 
 // handleWaitingChannelID 处理等待通知频道ID输入（仅私聊）
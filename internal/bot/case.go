@@ -0,0 +1,197 @@
+package bot
+
+import (
+	"admin-bot/internal/models"
+	"admin-bot/internal/utils"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// handleCase 处理 /case <编号> [备注] 命令：查看案件详情，带备注时为案件添加备注
+// 在群组中只能查看和备注本群的案件，详情（包括证据和备注）私聊发送，不在群内公开
+func (h *Handler) handleCase(message *tgbotapi.Message) {
+	if !h.authorize(message, models.PermCase) {
+		return
+	}
+
+	args := strings.SplitN(strings.TrimSpace(message.CommandArguments()), " ", 2)
+	caseID, err := strconv.ParseInt(strings.TrimPrefix(args[0], "#"), 10, 64)
+	if err != nil || caseID <= 0 {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 用法：/case 编号 [备注]")
+		return
+	}
+
+	inGroup := !message.Chat.IsPrivate()
+	log, err := h.logService.GetCase(caseID)
+	if err == nil && inGroup && log.GroupID != message.Chat.ID {
+		// 其他群组的案件按不存在处理，不透露案件是否存在
+		err = fmt.Errorf("case %d belongs to group %d", caseID, log.GroupID)
+	}
+	if err != nil {
+		text := fmt.Sprintf("❌ 案件 #%d 不存在", caseID)
+		if inGroup {
+			text = fmt.Sprintf("❌ 本群没有案件 #%d，其他群组的案件请在私聊中查看", caseID)
+		}
+		h.sendReply(message.Chat.ID, message.MessageID, text)
+		return
+	}
+
+	// 带备注时添加备注
+	if len(args) == 2 && strings.TrimSpace(args[1]) != "" {
+		_, authorName := GetUserInfo(message.From)
+		if err := h.logService.AddCaseNote(caseID, message.From.ID, authorName, strings.TrimSpace(args[1])); err != nil {
+			logrus.Errorf("Failed to add case note: %v", err)
+			h.sendReply(message.Chat.ID, message.MessageID, "❌ 添加备注失败")
+			return
+		}
+		logrus.WithFields(logrus.Fields{
+			"案件编号": caseID,
+			"操作人":  message.From.ID,
+		}).Info("📝 已添加案件备注")
+		h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("✅ 已为案件 #%d 添加备注", caseID))
+		return
+	}
+
+	if !inGroup {
+		h.sendReply(message.Chat.ID, message.MessageID, h.formatCase(log))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.From.ID, h.formatCase(log))
+	msg.DisableWebPagePreview = true
+	if _, err := h.bot.Send(msg); err != nil {
+		logrus.Warnf("Failed to send case to user %d: %v", message.From.ID, err)
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 无法私聊发送案件详情，请先私聊机器人发送 /start")
+		return
+	}
+	h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("📁 已私聊发送案件 #%d 的详情", caseID))
+}
+
+// formatCase 生成案件详情（纯文本）
+func (h *Handler) formatCase(log *models.OperationLog) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📁 案件 %s\n\n", log.CaseNumber()))
+	sb.WriteString(fmt.Sprintf("类型：%s\n", models.OperationName(log.OperationType)))
+
+	target := strconv.FormatInt(log.TargetUserID, 10)
+	if log.TargetUsername != "" {
+		target = fmt.Sprintf("@%s（%d）", log.TargetUsername, log.TargetUserID)
+	}
+	sb.WriteString(fmt.Sprintf("目标：%s\n", target))
+	sb.WriteString(fmt.Sprintf("操作人：%s（%d）\n", log.OperatorName, log.OperatorID))
+	sb.WriteString(fmt.Sprintf("群组：%s（%d）\n", log.GroupName, log.GroupID))
+
+	switch {
+	case log.Scope == models.ScopeLocal:
+		sb.WriteString("范围：仅本群\n")
+	case log.GroupCount > 0:
+		sb.WriteString(fmt.Sprintf("范围：全部授权群组（成功 %d 个）\n", log.GroupCount))
+	case log.Scope == models.ScopeGlobal:
+		sb.WriteString("范围：全部授权群组\n")
+	}
+
+	if log.Reason != "" {
+		if log.ReasonCode != "" {
+			sb.WriteString(fmt.Sprintf("理由：%s（%s）\n", log.Reason, log.ReasonCode))
+		} else {
+			sb.WriteString(fmt.Sprintf("理由：%s\n", log.Reason))
+		}
+	}
+	if log.Duration != nil && (log.OperationType == models.OpTypeBan || log.OperationType == models.OpTypeMute) {
+		sb.WriteString(fmt.Sprintf("时长：%s\n", utils.FormatDuration(*log.Duration)))
+	}
+
+	if log.Evidence.HasEvidence() {
+		if link := log.Evidence.ArchiveLink(); link != "" {
+			sb.WriteString(fmt.Sprintf("证据：%s\n", link))
+		}
+		if log.Evidence.Text != "" {
+			sb.WriteString(fmt.Sprintf("证据内容：%s\n", utils.TruncateString(log.Evidence.Text, 200)))
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("状态：%s\n", h.caseStatus(log)))
+	sb.WriteString(fmt.Sprintf("时间：%s\n", utils.FormatTimestamp(log.CreatedAt)))
+
+	notes, err := h.logService.GetCaseNotes(log.ID)
+	if err != nil {
+		logrus.Errorf("Failed to get case notes: %v", err)
+	}
+	if len(notes) > 0 {
+		sb.WriteString("\n备注：\n")
+		for _, note := range notes {
+			sb.WriteString(fmt.Sprintf("[%s] %s：%s\n", utils.FormatTimestamp(note.CreatedAt), note.AuthorName, note.Note))
+		}
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// caseStatus 案件当前状态（拉黑和禁言根据关联记录判断）
+func (h *Handler) caseStatus(log *models.OperationLog) string {
+	if log.Success != 1 {
+		return "执行失败：" + log.ErrorMsg
+	}
+	if log.IsReverted() {
+		return fmt.Sprintf("已撤销（%s，%s）", utils.FormatTimestamp(*log.RevertedAt), log.RevertedByName)
+	}
+	if log.RecordID == 0 || (log.OperationType != models.OpTypeBan && log.OperationType != models.OpTypeMute) {
+		return "已执行"
+	}
+
+	switch log.OperationType {
+	case models.OpTypeBan:
+		ban, err := h.banService.GetBan(log.RecordID)
+		if err != nil {
+			return "记录不存在"
+		}
+		if ban.CaseID != 0 && ban.CaseID != log.ID {
+			return fmt.Sprintf("已被案件 #%d 更新", ban.CaseID)
+		}
+		return recordStatus(ban.Status, ban.ExpireAt, ban.UnbanAt, ban.UnbanReason)
+	default:
+		mute, err := h.muteService.GetMute(log.RecordID)
+		if err != nil {
+			return "记录不存在"
+		}
+		if mute.CaseID != 0 && mute.CaseID != log.ID {
+			return fmt.Sprintf("已被案件 #%d 更新", mute.CaseID)
+		}
+		return recordStatus(mute.Status, mute.ExpireAt, mute.UnmuteAt, mute.UnmuteReason)
+	}
+}
+
+// recordStatus 拉黑/禁言记录的状态说明
+func recordStatus(status int8, expireAt, liftedAt *time.Time, liftReason string) string {
+	switch {
+	case status == models.RecordStatusReverted:
+		return "已撤销"
+	case status == models.RecordStatusReplaced:
+		text := "已被新的处罚替代"
+		if liftedAt != nil {
+			text += "（" + utils.FormatTimestamp(*liftedAt) + "）"
+		}
+		return text
+	case status != models.RecordStatusActive:
+		text := "已解除"
+		if liftedAt != nil {
+			text += "（" + utils.FormatTimestamp(*liftedAt)
+			if liftReason != "" {
+				text += "，" + liftReason
+			}
+			text += "）"
+		}
+		return text
+	case expireAt == nil:
+		return "生效中（永久）"
+	case time.Now().After(*expireAt):
+		return "已到期"
+	default:
+		return "生效中（剩余 " + utils.FormatRemainingTime(*expireAt) + "）"
+	}
+}
@@ -0,0 +1,166 @@
+package bot
+
+import (
+	"admin-bot/internal/models"
+	"admin-bot/internal/utils"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// handleGroupSettingsCallback 显示群组设置的群组列表
+func (h *Handler) handleGroupSettingsCallback(callback *tgbotapi.CallbackQuery) {
+	groups, err := h.groupService.GetAuthorizedGroups()
+	if err != nil {
+		logrus.Errorf("Failed to get authorized groups: %v", err)
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 获取列表失败", true)
+		return
+	}
+
+	text := "🛡 *群组设置*\n\n请选择要设置的群组："
+	if len(groups) == 0 {
+		text = "🛡 *群组设置*\n\n暂无授权群组"
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, group := range groups {
+		name := group.GroupName
+		if name == "" {
+			name = strconv.FormatInt(group.GroupID, 10)
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(utils.TruncateString(name, 40), fmt.Sprintf("config:gs_%d", group.GroupID)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ 返回", "config:back"),
+	))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	h.notificationService.EditMessage(callback.Message.Chat.ID, callback.Message.MessageID, text, &keyboard)
+	h.notificationService.AnswerCallbackQuery(callback.ID, "", false)
+}
+
+// handleGroupSettingCallback 处理单个群组的设置（config:gs_<群组ID> 显示设置，config:gs_rights_<群组ID> 切换权限检查方式，
+// config:gs_admins_<inherit|enabled|disabled>_<群组ID> 设置本群是否启用群管理员权限）
+func (h *Handler) handleGroupSettingCallback(callback *tgbotapi.CallbackQuery, action string) {
+	setting, adminMode := "", ""
+	idText := strings.TrimPrefix(action, "gs_")
+	if strings.HasPrefix(idText, "rights_") {
+		setting = "rights"
+		idText = strings.TrimPrefix(idText, "rights_")
+	} else if strings.HasPrefix(idText, "admins_") {
+		setting = "admins"
+		adminMode, idText, _ = strings.Cut(strings.TrimPrefix(idText, "admins_"), "_")
+	}
+	groupID, err := strconv.ParseInt(idText, 10, 64)
+	if err != nil {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 无效的群组", true)
+		return
+	}
+
+	group, err := h.groupService.GetAuthorizedGroup(groupID)
+	if err != nil || group == nil {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 该群组未授权", true)
+		return
+	}
+
+	if setting == "rights" {
+		mode := models.AdminRightsAny
+		if !group.RequiresAdminRights() {
+			mode = models.AdminRightsStrict
+		}
+		if err := h.groupService.SetAdminRights(groupID, mode); err != nil {
+			logrus.Errorf("Failed to set admin rights mode: %v", err)
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 设置失败", true)
+			return
+		}
+		group.AdminRights = mode
+
+		logrus.WithFields(logrus.Fields{
+			"操作人":  callback.From.ID,
+			"群组ID": groupID,
+			"检查方式": mode,
+		}).Info("🛡 已修改群管理员权限检查方式")
+	}
+
+	if setting == "admins" {
+		if err := h.groupService.SetAdminEnabled(groupID, adminMode); err != nil {
+			logrus.Errorf("Failed to set admin enabled mode: %v", err)
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 设置失败", true)
+			return
+		}
+		group.AdminEnabled = adminMode
+
+		logrus.WithFields(logrus.Fields{
+			"操作人":  callback.From.ID,
+			"群组ID": groupID,
+			"群管权限": adminMode,
+		}).Info("🛡 已修改本群的群管理员权限")
+	}
+
+	h.showGroupSettings(callback, group)
+}
+
+// showGroupSettings 显示群组设置页面
+func (h *Handler) showGroupSettings(callback *tgbotapi.CallbackQuery, group *models.AuthorizedGroup) {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🛡 *群组设置*：%s（`%d`）\n\n", utils.EscapeMarkdown(group.GroupName), group.GroupID))
+
+	globalState := "关闭"
+	if h.cfg.System.AdminEnabled {
+		globalState = "开启"
+	}
+	sb.WriteString("*群管理员权限：*")
+	switch group.AdminEnabled {
+	case models.AdminEnabledOn:
+		sb.WriteString("始终启用\n")
+	case models.AdminEnabledOff:
+		sb.WriteString("始终关闭\n")
+	default:
+		sb.WriteString(fmt.Sprintf("跟随全局设置（当前%s）\n", globalState))
+	}
+	if !group.AdminsEnabled(h.cfg.System.AdminEnabled) {
+		sb.WriteString("本群的 Telegram 管理员不能使用机器人命令（版主、全局管理员和作者不受影响）\n")
+	}
+	sb.WriteString("\n")
+
+	rightsButton := "🔓 改为任何管理员"
+	sb.WriteString("*群管理员权限检查：*")
+	if group.RequiresAdminRights() {
+		sb.WriteString("按 Telegram 权限\n")
+		sb.WriteString("封禁用户 → 踢出、拉黑、禁言及解除和修改记录\n删除消息 → 清理消息\n置顶消息 → 置顶\n")
+	} else {
+		sb.WriteString("任何管理员\n")
+		sb.WriteString("所有群管理员都可以执行其角色允许的全部操作\n")
+		rightsButton = "🔒 改为按 Telegram 权限"
+	}
+
+	var adminButtons []tgbotapi.InlineKeyboardButton
+	for _, option := range []struct{ mode, label string }{
+		{models.AdminEnabledInherit, "跟随全局"},
+		{models.AdminEnabledOn, "启用"},
+		{models.AdminEnabledOff, "关闭"},
+	} {
+		label := option.label
+		if group.AdminEnabled == option.mode || (option.mode == models.AdminEnabledInherit && group.AdminEnabled == "") {
+			label = "✅ " + label
+		}
+		adminButtons = append(adminButtons, tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("config:gs_admins_%s_%d", option.mode, group.GroupID)))
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		adminButtons,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(rightsButton, fmt.Sprintf("config:gs_rights_%d", group.GroupID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ 返回", "config:group_settings"),
+		),
+	)
+	h.notificationService.EditMessage(callback.Message.Chat.ID, callback.Message.MessageID, sb.String(), &keyboard)
+	h.notificationService.AnswerCallbackQuery(callback.ID, "", false)
+}
@@ -7,6 +7,9 @@ import (
 	"admin-bot/internal/service"
 	"admin-bot/internal/utils"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -25,6 +28,11 @@ type Handler struct {
 	logService           *service.LogService
 	notificationService  *service.NotificationService
 	userCacheService     *service.UserCacheService
+	banListService       *service.BanListService
+	reasonPresetService  *service.ReasonPresetService
+	roleService          *service.RoleService
+	moderatorService     *service.ModeratorService
+	authorService        *service.AuthorService
 	rateLimiter          *utils.RateLimiter
 	notifiedUnauthorized map[int64]bool      // 记录已通知的未授权群组
 	notifiedMutex        *utils.SafeMap      // 并发安全的通知记录 map
@@ -39,7 +47,12 @@ func NewHandler(bot *tgbotapi.BotAPI, cfg *config.Config,
 	adminService *service.AdminService,
 	logService *service.LogService,
 	notificationService *service.NotificationService,
-	userCacheService *service.UserCacheService) *Handler {
+	userCacheService *service.UserCacheService,
+	banListService *service.BanListService,
+	reasonPresetService *service.ReasonPresetService,
+	roleService *service.RoleService,
+	moderatorService *service.ModeratorService,
+	authorService *service.AuthorService) *Handler {
 
 	return &Handler{
 		bot:                  bot,
@@ -52,12 +65,25 @@ func NewHandler(bot *tgbotapi.BotAPI, cfg *config.Config,
 		logService:           logService,
 		notificationService:  notificationService,
 		userCacheService:     userCacheService,
+		banListService:       banListService,
+		reasonPresetService:  reasonPresetService,
+		roleService:          roleService,
+		moderatorService:     moderatorService,
+		authorService:        authorService,
 		rateLimiter:          utils.NewRateLimiter(cfg.System.RateLimitPerGroup),
 		notifiedUnauthorized: make(map[int64]bool),
 		notifiedMutex:        utils.NewSafeMap(30 * time.Minute), // 30分钟后自动清理通知记录
 	}
 }
 
+// TrackMessage 记录群组消息到最近消息缓存（用于 -purge 清理）
+func (h *Handler) TrackMessage(message *tgbotapi.Message) {
+	if message.From == nil {
+		return
+	}
+	cache.GetMessageCache().Add(message.Chat.ID, message.From.ID, message.MessageID)
+}
+
 // CacheUserInfo 缓存用户信息
 func (h *Handler) CacheUserInfo(user *tgbotapi.User) {
 	if user.UserName != "" {
@@ -127,8 +153,36 @@ func (h *Handler) HandleMessage(message *tgbotapi.Message) {
 		h.handleMute(message)
 	case "unjy":
 		h.handleUnmute(message)
+	case "purge":
+		h.handlePurge(message)
+	case "pin":
+		h.handlePin(message)
+	case "extend":
+		h.handleExtend(message)
+	case "shorten":
+		h.handleShorten(message)
+	case "editreason":
+		h.handleEditReason(message)
+	case "case":
+		h.handleCase(message)
+	case "warn":
+		h.handleWarn(message)
+	case "info":
+		h.handleInfo(message)
+	case "history":
+		h.handleHistory(message)
 	case "config":
 		h.handleConfig(message)
+	case "mod":
+		h.handleMod(message)
+	case "banlist":
+		h.handleBanList(message)
+	case "mutelist":
+		h.handleMuteList(message)
+	case "exportbans":
+		h.handleExportBans(message)
+	case "importbans":
+		h.handleImportBans(message)
 	default:
 		logrus.Debugf("Unknown command: %s", command)
 	}
@@ -150,15 +204,43 @@ func (h *Handler) handleHelp(message *tgbotapi.Message) {
 		"/unlh \\[理由\\] - 解除拉黑\n" +
 		"/jy \\[时间\\] \\[理由\\] - 禁言用户\n" +
 		"/unjy \\[理由\\] - 解除禁言\n" +
+		"/purge \\[数量\\] - 清理目标用户最近的消息\n" +
+		"/pin \\[\\-silent\\] - 置顶被回复的消息\n" +
+		"/extend 时间 - 延长拉黑/禁言（perm 改为永久，until 指定结束时间）\n" +
+		"/shorten 时间 - 缩短拉黑/禁言\n" +
+		"/editreason 理由 - 修改拉黑/禁言理由\n" +
+		"/case 编号 \\[备注\\] - 查看案件详情，带备注时添加备注\n" +
+		"/warn \\[理由\\] - 警告用户（只记录，不限制）\n" +
+		"/info - 查看用户档案（可在私聊中使用）\n" +
+		"/history - 查看用户的完整操作记录\n" +
 		"/cancel - 取消当前操作\n\n" +
+		"*作者命令（群组）：*\n" +
+		"/mod add|del - 添加/移除本群版主，/mod list 查看本群版主\n\n" +
+		"*作者命令（私聊）：*\n" +
+		"/banlist、/mutelist \\[\\-perm|\\-temp\\] \\[\\-op ID\\] \\[\\-group ID\\] \\[\\-expiry\\] \\[关键词\\] - 浏览拉黑/禁言列表（作者和全局管理员）\n" +
+		"/exportbans \\[csv|json\\] \\[all\\] - 导出黑名单\n" +
+		"/importbans - 导入黑名单（CSV/JSON 文件）\n\n" +
 		"*使用方式：*\n" +
 		"\\- 引用回复目标用户的消息\n" +
-		"\\- 或在命令后指定 @username\n\n" +
+		"\\- 或在命令后指定 @username、用户ID 或文本提及（可用于已退群的用户）\n" +
+		"\\- 理由之后的数字（例如手机号）视为理由的一部分，理由之后指定用户请使用 id:用户ID\n" +
+		"\\- 加上 \\-local 仅在本群执行（默认作用于所有授权群组）\n" +
+		"\\- 作者和全局管理员可以在私聊中通过用户ID或 @username 执行，作用于所有授权群组\n" +
+		"\\- 加上 \\-d 删除被回复的消息，\\-purge \\[数量\\] 清理目标用户最近的消息\n" +
+		"\\- 加上 \\-silent 成功时不在群内回复，\\-notify 私聊通知目标用户\n" +
+		"\\- 理由包含空格或 \\- 开头的内容时可以用引号括起来\n" +
+		"\\- 修改记录时用户同时被拉黑和禁言，加上 \\-ban 或 \\-mute 指定\n" +
+		"\\- /lh、/jy 未填写理由时弹出预设理由菜单，/jy 未填写时间时弹出时长菜单\n\n" +
+		"*禁言模式：*\n" +
+		"\\-media 禁止媒体，\\-links 禁止链接，\\-stickers 禁止贴纸/GIF，\\-mode 弹出选择菜单（默认完全禁言）\n\n" +
 		"*时间单位：*\n" +
-		"s=秒，m=分钟，h=小时，d=天\n\n" +
+		"s=秒，m=分钟，h=小时，d=天，w=周，mo=月（30天），可组合如 1d12h\n" +
+		"perm 或 永久 表示永久，until 2026\\-11\\-01 18:00 指定结束时间\n" +
+		"时长范围为 30 秒到 366 天\n\n" +
 		"*示例：*\n" +
 		"`/jy @user 10m 违规`\n" +
-		"`/lh 1d 刷屏`"
+		"`/lh 1d 刷屏`\n" +
+		"`/jy 1h -local 广告`"
 
 	h.sendReply(message.Chat.ID, message.MessageID, text)
 }
@@ -195,66 +277,50 @@ func (h *Handler) handleCancel(message *tgbotapi.Message) {
 // handleKick 处理踢出命令
 func (h *Handler) handleKick(message *tgbotapi.Message) {
 	// 检查权限
-	hasPermission, reason := h.permissionChecker.CheckPermission(message)
-	if !hasPermission {
-		logrus.WithFields(logrus.Fields{
-			"用户ID": message.From.ID,
-			"群组ID": message.Chat.ID,
-			"原因":   reason,
-		}).Warn("⛔ 权限检查失败")
-		h.sendReply(message.Chat.ID, message.MessageID, "❌ 您没有权限执行此操作")
+	if !h.authorize(message, models.PermKick) {
 		return
 	}
 
-	logrus.WithFields(logrus.Fields{
-		"用户ID": message.From.ID,
-		"群组ID": message.Chat.ID,
-		"权限类型": reason,
-	}).Debug("✅ 权限检查通过")
-
 	// 解析命令
-	params, err := ParseCommand(message, h.bot, h.userCacheService)
+	params, err := ParseCommand(message, h.userCacheService)
 	if err != nil {
 		h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("❌ %s", err.Error()))
 		return
 	}
+	h.limitToModeratorGroups(message, params)
 
+	// 受保护的用户需要作者确认
+	if !h.checkProtectedTargets(message, params, nil) {
+		return
+	}
+	// 符合审批策略的批量踢出需要另一名有权限的用户批准
+	if !h.checkApproval(message, params, nil) {
+		return
+	}
+	h.executeKick(message, params)
+}
+
+// executeKick 执行踢出操作
+func (h *Handler) executeKick(message *tgbotapi.Message, params *CommandParams) {
 	// 获取操作人信息
 	_, operatorName := GetUserInfo(message.From)
 	groupName := GetChatTitle(message.Chat)
 	groupUsername := GetChatUsername(message.Chat)
 
-	// 获取所有授权群组
-	authorizedGroups, err := h.groupService.GetAuthorizedGroups()
-	if err != nil {
-		logrus.Errorf("Failed to get authorized groups: %v", err)
-		authorizedGroups = []models.AuthorizedGroup{}
-	}
+	// 获取需要执行操作的群组（仅本群模式只包含当前群组）
+	authorizedGroups := h.getTargetGroups(message.Chat, params)
 
 	successCount := 0
 	failedCount := 0
+	caseIDs := make([]int64, 0, len(params.TargetUsers))
 
 	// 处理所有目标用户
 	for _, targetUserID := range params.TargetUsers {
 		// 限流
 		h.rateLimiter.Wait(message.Chat.ID)
 
-		// 获取目标用户信息
-		chatMember, err := h.bot.GetChatMember(tgbotapi.GetChatMemberConfig{
-			ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
-				ChatID: message.Chat.ID,
-				UserID: targetUserID,
-			},
-		})
-
-		if err != nil {
-			logrus.Errorf("Failed to get chat member: %v", err)
-			h.notificationService.SendErrorNotification(groupName, "踢出", fmt.Sprintf("%d", targetUserID),
-				targetUserID, err.Error(), operatorName)
-			continue
-		}
-
-		targetUsername, targetName := GetUserInfo(chatMember.User)
+		// 获取目标用户信息（用户可能已经退群）
+		targetUsername, targetName := h.resolveTargetUser(message.Chat.ID, targetUserID)
 
 		// 在所有授权群组中执行踢出
 		groupSuccessCount := 0
@@ -269,7 +335,7 @@ func (h *Handler) handleKick(message *tgbotapi.Message) {
 				},
 			}
 
-			_, err = h.bot.Request(kickConfig)
+			_, err := h.bot.Request(kickConfig)
 			if err != nil {
 				logrus.Errorf("Failed to kick user in group %d: %v", group.GroupID, err)
 			} else {
@@ -293,14 +359,33 @@ func (h *Handler) handleKick(message *tgbotapi.Message) {
 			continue
 		}
 
-		// 记录日志
-		h.logService.LogOperation(models.OpTypeKick, targetUserID, targetUsername,
-			message.Chat.ID, groupName, message.From.ID, operatorName,
-			"", nil, true, "")
+		// 存档被回复的消息作为证据（必须在删除消息之前）
+		evidence := h.captureEvidence(message, targetUserID)
+
+		// 删除被回复的消息并清理最近消息
+		h.cleanupTargetMessages(message, params, targetUserID, authorizedGroups)
+
+		// 记录日志（日志ID即案件编号）
+		caseID := h.logCase(&models.OperationLog{
+			OperationType:  models.OpTypeKick,
+			TargetUserID:   targetUserID,
+			TargetUsername: targetUsername,
+			GroupID:        message.Chat.ID,
+			GroupName:      groupName,
+			OperatorID:     message.From.ID,
+			OperatorName:   operatorName,
+			Reason:         params.Reason,
+			ReasonCode:     params.ReasonCode,
+			Evidence:       evidence,
+			Scope:          params.Scope(),
+			GroupCount:     groupSuccessCount,
+		})
+		caseIDs = append(caseIDs, caseID)
 
 		// 发送通知
 		h.notificationService.SendKickNotification(message.Chat.ID, groupName, groupUsername,
-			targetName, targetUserID, operatorName, message.From.ID)
+			targetName, targetUserID, operatorName, message.From.ID, params.Local, evidence.ArchiveLink(), caseID)
+		h.notifyTarget(params, targetUserID, "踢出", groupName, false)
 
 		logrus.WithFields(logrus.Fields{
 			"用户ID": targetUserID,
@@ -311,18 +396,23 @@ func (h *Handler) handleKick(message *tgbotapi.Message) {
 		successCount++
 	}
 
+	// 静默模式成功时不回复
+	if params.Silent && failedCount == 0 {
+		return
+	}
+
 	// 发送操作结果反馈
 	if params.IsBatch {
 		// 批量操作显示详细结果
 		if failedCount == 0 {
-			h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("✅ 踢出操作成功（%d/%d）", successCount, len(params.TargetUsers)))
+			h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("✅ 踢出操作成功（%d/%d）%s%s", successCount, len(params.TargetUsers), scopeSuffix(params), caseSuffix(caseIDs)))
 		} else {
-			h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("⚠️ 踢出操作完成，成功 %d，失败 %d", successCount, failedCount))
+			h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("⚠️ 踢出操作完成，成功 %d，失败 %d%s%s", successCount, failedCount, scopeSuffix(params), caseSuffix(caseIDs)))
 		}
 	} else {
 		// 单用户操作简单反馈
 		if successCount > 0 {
-			h.sendReply(message.Chat.ID, message.MessageID, "✅ 踢出操作成功")
+			h.sendReply(message.Chat.ID, message.MessageID, "✅ 踢出操作成功"+scopeSuffix(params)+caseSuffix(caseIDs))
 		} else {
 			h.sendReply(message.Chat.ID, message.MessageID, "❌ 踢出操作失败")
 		}
@@ -332,76 +422,58 @@ func (h *Handler) handleKick(message *tgbotapi.Message) {
 // handleBan 处理拉黑命令（异步优化版本）
 func (h *Handler) handleBan(message *tgbotapi.Message) {
 	// 检查权限
-	hasPermission, reason := h.permissionChecker.CheckPermission(message)
-	if !hasPermission {
-		logrus.WithFields(logrus.Fields{
-			"用户ID": message.From.ID,
-			"群组ID": message.Chat.ID,
-			"原因":   reason,
-		}).Warn("⛔ 权限检查失败")
-		h.sendReply(message.Chat.ID, message.MessageID, "❌ 您没有权限执行此操作")
+	if !h.authorize(message, models.PermBan) {
 		return
 	}
 
-	logrus.WithFields(logrus.Fields{
-		"用户ID": message.From.ID,
-		"群组ID": message.Chat.ID,
-		"权限类型": reason,
-	}).Debug("✅ 权限检查通过")
-
 	// 解析命令
-	params, err := ParseCommand(message, h.bot, h.userCacheService)
+	params, err := ParseCommand(message, h.userCacheService)
 	if err != nil {
 		h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("❌ %s", err.Error()))
 		return
 	}
+	h.limitToModeratorGroups(message, params)
 
-	// 立即发送"处理中"反馈，提升响应速度
-	processingMsg := h.sendReplyAndGetMessage(message.Chat.ID, message.MessageID, "⏳ 正在处理拉黑操作...")
+	// 没有理由时先让操作人选择预设理由
+	h.matchReasonPreset(params)
+	h.nextModerationStep(message, params, nil)
+}
+
+// executeBan 执行拉黑操作（processingMsg 为已有的菜单消息，为空时发送新的回复）
+func (h *Handler) executeBan(message *tgbotapi.Message, params *CommandParams, processingMsg *tgbotapi.Message) {
+	// 立即发送"处理中"反馈，提升响应速度（静默模式不回复）
+	if processingMsg == nil {
+		if !params.Silent {
+			processingMsg = h.sendReplyAndGetMessage(message.Chat.ID, message.MessageID, "⏳ 正在处理拉黑操作...")
+		}
+	} else {
+		h.editMessage(processingMsg.Chat.ID, processingMsg.MessageID, "⏳ 正在处理拉黑操作...")
+	}
 
-	// 获取操作人信息
-	_, operatorName := GetUserInfo(message.From)
 	groupName := GetChatTitle(message.Chat)
-	groupUsername := GetChatUsername(message.Chat)
 
-	// 获取所有授权群组
-	authorizedGroups, err := h.groupService.GetAuthorizedGroups()
-	if err != nil {
-		logrus.Errorf("Failed to get authorized groups: %v", err)
-		authorizedGroups = []models.AuthorizedGroup{}
-	}
+	// 获取需要执行操作的群组（仅本群模式只包含当前群组）
+	authorizedGroups := h.getTargetGroups(message.Chat, params)
 
 	// 异步处理所有用户
 	go func() {
 		successCount := 0
 		failedCount := 0
+		caseIDs := make([]int64, 0, len(params.TargetUsers))
 
 		// 批量处理
 		for _, targetUserID := range params.TargetUsers {
 			// 限流
 			h.rateLimiter.Wait(message.Chat.ID)
 
-			// 获取目标用户信息
-			chatMember, err := h.bot.GetChatMember(tgbotapi.GetChatMemberConfig{
-				ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
-					ChatID: message.Chat.ID,
-					UserID: targetUserID,
-				},
-			})
-
-			if err != nil {
-				logrus.Errorf("Failed to get chat member: %v", err)
-				h.notificationService.SendErrorNotification(groupName, "拉黑", fmt.Sprintf("%d", targetUserID),
-					targetUserID, err.Error(), operatorName)
-				failedCount++
-				continue
-			}
-
-			targetUsername, targetName := GetUserInfo(chatMember.User)
+			// 获取目标用户信息（用户可能已经退群或从未加入）
+			targetUsername, targetName := h.resolveTargetUser(message.Chat.ID, targetUserID)
 
 			// 并发执行多群组拉黑操作
 			var banSuccess int
 			var banFailed int
+			var banGroups []models.AuthorizedGroup
+			var resultMutex sync.Mutex
 			tasks := make([]func(), 0, len(authorizedGroups))
 
 			for _, group := range authorizedGroups {
@@ -417,15 +489,18 @@ func (h *Handler) handleBan(message *tgbotapi.Message) {
 					}
 
 					if params.Duration > 0 {
-						kickConfig.UntilDate = int64(params.Duration)
+						kickConfig.UntilDate = utils.UntilDate(params.Duration)
 					}
 
 					_, err := h.bot.Request(kickConfig)
+					resultMutex.Lock()
+					defer resultMutex.Unlock()
 					if err != nil {
 						logrus.Errorf("Failed to ban user in group %d: %v", grp.GroupID, err)
 						banFailed++
 					} else {
 						banSuccess++
+						banGroups = append(banGroups, grp)
 					}
 				})
 			}
@@ -437,32 +512,15 @@ func (h *Handler) handleBan(message *tgbotapi.Message) {
 			if banSuccess > 0 {
 				successCount++
 
-				// 异步保存到数据库并记录日志（不阻塞核心功能）
-				go func(uid int64, uname, fname string) {
-					// 保存到数据库
-					err := h.banService.BanUser(uid, uname, fname,
-						message.Chat.ID, groupName, message.From.ID, operatorName,
-						params.Reason, params.Duration)
-					if err != nil {
-						// 数据库保存失败不影响用户反馈，但记录详细错误
-						logrus.WithFields(logrus.Fields{
-							"用户ID": uid,
-							"用户名":  uname,
-							"群组":   groupName,
-							"错误":   err.Error(),
-						}).Error("❌ 数据库保存失败（Telegram操作已成功）")
-					}
+				// 存档被回复的消息作为证据（必须在删除消息之前）
+				evidence := h.captureEvidence(message, targetUserID)
 
-					// 记录操作日志
-					durationPtr := &params.Duration
-					h.logService.LogOperation(models.OpTypeBan, uid, uname,
-						message.Chat.ID, groupName, message.From.ID, operatorName,
-						params.Reason, durationPtr, true, "")
-				}(targetUserID, targetUsername, targetName)
+				// 删除被回复的消息并清理最近消息
+				h.cleanupTargetMessages(message, params, targetUserID, authorizedGroups)
 
-				// 发送通知（已经是异步的）
-				h.notificationService.SendBanNotification(message.Chat.ID, groupName, groupUsername,
-					targetName, targetUserID, params.Duration, params.Reason, operatorName, message.From.ID)
+				// 保存记录、记录日志并发送通知（日志ID即案件编号）
+				caseIDs = append(caseIDs, h.recordBan(message, params, targetUserID, targetUsername, targetName, banGroups, evidence))
+				h.notifyTarget(params, targetUserID, "拉黑", groupName, true)
 
 				logrus.WithFields(logrus.Fields{
 					"用户ID":  targetUserID,
@@ -476,63 +534,135 @@ func (h *Handler) handleBan(message *tgbotapi.Message) {
 			}
 		}
 
+		// 静默模式成功时不回复（删除已有的状态消息）
+		if params.Silent && failedCount == 0 {
+			if processingMsg != nil {
+				h.deleteMessage(message.Chat.ID, processingMsg.MessageID)
+			}
+			return
+		}
+
 		// 更新消息状态
 		var resultText string
 		if params.IsBatch {
 			// 批量操作显示详细结果
 			if failedCount == 0 {
-				resultText = fmt.Sprintf("✅ 拉黑操作成功（%d/%d）", successCount, len(params.TargetUsers))
+				resultText = fmt.Sprintf("✅ 拉黑操作成功（%d/%d）%s%s", successCount, len(params.TargetUsers), scopeSuffix(params), caseSuffix(caseIDs))
 			} else {
-				resultText = fmt.Sprintf("⚠️ 拉黑操作完成，成功 %d，失败 %d", successCount, failedCount)
+				resultText = fmt.Sprintf("⚠️ 拉黑操作完成，成功 %d，失败 %d%s%s", successCount, failedCount, scopeSuffix(params), caseSuffix(caseIDs))
 			}
 		} else {
 			// 单用户操作简单反馈
 			if successCount > 0 {
-				resultText = "✅ 拉黑操作成功"
+				resultText = "✅ 拉黑操作成功" + scopeSuffix(params) + caseSuffix(caseIDs)
 			} else {
 				resultText = "❌ 拉黑操作失败"
 			}
 		}
 
-		// 更新处理中的消息
+		// 更新处理中的消息（撤销时限内附带撤销按钮）
+		keyboard := h.undoKeyboard(message.From.ID, caseIDs)
 		if processingMsg != nil {
-			h.editMessage(message.Chat.ID, processingMsg.MessageID, resultText)
+			h.editMessageWithKeyboard(message.Chat.ID, processingMsg.MessageID, resultText, keyboard)
 		} else {
-			h.sendReply(message.Chat.ID, message.MessageID, resultText)
+			h.sendReplyWithKeyboard(message.Chat.ID, message.MessageID, resultText, keyboard)
 		}
 	}()
 }
 
+// recordBan 保存拉黑记录（已有生效中的记录时替代该记录）、记录操作日志并发送频道通知，返回案件编号
+// groups 为执行成功的群组，群组版主的多群操作在每个群组各保存一条本群记录，共用同一案件
+func (h *Handler) recordBan(message *tgbotapi.Message, params *CommandParams, userID int64, username, fullName string,
+	groups []models.AuthorizedGroup, evidence models.Evidence) int64 {
+
+	_, operatorName := GetUserInfo(message.From)
+	groupName := GetChatTitle(message.Chat)
+
+	// 保存到数据库（第一条为主记录，案件和频道通知对应主记录）
+	records := make([]*models.Blacklist, 0, len(groups))
+	for _, group := range recordGroups(message.Chat, params, groups) {
+		record, err := h.banService.BanUser(userID, username, fullName,
+			group.GroupID, group.GroupName, message.From.ID, operatorName,
+			params.Reason, params.ReasonCode, params.Duration, params.Scope(), evidence)
+		if err != nil {
+			// 数据库保存失败不影响用户反馈，但记录详细错误
+			logrus.WithFields(logrus.Fields{
+				"用户ID": userID,
+				"用户名":  username,
+				"群组":   group.GroupName,
+				"错误":   err.Error(),
+			}).Error("❌ 数据库保存失败（Telegram操作已成功）")
+		}
+		records = append(records, record)
+	}
+	ban := records[0]
+
+	// 记录操作日志
+	durationPtr := &params.Duration
+	caseID := h.logCase(&models.OperationLog{
+		OperationType:  models.OpTypeBan,
+		TargetUserID:   userID,
+		TargetUsername: username,
+		GroupID:        message.Chat.ID,
+		GroupName:      groupName,
+		OperatorID:     message.From.ID,
+		OperatorName:   operatorName,
+		Reason:         params.Reason,
+		ReasonCode:     params.ReasonCode,
+		Evidence:       evidence,
+		Duration:       durationPtr,
+		Scope:          params.Scope(),
+		GroupCount:     len(groups),
+		RecordID:       ban.ID,
+	})
+	for _, record := range records {
+		if record.ID != 0 && caseID != 0 {
+			record.CaseID = caseID
+			h.banService.SetBanCase(record.ID, caseID)
+		}
+		if record.ReplacesID != 0 {
+			if previous, err := h.banService.GetBan(record.ReplacesID); err == nil {
+				h.notificationService.UpdateBanNotification(previous, h.groupUsername(previous.GroupID), fmt.Sprintf("已被案件 #%d 替代", caseID))
+			}
+		}
+	}
+
+	// 发送通知，并记录通知消息以便修改记录时同步编辑
+	h.notificationService.SendBanNotification(ban, GetChatUsername(message.Chat), h.undoWindow() > 0, func(chatID int64, messageID int) {
+		if ban.ID != 0 {
+			h.banService.SetBanNotification(ban.ID, chatID, messageID)
+		}
+	})
+
+	return caseID
+}
+
 // handleUnban 处理解除拉黑命令
 func (h *Handler) handleUnban(message *tgbotapi.Message) {
 	// 检查权限
-	hasPermission, _ := h.permissionChecker.CheckPermission(message)
-	if !hasPermission {
-		h.sendReply(message.Chat.ID, message.MessageID, "❌ 您没有权限执行此操作")
+	if !h.authorize(message, models.PermUnban) {
 		return
 	}
 
 	// 解析命令
-	params, err := ParseCommand(message, h.bot, h.userCacheService)
+	params, err := ParseCommand(message, h.userCacheService)
 	if err != nil {
 		h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("❌ %s", err.Error()))
 		return
 	}
+	h.limitToModeratorGroups(message, params)
 
 	// 获取操作人信息
 	_, operatorName := GetUserInfo(message.From)
 	groupName := GetChatTitle(message.Chat)
 	groupUsername := GetChatUsername(message.Chat)
 
-	// 获取所有授权群组
-	authorizedGroups, err := h.groupService.GetAuthorizedGroups()
-	if err != nil {
-		logrus.Errorf("Failed to get authorized groups: %v", err)
-		authorizedGroups = []models.AuthorizedGroup{}
-	}
+	// 获取需要执行操作的群组（仅本群模式只包含当前群组）
+	authorizedGroups := h.getTargetGroups(message.Chat, params)
 
 	successCount := 0
 	failedCount := 0
+	caseIDs := make([]int64, 0, len(params.TargetUsers))
 
 	// 处理所有目标用户
 	for _, targetUserID := range params.TargetUsers {
@@ -540,22 +670,23 @@ func (h *Handler) handleUnban(message *tgbotapi.Message) {
 		h.rateLimiter.Wait(message.Chat.ID)
 
 		// 获取目标用户信息
-		chatMember, err := h.bot.GetChatMember(tgbotapi.GetChatMemberConfig{
-			ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
-				ChatID: message.Chat.ID,
-				UserID: targetUserID,
-			},
-		})
+		targetUsername, targetName := h.resolveTargetUser(message.Chat.ID, targetUserID)
 
-		var targetUsername, targetName string
-		if err == nil {
-			targetUsername, targetName = GetUserInfo(chatMember.User)
-		} else {
-			targetName = fmt.Sprintf("User_%d", targetUserID)
+		// 仅本群解除时，若仍有全局拉黑记录则不能单独放行
+		if params.Scope() == models.ScopeLocal {
+			globalBanned, err := h.banService.HasActiveGlobalBan(targetUserID)
+			if err != nil {
+				logrus.Errorf("Failed to check global ban: %v", err)
+			} else if globalBanned {
+				h.sendReply(message.Chat.ID, message.MessageID,
+					fmt.Sprintf("⚠️ %s 仍处于全局拉黑中，请使用 /unlh 全局解除", targetName))
+				failedCount++
+				continue
+			}
 		}
 
 		// 更新数据库
-		err = h.banService.UnbanUser(targetUserID, params.Reason, message.From.ID)
+		err = h.banService.UnbanUser(targetUserID, params.Reason, message.From.ID, scopeGroupIDs(message.Chat, params), params.Scope())
 		if err != nil {
 			logrus.Errorf("Failed to update unban record: %v", err)
 			failedCount++
@@ -579,30 +710,46 @@ func (h *Handler) handleUnban(message *tgbotapi.Message) {
 			}
 		}
 
-		// 记录日志
-		h.logService.LogOperation(models.OpTypeUnban, targetUserID, targetUsername,
-			message.Chat.ID, groupName, message.From.ID, operatorName,
-			params.Reason, nil, true, "")
+		// 记录日志（日志ID即案件编号）
+		caseID := h.logCase(&models.OperationLog{
+			OperationType:  models.OpTypeUnban,
+			TargetUserID:   targetUserID,
+			TargetUsername: targetUsername,
+			GroupID:        message.Chat.ID,
+			GroupName:      groupName,
+			OperatorID:     message.From.ID,
+			OperatorName:   operatorName,
+			Reason:         params.Reason,
+			Scope:          params.Scope(),
+			GroupCount:     len(authorizedGroups),
+		})
+		caseIDs = append(caseIDs, caseID)
 
 		// 发送通知
 		h.notificationService.SendUnbanNotification(message.Chat.ID, groupName, groupUsername,
-			targetName, targetUserID, params.Reason, operatorName, message.From.ID)
+			targetName, targetUserID, params.Reason, operatorName, message.From.ID, params.Scope() == models.ScopeLocal, caseID)
+		h.notifyTarget(params, targetUserID, "解除拉黑", groupName, false)
 
 		successCount++
 	}
 
+	// 静默模式成功时不回复
+	if params.Silent && failedCount == 0 {
+		return
+	}
+
 	// 发送操作结果反馈
 	if params.IsBatch {
 		// 批量操作显示详细结果
 		if failedCount == 0 {
-			h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("✅ 解除拉黑操作成功（%d/%d）", successCount, len(params.TargetUsers)))
+			h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("✅ 解除拉黑操作成功（%d/%d）%s%s", successCount, len(params.TargetUsers), scopeSuffix(params), caseSuffix(caseIDs)))
 		} else {
-			h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("⚠️ 解除拉黑操作完成，成功 %d，失败 %d", successCount, failedCount))
+			h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("⚠️ 解除拉黑操作完成，成功 %d，失败 %d%s%s", successCount, failedCount, scopeSuffix(params), caseSuffix(caseIDs)))
 		}
 	} else {
 		// 单用户操作简单反馈
 		if successCount > 0 {
-			h.sendReply(message.Chat.ID, message.MessageID, "✅ 解除拉黑操作成功")
+			h.sendReply(message.Chat.ID, message.MessageID, "✅ 解除拉黑操作成功"+scopeSuffix(params)+caseSuffix(caseIDs))
 		} else {
 			h.sendReply(message.Chat.ID, message.MessageID, "❌ 解除拉黑操作失败")
 		}
@@ -612,76 +759,58 @@ func (h *Handler) handleUnban(message *tgbotapi.Message) {
 // handleMute 处理禁言命令（异步优化版本）
 func (h *Handler) handleMute(message *tgbotapi.Message) {
 	// 检查权限
-	hasPermission, reason := h.permissionChecker.CheckPermission(message)
-	if !hasPermission {
-		logrus.WithFields(logrus.Fields{
-			"用户ID": message.From.ID,
-			"群组ID": message.Chat.ID,
-			"原因":   reason,
-		}).Warn("⛔ 权限检查失败")
-		h.sendReply(message.Chat.ID, message.MessageID, "❌ 您没有权限执行此操作")
+	if !h.authorize(message, models.PermMute) {
 		return
 	}
 
-	logrus.WithFields(logrus.Fields{
-		"用户ID": message.From.ID,
-		"群组ID": message.Chat.ID,
-		"权限类型": reason,
-	}).Debug("✅ 权限检查通过")
-
 	// 解析命令
-	params, err := ParseCommand(message, h.bot, h.userCacheService)
+	params, err := ParseCommand(message, h.userCacheService)
 	if err != nil {
 		h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("❌ %s", err.Error()))
 		return
 	}
+	h.limitToModeratorGroups(message, params)
 
-	// 立即发送"处理中"反馈，提升响应速度
-	processingMsg := h.sendReplyAndGetMessage(message.Chat.ID, message.MessageID, "⏳ 正在处理禁言操作...")
+	// 依次补全理由、时长和禁言模式（-mode）后执行
+	h.matchReasonPreset(params)
+	h.nextModerationStep(message, params, nil)
+}
+
+// executeMute 执行禁言操作（processingMsg 为已有的菜单消息，为空时发送新的回复）
+func (h *Handler) executeMute(message *tgbotapi.Message, params *CommandParams, processingMsg *tgbotapi.Message) {
+	// 立即发送"处理中"反馈，提升响应速度（静默模式不回复）
+	if processingMsg == nil {
+		if !params.Silent {
+			processingMsg = h.sendReplyAndGetMessage(message.Chat.ID, message.MessageID, "⏳ 正在处理禁言操作...")
+		}
+	} else {
+		h.editMessage(processingMsg.Chat.ID, processingMsg.MessageID, "⏳ 正在处理禁言操作...")
+	}
 
-	// 获取操作人信息
-	_, operatorName := GetUserInfo(message.From)
 	groupName := GetChatTitle(message.Chat)
-	groupUsername := GetChatUsername(message.Chat)
 
-	// 获取所有授权群组
-	authorizedGroups, err := h.groupService.GetAuthorizedGroups()
-	if err != nil {
-		logrus.Errorf("Failed to get authorized groups: %v", err)
-		authorizedGroups = []models.AuthorizedGroup{}
-	}
+	// 获取需要执行操作的群组（仅本群模式只包含当前群组）
+	authorizedGroups := h.getTargetGroups(message.Chat, params)
 
 	// 异步处理所有用户
 	go func() {
 		successCount := 0
 		failedCount := 0
+		caseIDs := make([]int64, 0, len(params.TargetUsers))
 
 		// 批量处理
 		for _, targetUserID := range params.TargetUsers {
 			// 限流
 			h.rateLimiter.Wait(message.Chat.ID)
 
-			// 获取目标用户信息
-			chatMember, err := h.bot.GetChatMember(tgbotapi.GetChatMemberConfig{
-				ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
-					ChatID: message.Chat.ID,
-					UserID: targetUserID,
-				},
-			})
-
-			if err != nil {
-				logrus.Errorf("Failed to get chat member: %v", err)
-				h.notificationService.SendErrorNotification(groupName, "禁言", fmt.Sprintf("%d", targetUserID),
-					targetUserID, err.Error(), operatorName)
-				failedCount++
-				continue
-			}
-
-			targetUsername, targetName := GetUserInfo(chatMember.User)
+			// 获取目标用户信息（用户可能已经退群或从未加入）
+			targetUsername, targetName := h.resolveTargetUser(message.Chat.ID, targetUserID)
 
 			// 并发执行多群组禁言操作
 			var muteSuccess int
 			var muteFailed int
+			var muteGroups []models.AuthorizedGroup
+			var resultMutex sync.Mutex
 			tasks := make([]func(), 0, len(authorizedGroups))
 
 			for _, group := range authorizedGroups {
@@ -694,21 +823,22 @@ func (h *Handler) handleMute(message *tgbotapi.Message) {
 							ChatID: grp.GroupID,
 							UserID: targetUserID,
 						},
-						Permissions: &tgbotapi.ChatPermissions{
-							CanSendMessages: false,
-						},
+						Permissions: service.GetMutePermissions(h.bot, grp.GroupID, params.MuteMode),
 					}
 
 					if params.Duration > 0 {
-						restrictConfig.UntilDate = int64(params.Duration)
+						restrictConfig.UntilDate = utils.UntilDate(params.Duration)
 					}
 
 					_, err := h.bot.Request(restrictConfig)
+					resultMutex.Lock()
+					defer resultMutex.Unlock()
 					if err != nil {
 						logrus.Errorf("Failed to mute user in group %d: %v", grp.GroupID, err)
 						muteFailed++
 					} else {
 						muteSuccess++
+						muteGroups = append(muteGroups, grp)
 					}
 				})
 			}
@@ -720,36 +850,20 @@ func (h *Handler) handleMute(message *tgbotapi.Message) {
 			if muteSuccess > 0 {
 				successCount++
 
-				// 异步保存到数据库并记录日志（不阻塞核心功能）
-				go func(uid int64, uname, fname string) {
-					// 保存到数据库
-					err := h.muteService.MuteUser(uid, uname, fname,
-						message.Chat.ID, groupName, message.From.ID, operatorName,
-						params.Reason, params.Duration)
-					if err != nil {
-						// 数据库保存失败不影响用户反馈，但记录详细错误
-						logrus.WithFields(logrus.Fields{
-							"用户ID": uid,
-							"用户名":  uname,
-							"群组":   groupName,
-							"错误":   err.Error(),
-						}).Error("❌ 数据库保存失败（Telegram操作已成功）")
-					}
+				// 存档被回复的消息作为证据（必须在删除消息之前）
+				evidence := h.captureEvidence(message, targetUserID)
 
-					// 记录操作日志
-					durationPtr := &params.Duration
-					h.logService.LogOperation(models.OpTypeMute, uid, uname,
-						message.Chat.ID, groupName, message.From.ID, operatorName,
-						params.Reason, durationPtr, true, "")
-				}(targetUserID, targetUsername, targetName)
+				// 删除被回复的消息并清理最近消息
+				h.cleanupTargetMessages(message, params, targetUserID, authorizedGroups)
 
-				// 发送通知（已经是异步的）
-				h.notificationService.SendMuteNotification(message.Chat.ID, groupName, groupUsername,
-					targetName, targetUserID, params.Duration, params.Reason, operatorName, message.From.ID)
+				// 保存记录、记录日志并发送通知（日志ID即案件编号）
+				caseIDs = append(caseIDs, h.recordMute(message, params, targetUserID, targetUsername, targetName, muteGroups, evidence))
+				h.notifyTarget(params, targetUserID, "禁言", groupName, true)
 
 				logrus.WithFields(logrus.Fields{
 					"用户ID":  targetUserID,
 					"用户名":   targetName,
+					"禁言模式":  params.MuteMode,
 					"成功群组数": muteSuccess,
 					"失败群组数": muteFailed,
 					"总群组数":  len(authorizedGroups),
@@ -759,63 +873,155 @@ func (h *Handler) handleMute(message *tgbotapi.Message) {
 			}
 		}
 
+		// 静默模式成功时不回复（删除已有的状态消息）
+		if params.Silent && failedCount == 0 {
+			if processingMsg != nil {
+				h.deleteMessage(message.Chat.ID, processingMsg.MessageID)
+			}
+			return
+		}
+
 		// 更新消息状态
 		var resultText string
 		if params.IsBatch {
 			// 批量操作显示详细结果
 			if failedCount == 0 {
-				resultText = fmt.Sprintf("✅ 禁言操作成功（%d/%d）", successCount, len(params.TargetUsers))
+				resultText = fmt.Sprintf("✅ 禁言操作成功（%d/%d）%s%s", successCount, len(params.TargetUsers), scopeSuffix(params), caseSuffix(caseIDs))
 			} else {
-				resultText = fmt.Sprintf("⚠️ 禁言操作完成，成功 %d，失败 %d", successCount, failedCount)
+				resultText = fmt.Sprintf("⚠️ 禁言操作完成，成功 %d，失败 %d%s%s", successCount, failedCount, scopeSuffix(params), caseSuffix(caseIDs))
 			}
 		} else {
 			// 单用户操作简单反馈
 			if successCount > 0 {
-				resultText = "✅ 禁言操作成功"
+				resultText = "✅ 禁言操作成功" + scopeSuffix(params) + caseSuffix(caseIDs)
 			} else {
 				resultText = "❌ 禁言操作失败"
 			}
 		}
 
-		// 更新处理中的消息
+		// 更新处理中的消息（撤销时限内附带撤销按钮）
+		keyboard := h.undoKeyboard(message.From.ID, caseIDs)
 		if processingMsg != nil {
-			h.editMessage(message.Chat.ID, processingMsg.MessageID, resultText)
+			h.editMessageWithKeyboard(message.Chat.ID, processingMsg.MessageID, resultText, keyboard)
 		} else {
-			h.sendReply(message.Chat.ID, message.MessageID, resultText)
+			h.sendReplyWithKeyboard(message.Chat.ID, message.MessageID, resultText, keyboard)
 		}
 	}()
 }
 
+// recordMute 保存禁言记录（已有生效中的记录时替代该记录）、记录操作日志并发送频道通知，返回案件编号
+// groups 为执行成功的群组，群组版主的多群操作在每个群组各保存一条本群记录，共用同一案件
+func (h *Handler) recordMute(message *tgbotapi.Message, params *CommandParams, userID int64, username, fullName string,
+	groups []models.AuthorizedGroup, evidence models.Evidence) int64 {
+
+	_, operatorName := GetUserInfo(message.From)
+	groupName := GetChatTitle(message.Chat)
+
+	// 保存到数据库（第一条为主记录，案件和频道通知对应主记录）
+	records := make([]*models.MuteList, 0, len(groups))
+	for _, group := range recordGroups(message.Chat, params, groups) {
+		record, err := h.muteService.MuteUser(userID, username, fullName,
+			group.GroupID, group.GroupName, message.From.ID, operatorName,
+			params.Reason, params.ReasonCode, params.Duration, params.Scope(), params.MuteMode, evidence)
+		if err != nil {
+			// 数据库保存失败不影响用户反馈，但记录详细错误
+			logrus.WithFields(logrus.Fields{
+				"用户ID": userID,
+				"用户名":  username,
+				"群组":   group.GroupName,
+				"错误":   err.Error(),
+			}).Error("❌ 数据库保存失败（Telegram操作已成功）")
+		}
+		records = append(records, record)
+	}
+	mute := records[0]
+
+	// 记录操作日志
+	durationPtr := &params.Duration
+	caseID := h.logCase(&models.OperationLog{
+		OperationType:  models.OpTypeMute,
+		TargetUserID:   userID,
+		TargetUsername: username,
+		GroupID:        message.Chat.ID,
+		GroupName:      groupName,
+		OperatorID:     message.From.ID,
+		OperatorName:   operatorName,
+		Reason:         params.Reason,
+		ReasonCode:     params.ReasonCode,
+		Evidence:       evidence,
+		Duration:       durationPtr,
+		Scope:          params.Scope(),
+		GroupCount:     len(groups),
+		RecordID:       mute.ID,
+	})
+	for _, record := range records {
+		if record.ID != 0 && caseID != 0 {
+			record.CaseID = caseID
+			h.muteService.SetMuteCase(record.ID, caseID)
+		}
+		if record.ReplacesID != 0 {
+			if previous, err := h.muteService.GetMute(record.ReplacesID); err == nil {
+				h.notificationService.UpdateMuteNotification(previous, h.groupUsername(previous.GroupID), fmt.Sprintf("已被案件 #%d 替代", caseID))
+			}
+		}
+	}
+
+	// 发送通知，并记录通知消息以便修改记录时同步编辑
+	h.notificationService.SendMuteNotification(mute, GetChatUsername(message.Chat), h.undoWindow() > 0, func(chatID int64, messageID int) {
+		if mute.ID != 0 {
+			h.muteService.SetMuteNotification(mute.ID, chatID, messageID)
+		}
+	})
+
+	return caseID
+}
+
+// showMuteModeMenu 显示禁言模式选择菜单（menuMsg 为已有的菜单消息，为空时发送新的回复）
+func (h *Handler) showMuteModeMenu(message *tgbotapi.Message, params *CommandParams, menuMsg *tgbotapi.Message) {
+	actionID := addPendingAction(message, params)
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, mode := range models.MuteModes {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(models.MuteModeName(mode),
+				fmt.Sprintf("mute:%s:%s", actionID, mode)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("❌ 取消", fmt.Sprintf("mute:%s:cancel", actionID)),
+	))
+
+	if !h.sendMenu(message, menuMsg, "🔇 请选择禁言模式：", tgbotapi.NewInlineKeyboardMarkup(rows...)) {
+		removePendingAction(actionID)
+	}
+}
+
 // handleUnmute 处理解除禁言命令
 func (h *Handler) handleUnmute(message *tgbotapi.Message) {
 	// 检查权限
-	hasPermission, _ := h.permissionChecker.CheckPermission(message)
-	if !hasPermission {
-		h.sendReply(message.Chat.ID, message.MessageID, "❌ 您没有权限执行此操作")
+	if !h.authorize(message, models.PermUnmute) {
 		return
 	}
 
 	// 解析命令
-	params, err := ParseCommand(message, h.bot, h.userCacheService)
+	params, err := ParseCommand(message, h.userCacheService)
 	if err != nil {
 		h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("❌ %s", err.Error()))
 		return
 	}
+	h.limitToModeratorGroups(message, params)
 
 	// 获取操作人信息
 	_, operatorName := GetUserInfo(message.From)
 	groupName := GetChatTitle(message.Chat)
 	groupUsername := GetChatUsername(message.Chat)
 
-	// 获取所有授权群组
-	authorizedGroups, err := h.groupService.GetAuthorizedGroups()
-	if err != nil {
-		logrus.Errorf("Failed to get authorized groups: %v", err)
-		authorizedGroups = []models.AuthorizedGroup{}
-	}
+	// 获取需要执行操作的群组（仅本群模式只包含当前群组）
+	authorizedGroups := h.getTargetGroups(message.Chat, params)
 
 	successCount := 0
 	failedCount := 0
+	caseIDs := make([]int64, 0, len(params.TargetUsers))
 
 	// 批量处理
 	for _, targetUserID := range params.TargetUsers {
@@ -823,22 +1029,23 @@ func (h *Handler) handleUnmute(message *tgbotapi.Message) {
 		h.rateLimiter.Wait(message.Chat.ID)
 
 		// 获取目标用户信息
-		chatMember, err := h.bot.GetChatMember(tgbotapi.GetChatMemberConfig{
-			ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
-				ChatID: message.Chat.ID,
-				UserID: targetUserID,
-			},
-		})
+		targetUsername, targetName := h.resolveTargetUser(message.Chat.ID, targetUserID)
 
-		var targetUsername, targetName string
-		if err == nil {
-			targetUsername, targetName = GetUserInfo(chatMember.User)
-		} else {
-			targetName = fmt.Sprintf("User_%d", targetUserID)
+		// 仅本群解除时，若仍有全局禁言记录则不能单独放行
+		if params.Scope() == models.ScopeLocal {
+			globalMuted, err := h.muteService.HasActiveGlobalMute(targetUserID)
+			if err != nil {
+				logrus.Errorf("Failed to check global mute: %v", err)
+			} else if globalMuted {
+				h.sendReply(message.Chat.ID, message.MessageID,
+					fmt.Sprintf("⚠️ %s 仍处于全局禁言中，请使用 /unjy 全局解除", targetName))
+				failedCount++
+				continue
+			}
 		}
 
 		// 更新数据库
-		err = h.muteService.UnmuteUser(targetUserID, params.Reason, message.From.ID)
+		err = h.muteService.UnmuteUser(targetUserID, params.Reason, message.From.ID, scopeGroupIDs(message.Chat, params), params.Scope())
 		if err != nil {
 			logrus.Errorf("Failed to update unmute record: %v", err)
 			failedCount++
@@ -854,16 +1061,8 @@ func (h *Handler) handleUnmute(message *tgbotapi.Message) {
 					ChatID: group.GroupID,
 					UserID: targetUserID,
 				},
-				Permissions: &tgbotapi.ChatPermissions{
-					CanSendMessages:       true,
-					CanSendMediaMessages:  true,
-					CanSendPolls:          true,
-					CanSendOtherMessages:  true,
-					CanAddWebPagePreviews: true,
-					CanChangeInfo:         false,
-					CanInviteUsers:        false,
-					CanPinMessages:        false,
-				},
+				// 恢复为群组当前的默认权限
+				Permissions: service.GetGroupDefaultPermissions(h.bot, group.GroupID),
 			}
 
 			_, err = h.bot.Request(restrictConfig)
@@ -872,36 +1071,144 @@ func (h *Handler) handleUnmute(message *tgbotapi.Message) {
 			}
 		}
 
-		// 记录日志
-		h.logService.LogOperation(models.OpTypeUnmute, targetUserID, targetUsername,
-			message.Chat.ID, groupName, message.From.ID, operatorName,
-			params.Reason, nil, true, "")
+		// 记录日志（日志ID即案件编号）
+		caseID := h.logCase(&models.OperationLog{
+			OperationType:  models.OpTypeUnmute,
+			TargetUserID:   targetUserID,
+			TargetUsername: targetUsername,
+			GroupID:        message.Chat.ID,
+			GroupName:      groupName,
+			OperatorID:     message.From.ID,
+			OperatorName:   operatorName,
+			Reason:         params.Reason,
+			Scope:          params.Scope(),
+			GroupCount:     len(authorizedGroups),
+		})
+		caseIDs = append(caseIDs, caseID)
 
 		// 发送通知
 		h.notificationService.SendUnmuteNotification(message.Chat.ID, groupName, groupUsername,
-			targetName, targetUserID, params.Reason, operatorName, message.From.ID)
+			targetName, targetUserID, params.Reason, operatorName, message.From.ID, params.Scope() == models.ScopeLocal, caseID)
+		h.notifyTarget(params, targetUserID, "解除禁言", groupName, false)
 
 		successCount++
 	}
 
+	// 静默模式成功时不回复
+	if params.Silent && failedCount == 0 {
+		return
+	}
+
 	// 发送操作结果反馈
 	if params.IsBatch {
 		// 批量操作显示详细结果
 		if failedCount == 0 {
-			h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("✅ 解除禁言操作成功（%d/%d）", successCount, len(params.TargetUsers)))
+			h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("✅ 解除禁言操作成功（%d/%d）%s%s", successCount, len(params.TargetUsers), scopeSuffix(params), caseSuffix(caseIDs)))
 		} else {
-			h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("⚠️ 解除禁言操作完成，成功 %d，失败 %d", successCount, failedCount))
+			h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("⚠️ 解除禁言操作完成，成功 %d，失败 %d%s%s", successCount, failedCount, scopeSuffix(params), caseSuffix(caseIDs)))
 		}
 	} else {
 		// 单用户操作简单反馈
 		if successCount > 0 {
-			h.sendReply(message.Chat.ID, message.MessageID, "✅ 解除禁言操作成功")
+			h.sendReply(message.Chat.ID, message.MessageID, "✅ 解除禁言操作成功"+scopeSuffix(params)+caseSuffix(caseIDs))
 		} else {
 			h.sendReply(message.Chat.ID, message.MessageID, "❌ 解除禁言操作失败")
 		}
 	}
 }
 
+// handlePurge 处理清理消息命令（/purge [数量]）
+func (h *Handler) handlePurge(message *tgbotapi.Message) {
+	// 检查权限
+	if !h.authorize(message, models.PermPurge) {
+		return
+	}
+
+	// 解析命令
+	params, err := ParseCommand(message, h.userCacheService)
+	if err != nil {
+		h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("❌ %s", err.Error()))
+		return
+	}
+	h.limitToModeratorGroups(message, params)
+
+	// 数量可以直接跟在命令后（/purge 20），也可以用 -purge 20 指定
+	count := params.PurgeCount
+	if count == 0 {
+		count = DefaultPurgeCount
+		if params.Reason != "" {
+			n, err := strconv.Atoi(params.Reason)
+			if err != nil || n <= 0 || n > MaxPurgeCount {
+				h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("❌ 清理数量必须在 1 到 %d 之间", MaxPurgeCount))
+				return
+			}
+			count = n
+		}
+	}
+
+	// 获取需要执行操作的群组（仅本群模式只包含当前群组）
+	authorizedGroups := h.getTargetGroups(message.Chat, params)
+
+	deleted := 0
+	for _, targetUserID := range params.TargetUsers {
+		// 清理前先存档被回复的消息
+		h.captureEvidence(message, targetUserID)
+		deleted += h.purgeUserMessages(targetUserID, count, authorizedGroups)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"操作人":   message.From.ID,
+		"目标用户数": len(params.TargetUsers),
+		"删除数量":  deleted,
+	}).Info("🧹 已清理目标用户消息")
+
+	h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("🧹 已清理 %d 条消息%s", deleted, scopeSuffix(params)))
+}
+
+// captureEvidence 存档被回复的目标用户消息作为证据（非引用回复或回复的不是目标用户时返回空证据）
+func (h *Handler) captureEvidence(message *tgbotapi.Message, targetUserID int64) models.Evidence {
+	reply := message.ReplyToMessage
+	if reply == nil {
+		return models.Evidence{}
+	}
+	// 私聊中回复转发的消息时，证据是转发的原消息
+	if senderID, err := replyTargetUser(message); err != nil || senderID != targetUserID {
+		return models.Evidence{}
+	}
+	return h.notificationService.CaptureEvidence(reply)
+}
+
+// cleanupTargetMessages 按命令选项删除被回复的消息（-d）并清理目标用户最近的消息（-purge）
+func (h *Handler) cleanupTargetMessages(message *tgbotapi.Message, params *CommandParams, targetUserID int64, groups []models.AuthorizedGroup) {
+	reply := message.ReplyToMessage
+	if params.DeleteMessage && reply != nil && reply.From != nil && reply.From.ID == targetUserID {
+		h.deleteMessage(message.Chat.ID, reply.MessageID)
+	}
+
+	if params.PurgeCount > 0 {
+		h.purgeUserMessages(targetUserID, params.PurgeCount, groups)
+	}
+}
+
+// purgeUserMessages 删除目标用户在指定群组中最近的消息（仅限机器人记录到的消息），返回删除数量
+func (h *Handler) purgeUserMessages(userID int64, limit int, groups []models.AuthorizedGroup) int {
+	messageCache := cache.GetMessageCache()
+	deleted := 0
+
+	for _, group := range groups {
+		messageIDs := messageCache.GetUserMessages(group.GroupID, userID, limit)
+		for _, messageID := range messageIDs {
+			h.rateLimiter.Wait(group.GroupID)
+			if h.deleteMessage(group.GroupID, messageID) {
+				deleted++
+			}
+		}
+		messageCache.RemoveMessages(group.GroupID, messageIDs)
+	}
+
+	return deleted
+}
+
 // handleConfig 处理配置命令（仅作者）
 func (h *Handler) handleConfig(message *tgbotapi.Message) {
 	// 只允许作者在私聊中使用
@@ -937,10 +1244,19 @@ func (h *Handler) showConfigMenu(chatID int64) {
 		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("📢 设置通知频道", "config:set_channel"),
+			tgbotapi.NewInlineKeyboardButtonData("📝 预设理由", "config:reason_presets"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🎭 角色管理", "config:roles"),
+			tgbotapi.NewInlineKeyboardButtonData("👮 群组版主", "config:moderators"),
 		),
 		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🛡 群组设置", "config:group_settings"),
 			tgbotapi.NewInlineKeyboardButtonData("🔄 更新管理员权限", "config:sync_admins"),
 		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("👑 作者管理", "config:authors"),
+		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("🔒 关闭群管权限", "config:disable_admins"),
 			tgbotapi.NewInlineKeyboardButtonData("🔓 开启群管权限", "config:enable_admins"),
@@ -953,6 +1269,147 @@ func (h *Handler) showConfigMenu(chatID int64) {
 	h.notificationService.SendMessageWithButtons(chatID, text, keyboard)
 }
 
+// getTargetGroups 获取需要执行操作的群组（仅本群模式只返回当前群组，限定群组时只返回这些群组）
+func (h *Handler) getTargetGroups(chat *tgbotapi.Chat, params *CommandParams) []models.AuthorizedGroup {
+	if params.Local {
+		return []models.AuthorizedGroup{{
+			GroupID:   chat.ID,
+			GroupName: GetChatTitle(chat),
+			Username:  GetChatUsername(chat),
+		}}
+	}
+
+	authorizedGroups, err := h.groupService.GetAuthorizedGroups()
+	if err != nil {
+		logrus.Errorf("Failed to get authorized groups: %v", err)
+		return []models.AuthorizedGroup{}
+	}
+	if len(params.Groups) == 0 {
+		return authorizedGroups
+	}
+
+	limited := make([]models.AuthorizedGroup, 0, len(params.Groups))
+	for _, group := range authorizedGroups {
+		for _, groupID := range params.Groups {
+			if group.GroupID == groupID {
+				limited = append(limited, group)
+				break
+			}
+		}
+	}
+	return limited
+}
+
+// notifyTarget 按 -notify 选项私聊通知目标用户（用户没有与机器人对话过时无法送达）
+func (h *Handler) notifyTarget(params *CommandParams, userID int64, action, groupName string, withDuration bool) {
+	if !params.NotifyTarget {
+		return
+	}
+
+	var sb strings.Builder
+	if groupName == PrivateConsoleName {
+		// 私聊控制台发起的操作没有来源群组
+		sb.WriteString(fmt.Sprintf("📢 您已在所有关联群组中被%s", action))
+	} else {
+		sb.WriteString(fmt.Sprintf("📢 您已被%s\n\n群组：%s", action, groupName))
+		if !params.Local {
+			sb.WriteString("（及所有关联群组）")
+		}
+	}
+	if withDuration {
+		sb.WriteString("\n时长：" + utils.FormatDuration(params.Duration))
+	}
+	if params.Reason != "" {
+		sb.WriteString("\n理由：" + params.Reason)
+	}
+
+	if _, err := h.bot.Send(tgbotapi.NewMessage(userID, sb.String())); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"用户ID": userID,
+			"错误":   err.Error(),
+		}).Debug("私聊通知目标用户失败")
+	}
+}
+
+// resolveTargetUser 获取目标用户的用户名和显示名称
+// 依次尝试当前群组的成员信息、用户缓存和 getChat（用户与机器人对话过时可用），都没有时使用 User_<ID>（用于处理已退群或从未加入的用户）
+func (h *Handler) resolveTargetUser(chatID, userID int64) (username, fullName string) {
+	// 私聊（聊天ID为正数）中没有其他用户的成员信息
+	if chatID < 0 {
+		chatMember, err := h.bot.GetChatMember(tgbotapi.GetChatMemberConfig{
+			ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
+				ChatID: chatID,
+				UserID: userID,
+			},
+		})
+		if err == nil && chatMember.User != nil {
+			return GetUserInfo(chatMember.User)
+		}
+	}
+
+	if cached, err := h.userCacheService.GetUserByID(userID); err == nil {
+		fullName = strings.TrimSpace(cached.FirstName + " " + cached.LastName)
+		if fullName == "" {
+			fullName = cached.Username
+		}
+		return cached.Username, fullName
+	}
+
+	chat, err := h.bot.GetChat(tgbotapi.ChatInfoConfig{
+		ChatConfig: tgbotapi.ChatConfig{
+			ChatID: userID,
+		},
+	})
+	if err == nil && chat.IsPrivate() {
+		h.userCacheService.SaveOrUpdateUser(userID, chat.UserName, chat.FirstName, chat.LastName)
+		fullName = strings.TrimSpace(chat.FirstName + " " + chat.LastName)
+		if fullName == "" {
+			fullName = chat.UserName
+		}
+		return chat.UserName, fullName
+	}
+
+	return "", fmt.Sprintf("User_%d", userID)
+}
+
+// scopeSuffix 操作结果的范围后缀
+func scopeSuffix(params *CommandParams) string {
+	if params.Local {
+		return "（仅本群）"
+	}
+	if len(params.Groups) > 0 {
+		return fmt.Sprintf("（仅限您管理的 %d 个群组）", len(params.Groups))
+	}
+	return ""
+}
+
+// caseSuffix 操作结果中显示的案件编号
+func caseSuffix(caseIDs []int64) string {
+	numbers := make([]string, 0, len(caseIDs))
+	for _, id := range caseIDs {
+		if id != 0 {
+			numbers = append(numbers, fmt.Sprintf("#%d", id))
+		}
+	}
+	if len(numbers) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n案件编号：%s", strings.Join(numbers, "、"))
+}
+
+// logCase 记录处罚操作日志并返回案件编号（保存失败时为 0）
+func (h *Handler) logCase(log *models.OperationLog) int64 {
+	if err := h.logService.LogModeration(log); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"操作类型": log.OperationType,
+			"用户ID": log.TargetUserID,
+			"错误":   err.Error(),
+		}).Error("❌ 记录操作日志失败")
+		return 0
+	}
+	return log.ID
+}
+
 // sendReply 发送回复消息
 func (h *Handler) sendReply(chatID int64, replyToMessageID int, text string) {
 	msg := tgbotapi.NewMessage(chatID, text)
@@ -979,6 +1436,53 @@ func (h *Handler) sendReplyAndGetMessage(chatID int64, replyToMessageID int, tex
 	return &sentMsg
 }
 
+// sendReplyWithKeyboard 发送带内联键盘的回复消息（keyboard 为 nil 时等同于 sendReply）
+func (h *Handler) sendReplyWithKeyboard(chatID int64, replyToMessageID int, text string, keyboard *tgbotapi.InlineKeyboardMarkup) {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyToMessageID = replyToMessageID
+	msg.DisableWebPagePreview = true // 禁用链接预览，避免占用空间
+	if keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+
+	_, err := h.bot.Send(msg)
+	if err != nil {
+		logrus.Errorf("Failed to send reply: %v", err)
+	}
+}
+
+// deleteMessage 删除消息，返回是否成功
+func (h *Handler) deleteMessage(chatID int64, messageID int) bool {
+	_, err := h.bot.Request(tgbotapi.NewDeleteMessage(chatID, messageID))
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"群组ID": chatID,
+			"消息ID": messageID,
+			"错误":   err.Error(),
+		}).Debug("删除消息失败")
+		return false
+	}
+	return true
+}
+
+// sendMenu 回复命令发送内联菜单，menuMsg 不为空时改为编辑已有的菜单消息，返回是否成功
+func (h *Handler) sendMenu(message *tgbotapi.Message, menuMsg *tgbotapi.Message, text string, keyboard tgbotapi.InlineKeyboardMarkup) bool {
+	var err error
+	if menuMsg != nil {
+		_, err = h.bot.Send(tgbotapi.NewEditMessageTextAndMarkup(menuMsg.Chat.ID, menuMsg.MessageID, text, keyboard))
+	} else {
+		msg := tgbotapi.NewMessage(message.Chat.ID, text)
+		msg.ReplyToMessageID = message.MessageID
+		msg.ReplyMarkup = keyboard
+		_, err = h.bot.Send(msg)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to send menu: %v", err)
+		return false
+	}
+	return true
+}
+
 // editMessage 编辑消息内容
 func (h *Handler) editMessage(chatID int64, messageID int, text string) {
 	msg := tgbotapi.NewEditMessageText(chatID, messageID, text)
@@ -990,6 +1494,18 @@ func (h *Handler) editMessage(chatID int64, messageID int, text string) {
 	}
 }
 
+// editMessageWithKeyboard 编辑消息内容和内联键盘（keyboard 为 nil 时等同于 editMessage）
+func (h *Handler) editMessageWithKeyboard(chatID int64, messageID int, text string, keyboard *tgbotapi.InlineKeyboardMarkup) {
+	msg := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	msg.DisableWebPagePreview = true
+	msg.ReplyMarkup = keyboard
+
+	_, err := h.bot.Send(msg)
+	if err != nil {
+		logrus.Errorf("Failed to edit message: %v", err)
+	}
+}
+
 // CheckBotAddedToGroup 检查机器人是否被添加到群组
 func (h *Handler) CheckBotAddedToGroup(message *tgbotapi.Message, botID int64) {
 	if len(message.NewChatMembers) == 0 {
@@ -1053,7 +1569,7 @@ func (h *Handler) CheckNewMember(message *tgbotapi.Message) {
 
 	for _, newMember := range message.NewChatMembers {
 		// 检查是否被拉黑
-		banned, banRecord, err := h.banService.IsUserBanned(newMember.ID)
+		banned, banRecord, err := h.banService.IsUserBanned(newMember.ID, message.Chat.ID)
 		if err != nil {
 			logrus.Errorf("Failed to check ban status: %v", err)
 			continue
@@ -1085,6 +1601,43 @@ func (h *Handler) CheckNewMember(message *tgbotapi.Message) {
 	}
 }
 
+// EnforceLinkMute 删除处于"禁止链接"禁言模式的用户发送的含链接消息
+func (h *Handler) EnforceLinkMute(message *tgbotapi.Message) {
+	if message.From == nil || !hasLinks(message) {
+		return
+	}
+
+	muted, muteRecord, err := h.muteService.IsUserMuted(message.From.ID, message.Chat.ID)
+	if err != nil {
+		logrus.Errorf("Failed to check mute status: %v", err)
+		return
+	}
+	if !muted || muteRecord == nil || muteRecord.Mode != models.MuteModeLinks {
+		return
+	}
+
+	if !h.deleteMessage(message.Chat.ID, message.MessageID) {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"用户ID": message.From.ID,
+		"群组ID": message.Chat.ID,
+	}).Info("🔗 已删除禁止链接用户的消息")
+}
+
+// hasLinks 检查消息是否包含链接
+func hasLinks(message *tgbotapi.Message) bool {
+	for _, entities := range [][]tgbotapi.MessageEntity{message.Entities, message.CaptionEntities} {
+		for _, entity := range entities {
+			if entity.Type == "url" || entity.Type == "text_link" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // CheckUnauthorizedGroup 检查未授权群组（优化版：特殊保护 + 并发安全）
 func (h *Handler) CheckUnauthorizedGroup(message *tgbotapi.Message) {
 	// 如果是群组消息
@@ -1153,7 +1706,7 @@ func (h *Handler) CheckUnauthorizedGroup(message *tgbotapi.Message) {
 		}
 
 		// 通知所有作者
-		for _, authorID := range h.cfg.Telegram.AuthorIDs {
+		for _, authorID := range h.cfg.Telegram.GetAuthorIDs() {
 			h.notificationService.SendTextMessage(authorID, text)
 		}
 
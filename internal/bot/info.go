@@ -0,0 +1,250 @@
+package bot
+
+import (
+	"admin-bot/internal/models"
+	"admin-bot/internal/utils"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// historyPageSize 操作记录每页条数
+const historyPageSize = 10
+
+// 用户档案的显示方式（回调数据 info:<方式>:<用户ID>:<页码>）
+const (
+	infoViewProfile = "p" // 档案和操作记录（/info）
+	infoViewHistory = "h" // 只显示操作记录（/history）
+)
+
+// handleInfo 处理 /info 命令：查看用户档案（曾用名、当前处罚、警告次数和操作记录）
+func (h *Handler) handleInfo(message *tgbotapi.Message) {
+	h.handleProfileCommand(message, infoViewProfile)
+}
+
+// handleHistory 处理 /history 命令：查看用户的完整操作记录
+func (h *Handler) handleHistory(message *tgbotapi.Message) {
+	h.handleProfileCommand(message, infoViewHistory)
+}
+
+// handleProfileCommand 解析目标用户并回复档案或操作记录（群组和私聊中都可以使用）
+func (h *Handler) handleProfileCommand(message *tgbotapi.Message, view string) {
+	if !h.authorize(message, models.PermInfo) {
+		return
+	}
+
+	params, err := ParseCommand(message, h.userCacheService)
+	if err != nil {
+		h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("❌ %s", err.Error()))
+		return
+	}
+	if len(params.TargetUsers) > 1 {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 一次只能查询一个用户")
+		return
+	}
+
+	text, keyboard := h.renderProfile(params.TargetUsers[0], view, 0)
+	h.sendReplyWithKeyboard(message.Chat.ID, message.MessageID, text, keyboard)
+}
+
+// handleInfoCallback 处理档案和操作记录的翻页按钮（能执行 /info 的人都可以翻页）
+func (h *Handler) handleInfoCallback(callback *tgbotapi.CallbackQuery) {
+	if callback.Message == nil {
+		return
+	}
+
+	// 按按钮所在聊天检查点击者的权限
+	if !h.authorizeCallback(callback, models.PermInfo) {
+		return
+	}
+
+	parts := strings.Split(callback.Data, ":")
+	if len(parts) != 4 {
+		return
+	}
+	userID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return
+	}
+	page, err := strconv.Atoi(parts[3])
+	if err != nil || page < 0 {
+		return
+	}
+
+	text, keyboard := h.renderProfile(userID, parts[1], page)
+	h.editMessageWithKeyboard(callback.Message.Chat.ID, callback.Message.MessageID, text, keyboard)
+	h.notificationService.AnswerCallbackQuery(callback.ID, "", false)
+}
+
+// renderProfile 生成档案或操作记录的指定页（纯文本）和翻页按钮
+func (h *Handler) renderProfile(userID int64, view string, page int) (string, *tgbotapi.InlineKeyboardMarkup) {
+	var sb strings.Builder
+	if view == infoViewProfile {
+		sb.WriteString(h.formatProfile(userID))
+		sb.WriteString("\n\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("👤 %s\n\n", h.profileName(userID)))
+	}
+
+	logs, total, err := h.logService.GetUserLogsPage(userID, page*historyPageSize, historyPageSize)
+	if err != nil {
+		logrus.Errorf("Failed to get user logs: %v", err)
+		sb.WriteString("❌ 获取操作记录失败")
+		return sb.String(), nil
+	}
+
+	pages := int((total + historyPageSize - 1) / historyPageSize)
+	if pages == 0 {
+		sb.WriteString("📜 操作记录：暂无")
+		return sb.String(), nil
+	}
+	if page >= pages {
+		page = pages - 1
+		logs, _, err = h.logService.GetUserLogsPage(userID, page*historyPageSize, historyPageSize)
+		if err != nil {
+			logrus.Errorf("Failed to get user logs: %v", err)
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("📜 操作记录（共 %d 条，第 %d/%d 页）\n", total, page+1, pages))
+	for _, log := range logs {
+		sb.WriteString(formatLogLine(&log) + "\n")
+	}
+	sb.WriteString("\n使用 /case 编号 查看详情")
+
+	if pages == 1 {
+		return sb.String(), nil
+	}
+	row := make([]tgbotapi.InlineKeyboardButton, 0, 2)
+	if page > 0 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("⬅️ 上一页", fmt.Sprintf("info:%s:%d:%d", view, userID, page-1)))
+	}
+	if page < pages-1 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("下一页 ➡️", fmt.Sprintf("info:%s:%d:%d", view, userID, page+1)))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(row)
+	return sb.String(), &keyboard
+}
+
+// formatProfile 生成用户档案：曾用名、当前处罚、警告次数和累计处罚次数
+func (h *Handler) formatProfile(userID int64) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("👤 用户档案：%s\n\n", h.profileName(userID)))
+
+	// 曾用的用户名和名字（来自用户缓存）
+	names, err := h.userCacheService.GetUserNames(userID)
+	if err != nil {
+		logrus.Errorf("Failed to get user names: %v", err)
+	}
+	var usernames, fullNames []string
+	for _, name := range names {
+		if name.Username != "" && !containsString(usernames, "@"+name.Username) {
+			usernames = append(usernames, "@"+name.Username)
+		}
+		fullName := strings.TrimSpace(name.FirstName + " " + name.LastName)
+		if fullName != "" && !containsString(fullNames, fullName) {
+			fullNames = append(fullNames, fullName)
+		}
+	}
+	if len(usernames) > 0 {
+		sb.WriteString("用户名：" + strings.Join(usernames, "、") + "\n")
+	}
+	if len(fullNames) > 0 {
+		sb.WriteString("名字：" + strings.Join(fullNames, "、") + "\n")
+	}
+	if len(names) > 0 {
+		sb.WriteString("最后出现：" + utils.FormatTimestamp(names[0].UpdatedAt) + "\n")
+	}
+
+	// 当前生效中的处罚
+	bans, err := h.banService.GetUserBanHistory(userID)
+	if err != nil {
+		logrus.Errorf("Failed to get user ban history: %v", err)
+	}
+	mutes, err := h.muteService.GetUserMuteHistory(userID)
+	if err != nil {
+		logrus.Errorf("Failed to get user mute history: %v", err)
+	}
+
+	var active []string
+	banCount, muteCount := 0, 0
+	for _, ban := range bans {
+		if ban.Status != models.RecordStatusReverted {
+			banCount++
+		}
+		if ban.IsActive() {
+			active = append(active, "🚫 拉黑 "+activeRecordLine(ban.IsLocal(), ban.GroupName, ban.ExpireAt, ban.CaseID, ban.Reason))
+		}
+	}
+	for _, mute := range mutes {
+		if mute.Status != models.RecordStatusReverted {
+			muteCount++
+		}
+		if mute.IsActive() {
+			active = append(active, "🔇 禁言（"+models.MuteModeName(mute.Mode)+"） "+activeRecordLine(mute.IsLocal(), mute.GroupName, mute.ExpireAt, mute.CaseID, mute.Reason))
+		}
+	}
+	if len(active) == 0 {
+		sb.WriteString("当前状态：无生效中的处罚\n")
+	} else {
+		sb.WriteString("当前状态：\n" + strings.Join(active, "\n") + "\n")
+	}
+
+	warnCount, err := h.logService.CountUserWarnings(userID)
+	if err != nil {
+		logrus.Errorf("Failed to count user warnings: %v", err)
+	}
+	sb.WriteString(fmt.Sprintf("警告：%d 次\n", warnCount))
+	sb.WriteString(fmt.Sprintf("累计：拉黑 %d 次，禁言 %d 次", banCount, muteCount))
+	return sb.String()
+}
+
+// activeRecordLine 生效中的拉黑/禁言记录说明（范围、剩余时间、案件编号和理由）
+func activeRecordLine(local bool, groupName string, expireAt *time.Time, caseID int64, reason string) string {
+	scope := "全部授权群组"
+	if local {
+		scope = "仅 " + groupName
+	}
+	remaining := "永久"
+	if expireAt != nil {
+		remaining = "剩余 " + utils.FormatRemainingTime(*expireAt)
+	}
+	line := fmt.Sprintf("%s，%s", scope, remaining)
+	if caseID != 0 {
+		line += fmt.Sprintf("，案件 #%d", caseID)
+	}
+	if reason != "" {
+		line += "，理由：" + utils.TruncateString(reason, 100)
+	}
+	return line
+}
+
+// profileName 档案中显示的用户名称（来自用户缓存，没有记录时只显示ID）
+func (h *Handler) profileName(userID int64) string {
+	cached, err := h.userCacheService.GetUserByID(userID)
+	if err != nil {
+		return strconv.FormatInt(userID, 10)
+	}
+	name := strings.TrimSpace(cached.FirstName + " " + cached.LastName)
+	if name == "" && cached.Username != "" {
+		name = "@" + cached.Username
+	}
+	if name == "" {
+		return strconv.FormatInt(userID, 10)
+	}
+	return fmt.Sprintf("%s（%d）", name, userID)
+}
+
+// containsString 切片中是否包含指定字符串
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
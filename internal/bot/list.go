@@ -0,0 +1,461 @@
+package bot
+
+import (
+	"admin-bot/internal/models"
+	"admin-bot/internal/service"
+	"admin-bot/internal/utils"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// 记录列表的分页和会话设置
+const (
+	recordListPageSize = 8
+	recordListTTL      = 30 * time.Minute
+)
+
+// RecordListSession 私聊中浏览拉黑/禁言列表的会话（筛选条件较长，不放进回调数据）
+type RecordListSession struct {
+	RecordType string // service.RecordTypeBan 或 service.RecordTypeMute
+	Filter     service.RecordFilter
+	Page       int
+	UpdatedAt  time.Time
+}
+
+var (
+	recordListSessions = make(map[string]*RecordListSession)
+	recordListSeq      int64
+	recordListMutex    sync.Mutex
+)
+
+// addRecordListSession 保存列表会话并返回其ID（用于回调数据）
+func addRecordListSession(session *RecordListSession) string {
+	recordListMutex.Lock()
+	defer recordListMutex.Unlock()
+
+	// 顺便清理过期的会话
+	now := time.Now()
+	for id, s := range recordListSessions {
+		if now.Sub(s.UpdatedAt) > recordListTTL {
+			delete(recordListSessions, id)
+		}
+	}
+
+	recordListSeq++
+	id := strconv.FormatInt(recordListSeq, 36)
+	session.UpdatedAt = now
+	recordListSessions[id] = session
+	return id
+}
+
+// getRecordListSession 获取列表会话（已过期返回 nil），并刷新有效期
+func getRecordListSession(id string) *RecordListSession {
+	recordListMutex.Lock()
+	defer recordListMutex.Unlock()
+
+	session, exists := recordListSessions[id]
+	if !exists {
+		return nil
+	}
+	if time.Since(session.UpdatedAt) > recordListTTL {
+		delete(recordListSessions, id)
+		return nil
+	}
+	session.UpdatedAt = time.Now()
+	return session
+}
+
+// handleBanList 处理 /banlist 命令：私聊浏览生效中的拉黑记录
+func (h *Handler) handleBanList(message *tgbotapi.Message) {
+	h.handleRecordList(message, service.RecordTypeBan)
+}
+
+// handleMuteList 处理 /mutelist 命令：私聊浏览生效中的禁言记录
+func (h *Handler) handleMuteList(message *tgbotapi.Message) {
+	h.handleRecordList(message, service.RecordTypeMute)
+}
+
+// handleRecordList 解析筛选条件并发送列表第一页（仅作者和全局管理员，仅私聊）
+// 用法：/banlist [-perm|-temp] [-op 用户ID|@用户名] [-group 群组ID] [-expiry] [关键词]
+func (h *Handler) handleRecordList(message *tgbotapi.Message, recordType string) {
+	if !message.Chat.IsPrivate() {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 请在私聊中使用此命令")
+		return
+	}
+	if !h.canBrowseRecords(message.From.ID) {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 只有作者和全局管理员可以查看列表")
+		return
+	}
+
+	filter, err := h.parseRecordFilter(message.CommandArguments())
+	if err != nil {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ "+err.Error())
+		return
+	}
+
+	session := &RecordListSession{
+		RecordType: recordType,
+		Filter:     filter,
+	}
+	sessionID := addRecordListSession(session)
+
+	text, keyboard := h.renderRecordList(sessionID, session)
+	h.sendReplyWithKeyboard(message.Chat.ID, message.MessageID, text, keyboard)
+}
+
+// canBrowseRecords 是否可以浏览和操作拉黑/禁言列表
+func (h *Handler) canBrowseRecords(userID int64) bool {
+	return h.cfg.Telegram.IsAuthor(userID) || h.permissionChecker.IsGlobalAdmin(userID)
+}
+
+// parseRecordFilter 解析列表命令的筛选参数，非选项内容作为理由关键词
+func (h *Handler) parseRecordFilter(args string) (service.RecordFilter, error) {
+	var filter service.RecordFilter
+	var keywords []string
+
+	fields := strings.Fields(args)
+	for i := 0; i < len(fields); i++ {
+		switch arg := fields[i]; arg {
+		case "-perm":
+			filter.Kind = service.RecordKindPermanent
+		case "-temp":
+			filter.Kind = service.RecordKindTemporary
+		case "-expiry":
+			filter.SortByExpiry = true
+		case "-op", "-group":
+			if i+1 >= len(fields) {
+				return filter, fmt.Errorf("%s 后需要指定ID", arg)
+			}
+			i++
+			value := fields[i]
+			if arg == "-op" && strings.HasPrefix(value, "@") {
+				userID, err := h.userCacheService.GetUserIDByUsername(strings.TrimPrefix(value, "@"))
+				if err != nil {
+					return filter, fmt.Errorf("%s：暂无该用户信息，请使用用户ID", value)
+				}
+				filter.OperatorID = userID
+				continue
+			}
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return filter, fmt.Errorf("无效的ID：%s", value)
+			}
+			if arg == "-op" {
+				filter.OperatorID = id
+			} else {
+				filter.GroupID = id
+			}
+		default:
+			if isFlag(arg) {
+				return filter, fmt.Errorf("未知选项：%s（可用 -perm、-temp、-op、-group、-expiry）", arg)
+			}
+			keywords = append(keywords, arg)
+		}
+	}
+	filter.Keyword = strings.Join(keywords, " ")
+	return filter, nil
+}
+
+// handleRecordListCallback 处理列表按钮（list:<会话ID>:<操作>[:参数]）
+// 操作：page 翻页、kind 切换时长筛选、sort 切换排序、info 查看用户档案、lift 解除记录
+func (h *Handler) handleRecordListCallback(callback *tgbotapi.CallbackQuery) {
+	if callback.Message == nil {
+		return
+	}
+	if !h.canBrowseRecords(callback.From.ID) {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 只有作者和全局管理员可以操作", true)
+		return
+	}
+
+	parts := strings.Split(callback.Data, ":")
+	if len(parts) < 3 {
+		return
+	}
+	sessionID, action := parts[1], parts[2]
+	session := getRecordListSession(sessionID)
+	if session == nil {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "列表已过期，请重新发送命令", true)
+		return
+	}
+
+	var arg int64
+	if len(parts) > 3 {
+		var err error
+		if arg, err = strconv.ParseInt(parts[3], 10, 64); err != nil {
+			return
+		}
+	}
+
+	answer := ""
+	switch action {
+	case "page":
+		session.Page = int(arg)
+	case "kind":
+		// 全部 → 永久 → 临时 → 全部
+		switch session.Filter.Kind {
+		case service.RecordKindAll:
+			session.Filter.Kind = service.RecordKindPermanent
+		case service.RecordKindPermanent:
+			session.Filter.Kind = service.RecordKindTemporary
+		default:
+			session.Filter.Kind = service.RecordKindAll
+		}
+		session.Page = 0
+	case "sort":
+		session.Filter.SortByExpiry = !session.Filter.SortByExpiry
+		session.Page = 0
+	case "info":
+		text, keyboard := h.renderProfile(arg, infoViewProfile, 0)
+		h.sendReplyWithKeyboard(callback.Message.Chat.ID, 0, text, keyboard)
+		h.notificationService.AnswerCallbackQuery(callback.ID, "", false)
+		return
+	case "lift":
+		permission := models.PermUnmute
+		if session.RecordType == service.RecordTypeBan {
+			permission = models.PermUnban
+		}
+		if !h.authorizeCallback(callback, permission) {
+			return
+		}
+		var err error
+		if answer, err = h.liftListedRecord(session.RecordType, arg, callback.From); err != nil {
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ "+err.Error(), true)
+			return
+		}
+	default:
+		return
+	}
+
+	text, keyboard := h.renderRecordList(sessionID, session)
+	h.editMessageWithKeyboard(callback.Message.Chat.ID, callback.Message.MessageID, text, keyboard)
+	h.notificationService.AnswerCallbackQuery(callback.ID, answer, false)
+}
+
+// liftListedRecord 解除列表中的记录，返回提示文本
+func (h *Handler) liftListedRecord(recordType string, recordID int64, operator *tgbotapi.User) (string, error) {
+	_, operatorName := GetUserInfo(operator)
+	if recordType == service.RecordTypeBan {
+		ban, err := h.banService.GetBan(recordID)
+		if err != nil {
+			return "", fmt.Errorf("记录不存在")
+		}
+		if !ban.IsActive() {
+			return "", fmt.Errorf("该记录已失效：%s", recordStatus(ban.Status, ban.ExpireAt, ban.UnbanAt, ban.UnbanReason))
+		}
+		caseID, err := h.liftBan(ban, operator.ID, operatorName)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("✅ 已解除拉黑（案件 #%d）", caseID), nil
+	}
+
+	mute, err := h.muteService.GetMute(recordID)
+	if err != nil {
+		return "", fmt.Errorf("记录不存在")
+	}
+	if !mute.IsActive() {
+		return "", fmt.Errorf("该记录已失效：%s", recordStatus(mute.Status, mute.ExpireAt, mute.UnmuteAt, mute.UnmuteReason))
+	}
+	caseID, err := h.liftMute(mute, operator.ID, operatorName)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("✅ 已解除禁言（案件 #%d）", caseID), nil
+}
+
+// listEntry 列表中的一条记录（拉黑和禁言共用的显示字段）
+type listEntry struct {
+	RecordID     int64
+	UserID       int64
+	Name         string
+	Local        bool
+	GroupName    string
+	ExpireAt     *time.Time
+	OperatorName string
+	Reason       string
+	CaseID       int64
+	Extra        string // 禁言模式等附加说明
+}
+
+// loadRecordList 查询会话当前页的记录
+func (h *Handler) loadRecordList(session *RecordListSession) ([]listEntry, int64, error) {
+	offset := session.Page * recordListPageSize
+	if session.RecordType == service.RecordTypeBan {
+		bans, total, err := h.banService.ListActiveBans(session.Filter, offset, recordListPageSize)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries := make([]listEntry, 0, len(bans))
+		for _, ban := range bans {
+			entries = append(entries, listEntry{
+				RecordID:     ban.ID,
+				UserID:       ban.UserID,
+				Name:         recordName(ban.FullName, ban.Username),
+				Local:        ban.IsLocal(),
+				GroupName:    ban.GroupName,
+				ExpireAt:     ban.ExpireAt,
+				OperatorName: ban.OperatorName,
+				Reason:       ban.Reason,
+				CaseID:       ban.CaseID,
+			})
+		}
+		return entries, total, nil
+	}
+
+	mutes, total, err := h.muteService.ListActiveMutes(session.Filter, offset, recordListPageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	entries := make([]listEntry, 0, len(mutes))
+	for _, mute := range mutes {
+		entries = append(entries, listEntry{
+			RecordID:     mute.ID,
+			UserID:       mute.UserID,
+			Name:         recordName(mute.FullName, mute.Username),
+			Local:        mute.IsLocal(),
+			GroupName:    mute.GroupName,
+			ExpireAt:     mute.ExpireAt,
+			OperatorName: mute.OperatorName,
+			Reason:       mute.Reason,
+			CaseID:       mute.CaseID,
+			Extra:        models.MuteModeName(mute.Mode),
+		})
+	}
+	return entries, total, nil
+}
+
+// renderRecordList 生成列表当前页（纯文本）和按钮：每条记录一行档案/解除按钮，下方为翻页和筛选按钮
+func (h *Handler) renderRecordList(sessionID string, session *RecordListSession) (string, *tgbotapi.InlineKeyboardMarkup) {
+	title, liftLabel := "🚫 拉黑列表", "🔓 解除"
+	if session.RecordType == service.RecordTypeMute {
+		title, liftLabel = "🔇 禁言列表", "🔈 解除"
+	}
+
+	entries, total, err := h.loadRecordList(session)
+	if err != nil {
+		logrus.Errorf("Failed to list %s records: %v", session.RecordType, err)
+		return title + "\n\n❌ 查询失败", nil
+	}
+
+	// 解除记录后当前页可能已超出范围
+	pages := int((total + recordListPageSize - 1) / recordListPageSize)
+	if session.Page > 0 && session.Page >= pages {
+		session.Page = pages - 1
+		if session.Page < 0 {
+			session.Page = 0
+		}
+		entries, total, err = h.loadRecordList(session)
+		if err != nil {
+			logrus.Errorf("Failed to list %s records: %v", session.RecordType, err)
+			return title + "\n\n❌ 查询失败", nil
+		}
+	}
+
+	var sb strings.Builder
+	if pages == 0 {
+		sb.WriteString(title + "（共 0 条）\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("%s（共 %d 条，第 %d/%d 页）\n", title, total, session.Page+1, pages))
+	}
+	if desc := h.describeRecordFilter(session.Filter); desc != "" {
+		sb.WriteString("筛选：" + desc + "\n")
+	}
+	sb.WriteString("\n")
+	if len(entries) == 0 {
+		sb.WriteString("暂无符合条件的记录")
+	}
+
+	prefix := "list:" + sessionID + ":"
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(entries)+2)
+	for i, entry := range entries {
+		n := session.Page*recordListPageSize + i + 1
+		sb.WriteString(fmt.Sprintf("%d. %s（%d）\n", n, entry.Name, entry.UserID))
+
+		details := []string{"全部授权群组"}
+		if entry.Local {
+			details[0] = "仅 " + entry.GroupName
+		} else if entry.GroupName != "" {
+			details = append(details, "来自 "+entry.GroupName)
+		}
+		if entry.Extra != "" {
+			details = append(details, entry.Extra)
+		}
+		if entry.ExpireAt == nil {
+			details = append(details, "永久")
+		} else {
+			details = append(details, "剩余 "+utils.FormatRemainingTime(*entry.ExpireAt))
+		}
+		if entry.OperatorName != "" {
+			details = append(details, "操作人 "+entry.OperatorName)
+		}
+		if entry.CaseID != 0 {
+			details = append(details, fmt.Sprintf("案件 #%d", entry.CaseID))
+		}
+		sb.WriteString("   " + strings.Join(details, " · ") + "\n")
+		if entry.Reason != "" {
+			sb.WriteString("   理由：" + utils.TruncateString(entry.Reason, 80) + "\n")
+		}
+
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("👤 %d. %s", n, utils.TruncateString(entry.Name, 20)), fmt.Sprintf("%sinfo:%d", prefix, entry.UserID)),
+			tgbotapi.NewInlineKeyboardButtonData(liftLabel, fmt.Sprintf("%slift:%d", prefix, entry.RecordID)),
+		))
+	}
+
+	// 翻页
+	var nav []tgbotapi.InlineKeyboardButton
+	if session.Page > 0 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("⬅️ 上一页", fmt.Sprintf("%spage:%d", prefix, session.Page-1)))
+	}
+	if session.Page < pages-1 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("下一页 ➡️", fmt.Sprintf("%spage:%d", prefix, session.Page+1)))
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+
+	// 筛选和排序
+	kindLabel := "时长：全部"
+	switch session.Filter.Kind {
+	case service.RecordKindPermanent:
+		kindLabel = "时长：永久"
+	case service.RecordKindTemporary:
+		kindLabel = "时长：临时"
+	}
+	sortLabel := "排序：最新操作"
+	if session.Filter.SortByExpiry {
+		sortLabel = "排序：即将到期"
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔁 "+kindLabel, prefix+"kind"),
+		tgbotapi.NewInlineKeyboardButtonData("↕️ "+sortLabel, prefix+"sort"),
+	))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return strings.TrimRight(sb.String(), "\n"), &keyboard
+}
+
+// describeRecordFilter 筛选条件说明（时长和排序在按钮上显示）
+func (h *Handler) describeRecordFilter(filter service.RecordFilter) string {
+	var parts []string
+	if filter.OperatorID != 0 {
+		parts = append(parts, "操作人 "+h.profileName(filter.OperatorID))
+	}
+	if filter.GroupID != 0 {
+		groupDesc := strconv.FormatInt(filter.GroupID, 10)
+		if group, err := h.groupService.GetAuthorizedGroup(filter.GroupID); err == nil && group != nil {
+			groupDesc = fmt.Sprintf("%s（%d）", group.GroupName, filter.GroupID)
+		}
+		parts = append(parts, "来源群组 "+groupDesc)
+	}
+	if filter.Keyword != "" {
+		parts = append(parts, fmt.Sprintf("理由包含「%s」", filter.Keyword))
+	}
+	return strings.Join(parts, " · ")
+}
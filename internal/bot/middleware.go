@@ -3,7 +3,10 @@ package bot
 import (
 	"admin-bot/internal/cache"
 	"admin-bot/internal/config"
+	"admin-bot/internal/models"
 	"admin-bot/internal/service"
+	"admin-bot/internal/utils"
+	"fmt"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/sirupsen/logrus"
@@ -19,86 +22,250 @@ type PermissionChecker struct {
 	cfg          *config.Config
 	adminService *service.AdminService
 	groupService *service.GroupService
+	roleService  *service.RoleService
+	modService   *service.ModeratorService
 	bot          *tgbotapi.BotAPI
 }
 
 // NewPermissionChecker 创建权限检查器
 func NewPermissionChecker(cfg *config.Config, adminService *service.AdminService,
-	groupService *service.GroupService, bot *tgbotapi.BotAPI) *PermissionChecker {
+	groupService *service.GroupService, roleService *service.RoleService,
+	modService *service.ModeratorService, bot *tgbotapi.BotAPI) *PermissionChecker {
 	return &PermissionChecker{
 		cfg:          cfg,
 		adminService: adminService,
 		groupService: groupService,
+		roleService:  roleService,
+		modService:   modService,
 		bot:          bot,
 	}
 }
 
-// CheckPermission 检查用户权限
+// CheckPermission 检查用户是否有管理权限（有任意角色即可）
 func (p *PermissionChecker) CheckPermission(message *tgbotapi.Message) (bool, string) {
+	role, reason := p.ResolveRole(message)
+	return role != "", reason
+}
+
+// ResolveRole 确定用户在当前聊天中的角色，没有角色时返回空字符串和原因
+func (p *PermissionChecker) ResolveRole(message *tgbotapi.Message) (string, string) {
+	role, reason, _ := p.resolveRole(message)
+	return role, reason
+}
+
+// resolveRole 确定用户在当前聊天中的角色，通过群管理员身份获得角色时同时返回其成员信息
+func (p *PermissionChecker) resolveRole(message *tgbotapi.Message) (string, string, *tgbotapi.ChatMember) {
 	userID := message.From.ID
 	chatID := message.Chat.ID
+	isGroup := message.Chat.IsGroup() || message.Chat.IsSuperGroup()
 
-	// 1. 检查是否为作者
+	// 1. 检查是否为作者（固定为所有者）
 	if p.cfg.Telegram.IsAuthor(userID) {
 		logrus.Debugf("User %d is author, permission granted", userID)
-		return true, "author"
+		return models.RoleOwner, "author", nil
 	}
 
-	// 2. 检查是否为全局管理员
+	// 2. 查询被分配的角色：角色只决定能做什么，不决定在哪里生效
+	// 用户仍需是全局管理员、本群管理员或本群版主，分配的角色替代对应的默认角色
+	assigned, err := p.roleService.GetUserRole(userID)
+	if err != nil {
+		logrus.Errorf("Failed to get user role: %v", err)
+	}
+	withAssigned := func(defaultRole string) string {
+		if assigned != "" {
+			return assigned
+		}
+		return defaultRole
+	}
+
+	// 3. 检查是否为全局管理员
 	isGlobalAdmin, err := p.adminService.IsGlobalAdmin(userID)
 	if err != nil {
 		logrus.Errorf("Failed to check global admin: %v", err)
 	} else if isGlobalAdmin {
 		logrus.Debugf("User %d is global admin, permission granted", userID)
-		return true, "global_admin"
+		return withAssigned(models.DefaultGlobalAdminRole), "global_admin", nil
 	}
 
-	// 3. 检查群组是否已授权
-	if !message.Chat.IsGroup() && !message.Chat.IsSuperGroup() {
+	// 4. 检查群组是否已授权
+	if !isGroup {
 		// 私聊消息，只有作者和全局管理员可以使用
 		logrus.Debugf("Private chat, only author and global admin allowed")
-		return false, "private_chat_not_allowed"
+		return "", "private_chat_not_allowed", nil
 	}
 
 	// 检查群组授权
 	isAuthorized, err := p.groupService.IsAuthorized(chatID)
 	if err != nil {
 		logrus.Errorf("Failed to check group authorization: %v", err)
-		return false, "check_error"
+		return "", "check_error", nil
 	}
 
 	if !isAuthorized {
 		logrus.Debugf("Group %d is not authorized", chatID)
-		return false, "group_not_authorized"
+		return "", "group_not_authorized", nil
+	}
+
+	// 5. 检查是否为群管理员（可以通过 AdminEnabled 统一关闭，群组设置可以单独覆盖）
+	adminsEnabled := p.groupAdminsEnabled(chatID)
+	adminCheckFailed := false
+	if adminsEnabled {
+		// 使用群管理员名单缓存，避免每条命令都调用 getChatMember
+		// 查询失败时继续检查版主身份，避免版主因 Telegram 接口异常无法操作
+		chatMember, err := service.GetGroupAdmin(p.bot, chatID, userID)
+		if err != nil {
+			logrus.Errorf("Failed to get chat member: %v", err)
+			adminCheckFailed = true
+		} else if chatMember != nil {
+			logrus.Debugf("User %d is group admin, permission granted", userID)
+			return withAssigned(models.DefaultGroupAdminRole), "group_admin", chatMember
+		}
+	}
+
+	// 6. 检查是否为本群版主（不受 AdminEnabled 影响）
+	isModerator, err := p.modService.IsGroupModerator(userID, chatID)
+	if err != nil {
+		logrus.Errorf("Failed to check group moderator: %v", err)
+	} else if isModerator {
+		logrus.Debugf("User %d is group moderator, permission granted", userID)
+		return withAssigned(models.DefaultGroupAdminRole), "group_moderator", nil
 	}
 
-	// 4. 检查是否为群管理员
-	if !p.cfg.System.AdminEnabled {
+	if !adminsEnabled {
 		logrus.Debugf("Admin permission is disabled")
-		return false, "admin_disabled"
+		return "", "admin_disabled", nil
+	}
+	if adminCheckFailed {
+		return "", "check_error", nil
 	}
 
-	// 检查是否为群管理员
-	chatMember, err := p.bot.GetChatMember(tgbotapi.GetChatMemberConfig{
-		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
-			ChatID: chatID,
-			UserID: userID,
-		},
-	})
+	logrus.Debugf("User %d has no permission", userID)
+	return "", "no_permission", nil
+}
 
+// ModeratorGroupIDs 操作人的权限只来自群组版主身份时，返回其可以管理的群组（全局操作只在这些群组执行）
+func (p *PermissionChecker) ModeratorGroupIDs(message *tgbotapi.Message) ([]int64, bool) {
+	if _, reason := p.ResolveRole(message); reason != "group_moderator" {
+		return nil, false
+	}
+	groupIDs, err := p.modService.GetModeratorGroupIDs(message.From.ID)
 	if err != nil {
-		logrus.Errorf("Failed to get chat member: %v", err)
-		return false, "check_error"
+		logrus.Errorf("Failed to get moderator groups: %v", err)
+		// 查询失败时只允许在当前群组操作
+		return []int64{message.Chat.ID}, true
 	}
+	return groupIDs, true
+}
+
+// Authorize 检查用户是否有执行某项命令的权限，没有时返回说明缺少哪项权限的提示
+func (p *PermissionChecker) Authorize(message *tgbotapi.Message, permission string) (bool, string) {
+	_, ok, denial := p.authorize(message, permission)
+	return ok, denial
+}
 
-	// 检查管理员状态
-	if chatMember.Status == "creator" || chatMember.Status == "administrator" {
-		logrus.Debugf("User %d is group admin, permission granted", userID)
-		return true, "group_admin"
+// AuthorizeDuration 检查用户是否有执行某项命令的权限，以及时长是否在角色允许的范围内（seconds 为 0 表示永久）
+func (p *PermissionChecker) AuthorizeDuration(message *tgbotapi.Message, permission string, seconds int) (bool, string) {
+	perm, ok, denial := p.authorize(message, permission)
+	if !ok || perm == nil || perm.MaxDuration == 0 {
+		return ok, denial
 	}
 
-	logrus.Debugf("User %d has no permission", userID)
-	return false, "no_permission"
+	if seconds == 0 || seconds > perm.MaxDuration {
+		return false, fmt.Sprintf("您的角色（%s）%s时长最多 %s，不能%s",
+			models.RoleName(perm.Role), models.PermissionName(permission),
+			utils.FormatDuration(perm.MaxDuration), durationDenial(seconds))
+	}
+	return true, ""
+}
+
+// authorize 查找用户角色对应的权限（所有者返回 nil 表示不受限制）
+func (p *PermissionChecker) authorize(message *tgbotapi.Message, permission string) (*models.RolePermission, bool, string) {
+	role, reason, member := p.resolveRole(message)
+	if role == "" {
+		return nil, false, roleDenialReason(reason)
+	}
+	if role == models.RoleOwner {
+		return nil, true, ""
+	}
+
+	// 群管理员还需要拥有对应的 Telegram 管理员权限（群组设置为任何管理员时不检查）
+	if member != nil && p.requiresAdminRights(message.Chat.ID) {
+		if right, ok := adminRight(member, permission); !ok {
+			return nil, false, fmt.Sprintf("您在本群的管理员权限中没有「%s」，无法执行「%s」", right, models.PermissionName(permission))
+		}
+	}
+
+	perm, err := p.roleService.GetPermission(role, permission)
+	if err != nil {
+		logrus.Errorf("Failed to get role permission: %v", err)
+		return nil, false, "权限检查失败，请稍后重试"
+	}
+	if perm == nil {
+		return nil, false, fmt.Sprintf("您的角色（%s）没有「%s」权限", models.RoleName(role), models.PermissionName(permission))
+	}
+	return perm, true, ""
+}
+
+// requiresAdminRights 群组是否按 Telegram 管理员的具体权限检查（默认检查）
+func (p *PermissionChecker) requiresAdminRights(chatID int64) bool {
+	group, err := p.groupService.GetAuthorizedGroup(chatID)
+	if err != nil {
+		logrus.Errorf("Failed to get authorized group: %v", err)
+		return true
+	}
+	return group == nil || group.RequiresAdminRights()
+}
+
+// groupAdminsEnabled 群组的群管理员是否拥有权限（群组设置优先，跟随全局设置时使用 AdminEnabled）
+func (p *PermissionChecker) groupAdminsEnabled(chatID int64) bool {
+	group, err := p.groupService.GetAuthorizedGroup(chatID)
+	if err != nil {
+		logrus.Errorf("Failed to get authorized group: %v", err)
+		return p.cfg.System.AdminEnabled
+	}
+	if group == nil {
+		return p.cfg.System.AdminEnabled
+	}
+	return group.AdminsEnabled(p.cfg.System.AdminEnabled)
+}
+
+// adminRight 机器人权限对应的 Telegram 管理员权限，返回权限名称和成员是否拥有（群主拥有全部权限）
+func adminRight(member *tgbotapi.ChatMember, permission string) (string, bool) {
+	creator := member.Status == "creator"
+	switch permission {
+	case models.PermKick, models.PermBan, models.PermUnban, models.PermMute, models.PermUnmute, models.PermModify:
+		return "封禁用户", creator || member.CanRestrictMembers
+	case models.PermPurge:
+		return "删除消息", creator || member.CanDeleteMessages
+	case models.PermPin:
+		return "置顶消息", creator || member.CanPinMessages
+	default:
+		return "", true
+	}
+}
+
+// roleDenialReason 没有角色时的提示
+func roleDenialReason(reason string) string {
+	switch reason {
+	case "private_chat_not_allowed":
+		return "私聊中只有作者和全局管理员可以使用此命令"
+	case "group_not_authorized":
+		return "本群组未授权"
+	case "admin_disabled":
+		return "群管理员权限已关闭"
+	case "check_error":
+		return "权限检查失败，请稍后重试"
+	default:
+		return "您不是管理员"
+	}
+}
+
+// durationDenial 超出时长限制时的说明
+func durationDenial(seconds int) string {
+	if seconds == 0 {
+		return "永久"
+	}
+	return "设置为 " + utils.FormatDuration(seconds)
 }
 
 // IsAuthor 检查是否为作者
@@ -149,9 +316,9 @@ func (p *PermissionChecker) IsGlobalAdmin(userID int64) bool {
 	return isAdmin
 }
 
-// CheckUserBanned 检查用户是否被拉黑
-func CheckUserBanned(userID int64, banService *service.BanService) bool {
-	banned, _, err := banService.IsUserBanned(userID)
+// CheckUserBanned 检查用户在指定群组中是否被拉黑
+func CheckUserBanned(userID, groupID int64, banService *service.BanService) bool {
+	banned, _, err := banService.IsUserBanned(userID, groupID)
 	if err != nil {
 		logrus.Errorf("Failed to check user ban status: %v", err)
 		return false
@@ -159,9 +326,9 @@ func CheckUserBanned(userID int64, banService *service.BanService) bool {
 	return banned
 }
 
-// CheckUserMuted 检查用户是否被禁言
-func CheckUserMuted(userID int64, muteService *service.MuteService) bool {
-	muted, _, err := muteService.IsUserMuted(userID)
+// CheckUserMuted 检查用户在指定群组中是否被禁言
+func CheckUserMuted(userID, groupID int64, muteService *service.MuteService) bool {
+	muted, _, err := muteService.IsUserMuted(userID, groupID)
 	if err != nil {
 		logrus.Errorf("Failed to check user mute status: %v", err)
 		return false
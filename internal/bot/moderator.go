@@ -0,0 +1,275 @@
+package bot
+
+import (
+	"admin-bot/internal/models"
+	"admin-bot/internal/utils"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// limitToModeratorGroups 群组版主的全局操作只在其管理的群组执行（只管理当前群组时按仅本群处理）
+func (h *Handler) limitToModeratorGroups(message *tgbotapi.Message, params *CommandParams) {
+	if params.Local {
+		return
+	}
+	groupIDs, limited := h.permissionChecker.ModeratorGroupIDs(message)
+	if !limited {
+		return
+	}
+	if len(groupIDs) <= 1 {
+		params.Local = true
+		return
+	}
+	params.Groups = groupIDs
+}
+
+// recordGroups 保存处罚记录的群组：群组版主的多群操作在每个执行成功的群组各保存一条本群记录（当前群组排在最前），
+// 其他操作只在当前群组保存一条记录
+func recordGroups(chat *tgbotapi.Chat, params *CommandParams, succeeded []models.AuthorizedGroup) []models.AuthorizedGroup {
+	if len(params.Groups) == 0 || len(succeeded) == 0 {
+		return []models.AuthorizedGroup{{GroupID: chat.ID, GroupName: GetChatTitle(chat)}}
+	}
+	groups := make([]models.AuthorizedGroup, 0, len(succeeded))
+	for _, group := range succeeded {
+		if group.GroupID == chat.ID {
+			groups = append(groups, group)
+		}
+	}
+	for _, group := range succeeded {
+		if group.GroupID != chat.ID {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// scopeGroupIDs 仅本群解除时需要解除本群记录的群组（群组版主的多群操作为其管理的群组）
+func scopeGroupIDs(chat *tgbotapi.Chat, params *CommandParams) []int64 {
+	if len(params.Groups) > 0 {
+		return params.Groups
+	}
+	return []int64{chat.ID}
+}
+
+// handleMod 处理 /mod 命令（仅作者，在群组中使用）
+// 用法：/mod add|del（引用回复、@用户名或用户ID），/mod list
+func (h *Handler) handleMod(message *tgbotapi.Message) {
+	if !h.cfg.Telegram.IsAuthor(message.From.ID) {
+		return // 不回复非作者用户
+	}
+	if !message.Chat.IsGroup() && !message.Chat.IsSuperGroup() {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ /mod 只能在群组中使用，私聊请使用 /config 管理群组版主")
+		return
+	}
+	if !h.permissionChecker.IsGroupAuthorized(message.Chat.ID) {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 本群组未授权")
+		return
+	}
+
+	usage := "❌ 用法：/mod add|del（引用回复、@用户名或用户ID），/mod list"
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		h.sendReply(message.Chat.ID, message.MessageID, usage)
+		return
+	}
+
+	action := strings.ToLower(args[0])
+	if action == "list" {
+		h.sendReply(message.Chat.ID, message.MessageID, h.formatGroupModerators(message.Chat.ID))
+		return
+	}
+	if action != "add" && action != "del" {
+		h.sendReply(message.Chat.ID, message.MessageID, usage)
+		return
+	}
+
+	// 子命令会被解析为理由，只取目标用户
+	params, err := ParseCommand(message, h.userCacheService)
+	if err != nil {
+		h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("❌ %s", err.Error()))
+		return
+	}
+
+	groupName := GetChatTitle(message.Chat)
+	results := make([]string, 0, len(params.TargetUsers))
+	for _, userID := range params.TargetUsers {
+		username, fullName := h.resolveTargetUser(message.Chat.ID, userID)
+		if action == "add" {
+			err = h.moderatorService.AddModerator(message.Chat.ID, groupName, userID, username, fullName, message.From.ID)
+		} else {
+			var removed bool
+			if removed, err = h.moderatorService.RemoveModerator(message.Chat.ID, userID); err == nil && !removed {
+				err = fmt.Errorf("该用户不是本群版主")
+			}
+		}
+		if err != nil {
+			results = append(results, fmt.Sprintf("❌ %s（%d）：%s", fullName, userID, err.Error()))
+			continue
+		}
+
+		verb := "已添加为本群版主"
+		if action == "del" {
+			verb = "已移除本群版主"
+		}
+		results = append(results, fmt.Sprintf("✅ %s（%d）%s", fullName, userID, verb))
+
+		logrus.WithFields(logrus.Fields{
+			"操作人":  message.From.ID,
+			"用户ID": userID,
+			"群组ID": message.Chat.ID,
+			"操作":   action,
+		}).Info("👮 已修改群组版主")
+	}
+
+	h.sendReply(message.Chat.ID, message.MessageID, strings.Join(results, "\n"))
+}
+
+// formatGroupModerators 本群版主列表（纯文本）
+func (h *Handler) formatGroupModerators(groupID int64) string {
+	moderators, err := h.moderatorService.GetGroupModerators(groupID)
+	if err != nil {
+		logrus.Errorf("Failed to get group moderators: %v", err)
+		return "❌ 获取版主列表失败"
+	}
+	if len(moderators) == 0 {
+		return "👮 本群暂无版主"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("👮 本群版主（%d）\n", len(moderators)))
+	for _, moderator := range moderators {
+		sb.WriteString(fmt.Sprintf("• %s（%d）\n", recordName(moderator.FullName, moderator.Username), moderator.UserID))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// handleModeratorsCallback 显示群组版主管理页面
+func (h *Handler) handleModeratorsCallback(callback *tgbotapi.CallbackQuery) {
+	moderators, err := h.moderatorService.GetAllModerators()
+	if err != nil {
+		logrus.Errorf("Failed to get group moderators: %v", err)
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 获取列表失败", true)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("👮 *群组版主*\n\n")
+	sb.WriteString("版主只能管理指定的群组，不需要是该群的 Telegram 管理员；发起的全局操作只在其管理的群组执行\n\n")
+	if len(moderators) == 0 {
+		sb.WriteString("暂无群组版主\n")
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var lastGroupID int64
+	for _, moderator := range moderators {
+		if moderator.GroupID != lastGroupID {
+			groupName := moderator.GroupName
+			if groupName == "" {
+				groupName = strconv.FormatInt(moderator.GroupID, 10)
+			}
+			sb.WriteString(fmt.Sprintf("\n*%s*（`%d`）\n", utils.EscapeMarkdown(groupName), moderator.GroupID))
+			lastGroupID = moderator.GroupID
+		}
+		name := recordName(moderator.FullName, moderator.Username)
+		sb.WriteString(fmt.Sprintf("• %s（`%d`）\n", utils.EscapeMarkdown(name), moderator.UserID))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🗑 %s @ %s", name, utils.TruncateString(moderator.GroupName, 20)),
+				fmt.Sprintf("config:del_mod_%d_%d", moderator.GroupID, moderator.UserID)),
+		))
+	}
+	sb.WriteString("\n在群组中也可以使用 /mod add、/mod del 管理本群版主")
+
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("➕ 添加版主", "config:add_mod"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ 返回", "config:back"),
+		),
+	)
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	h.notificationService.EditMessage(callback.Message.Chat.ID, callback.Message.MessageID, sb.String(), &keyboard)
+	h.notificationService.AnswerCallbackQuery(callback.ID, "", false)
+}
+
+// handleAddModCallback 处理添加群组版主回调
+func (h *Handler) handleAddModCallback(callback *tgbotapi.CallbackQuery) {
+	setUserState(callback.From.ID, "waiting_moderator", nil)
+
+	text := "请发送群组ID和用户ID，用空格分隔\n\n格式示例：`-1001234567890 123456789`\n\n群组必须已授权\n\n发送 /cancel 取消操作"
+	h.notificationService.EditMessage(callback.Message.Chat.ID, callback.Message.MessageID, text, nil)
+	h.notificationService.AnswerCallbackQuery(callback.ID, "请发送群组ID和用户ID", false)
+}
+
+// handleDelModCallback 移除群组版主（config:del_mod_<群组ID>_<用户ID>）
+func (h *Handler) handleDelModCallback(callback *tgbotapi.CallbackQuery, action string) {
+	parts := strings.Split(strings.TrimPrefix(action, "del_mod_"), "_")
+	if len(parts) != 2 {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 无效的操作", true)
+		return
+	}
+	groupID, err1 := strconv.ParseInt(parts[0], 10, 64)
+	userID, err2 := strconv.ParseInt(parts[1], 10, 64)
+	if err1 != nil || err2 != nil {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 无效的操作", true)
+		return
+	}
+
+	if _, err := h.moderatorService.RemoveModerator(groupID, userID); err != nil {
+		logrus.Errorf("Failed to remove group moderator: %v", err)
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 删除失败", true)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"操作人":  callback.From.ID,
+		"用户ID": userID,
+		"群组ID": groupID,
+	}).Info("👮 已移除群组版主")
+
+	// 刷新页面
+	h.handleModeratorsCallback(callback)
+}
+
+// handleWaitingModerator 处理等待输入群组版主（仅私聊）
+func (h *Handler) handleWaitingModerator(message *tgbotapi.Message) {
+	parts := strings.Fields(message.Text)
+	if len(parts) != 2 {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 格式错误，请发送：群组ID 用户ID\n\n例如：-1001234567890 123456789")
+		return
+	}
+	groupID, err1 := strconv.ParseInt(parts[0], 10, 64)
+	userID, err2 := strconv.ParseInt(parts[1], 10, 64)
+	if err1 != nil || err2 != nil {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 无效的ID格式，请重新输入或发送 /cancel 取消")
+		return
+	}
+
+	group, err := h.groupService.GetAuthorizedGroup(groupID)
+	if err != nil || group == nil {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 该群组未授权，请重新输入或发送 /cancel 取消")
+		return
+	}
+
+	username, fullName := h.resolveTargetUser(groupID, userID)
+	if err := h.moderatorService.AddModerator(groupID, group.GroupName, userID, username, fullName, message.From.ID); err != nil {
+		h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("❌ %s", err.Error()))
+		return
+	}
+
+	clearUserState(message.From.ID)
+	h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("✅ 已将 %s（%d）添加为 %s 的版主", fullName, userID, group.GroupName))
+
+	logrus.WithFields(logrus.Fields{
+		"操作人":  message.From.ID,
+		"用户ID": userID,
+		"群组ID": groupID,
+	}).Info("👮 已添加群组版主")
+
+	h.showConfigMenu(message.Chat.ID)
+}
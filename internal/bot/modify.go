@@ -0,0 +1,556 @@
+package bot
+
+import (
+	"admin-bot/internal/models"
+	"admin-bot/internal/service"
+	"admin-bot/internal/utils"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// handleExtend 处理 /extend 命令：延长生效中的拉黑或禁言（perm 改为永久，until 指定新的结束时间）
+func (h *Handler) handleExtend(message *tgbotapi.Message) {
+	h.handleModifyDuration(message, false)
+}
+
+// handleShorten 处理 /shorten 命令：缩短生效中的拉黑或禁言
+func (h *Handler) handleShorten(message *tgbotapi.Message) {
+	h.handleModifyDuration(message, true)
+}
+
+// handleModifyDuration 修改目标用户生效中记录的到期时间
+func (h *Handler) handleModifyDuration(message *tgbotapi.Message, shorten bool) {
+	params, ok := h.parseModifyCommand(message)
+	if !ok {
+		return
+	}
+
+	if params.Duration == 0 && !params.Permanent {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 请指定时长（例如 1d、perm 或 until 2026-11-01）")
+		return
+	}
+	if shorten && (params.Permanent || params.Until) {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ /shorten 只能指定要缩短的时长，指定结束时间请使用 /extend until")
+		return
+	}
+
+	// 修改后为永久或超过审批天数的拉黑需要审批，批准后再执行
+	if reason, seconds := h.modifyApproval(message, params, shorten); reason != "" {
+		h.requestApproval(&approvalRequest{
+			Message:  message,
+			Params:   params,
+			Reason:   reason,
+			Duration: seconds,
+		}, nil)
+		return
+	}
+	h.executeModifyDuration(message, params, shorten, nil)
+}
+
+// executeModifyDuration 修改目标用户生效中记录的到期时间，statusMsg 不为空时在该消息上显示结果（审批通过后执行）
+func (h *Handler) executeModifyDuration(message *tgbotapi.Message, params *CommandParams, shorten bool, statusMsg *tgbotapi.Message) {
+	_, operatorName := GetUserInfo(message.From)
+	results := make([]string, 0, len(params.TargetUsers))
+	snapshots := make(map[int64]*recordSnapshot, len(params.TargetUsers))
+	for _, userID := range params.TargetUsers {
+		ban, mute, err := h.findModifiableRecord(userID, message.Chat.ID, params)
+		var note string
+		var caseID int64
+		var snapshot *recordSnapshot
+		if err == nil {
+			var expireAt *time.Time
+			if ban != nil {
+				snapshot = banSnapshot(ban)
+				if expireAt, err = newExpireTime(ban.ExpireAt, params, shorten); err == nil {
+					if err = h.checkModifiedDuration(message, models.PermBan, expireAt); err == nil {
+						note, caseID, err = h.changeBanExpiry(ban, expireAt, message.From.ID, operatorName)
+					}
+				}
+			} else {
+				snapshot = muteSnapshot(mute)
+				if expireAt, err = newExpireTime(mute.ExpireAt, params, shorten); err == nil {
+					if err = h.checkModifiedDuration(message, models.PermMute, expireAt); err == nil {
+						note, caseID, err = h.changeMuteExpiry(mute, expireAt, message.From.ID, operatorName)
+					}
+				}
+			}
+		}
+		if err == nil && caseID != 0 {
+			snapshots[caseID] = snapshot.edited(ban, mute)
+		}
+		results = append(results, modifyResultLine(userID, ban != nil, note, err, len(params.TargetUsers) > 1))
+	}
+
+	keyboard := h.undoEditKeyboard(message.From.ID, snapshots)
+	if statusMsg != nil {
+		h.editMessageWithKeyboard(statusMsg.Chat.ID, statusMsg.MessageID, strings.Join(results, "\n"), keyboard)
+	} else {
+		h.sendReplyWithKeyboard(message.Chat.ID, message.MessageID, strings.Join(results, "\n"), keyboard)
+	}
+}
+
+// modifyApproval 检查修改后的拉黑是否需要审批（永久或超过审批天数），返回需要审批的原因和修改后最长的剩余时长（0 表示永久）
+// 找不到记录或操作人无权修改的目标不计入，执行时再报告错误
+func (h *Handler) modifyApproval(message *tgbotapi.Message, params *CommandParams, shorten bool) (string, int) {
+	if h.approvalExempt(message.From.ID) {
+		return "", 0
+	}
+
+	reason, longest := "", -1
+	for _, userID := range params.TargetUsers {
+		ban, _, err := h.findModifiableRecord(userID, message.Chat.ID, params)
+		if err != nil || ban == nil {
+			continue
+		}
+		expireAt, err := newExpireTime(ban.ExpireAt, params, shorten)
+		if err != nil || h.checkModifiedDuration(message, models.PermBan, expireAt) != nil {
+			continue
+		}
+		seconds := remainingSeconds(expireAt)
+		if r := h.banExpiryApproval(message.From.ID, expireAt); r != "" && longer(seconds, longest) {
+			reason, longest = r, seconds
+		}
+	}
+	return reason, longest
+}
+
+// banExpiryApproval 拉黑改为 expireAt（空表示永久）时需要审批的原因，不需要审批时返回空字符串
+func (h *Handler) banExpiryApproval(operatorID int64, expireAt *time.Time) string {
+	if h.approvalExempt(operatorID) {
+		return ""
+	}
+	return h.cfg.System.Approval.Reason(true, remainingSeconds(expireAt), 1)
+}
+
+// remainingSeconds 到期时间对应的剩余时长（expireAt 为空表示永久，返回 0）
+func remainingSeconds(expireAt *time.Time) int {
+	if expireAt == nil {
+		return 0
+	}
+	return int(time.Until(*expireAt).Seconds())
+}
+
+// longer 时长 a 是否比 b 长（0 表示永久，b 为负数表示还没有时长）
+func longer(a, b int) bool {
+	if b < 0 {
+		return true
+	}
+	return b != 0 && (a == 0 || a > b)
+}
+
+// handleEditReason 处理 /editreason 命令：修改生效中的拉黑或禁言的理由
+func (h *Handler) handleEditReason(message *tgbotapi.Message) {
+	params, ok := h.parseModifyCommand(message)
+	if !ok {
+		return
+	}
+
+	if params.Reason == "" {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 请填写新的理由")
+		return
+	}
+	h.matchReasonPreset(params)
+
+	_, operatorName := GetUserInfo(message.From)
+	results := make([]string, 0, len(params.TargetUsers))
+	snapshots := make(map[int64]*recordSnapshot, len(params.TargetUsers))
+	for _, userID := range params.TargetUsers {
+		ban, mute, err := h.findModifiableRecord(userID, message.Chat.ID, params)
+		var note string
+		var caseID int64
+		var snapshot *recordSnapshot
+		if err == nil {
+			if ban != nil {
+				snapshot = banSnapshot(ban)
+				note, caseID, err = h.changeBanReason(ban, params.Reason, params.ReasonCode, message.From.ID, operatorName)
+			} else {
+				snapshot = muteSnapshot(mute)
+				note, caseID, err = h.changeMuteReason(mute, params.Reason, params.ReasonCode, message.From.ID, operatorName)
+			}
+		}
+		if err == nil && caseID != 0 {
+			snapshots[caseID] = snapshot.edited(ban, mute)
+		}
+		results = append(results, modifyResultLine(userID, ban != nil, note, err, len(params.TargetUsers) > 1))
+	}
+
+	h.sendReplyWithKeyboard(message.Chat.ID, message.MessageID, strings.Join(results, "\n"),
+		h.undoEditKeyboard(message.From.ID, snapshots))
+}
+
+// parseModifyCommand 检查权限并解析修改记录的命令
+func (h *Handler) parseModifyCommand(message *tgbotapi.Message) (*CommandParams, bool) {
+	if !h.authorize(message, models.PermModify) {
+		return nil, false
+	}
+
+	params, err := ParseCommand(message, h.userCacheService)
+	if err != nil {
+		h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("❌ %s", err.Error()))
+		return nil, false
+	}
+	// 群组版主只能修改本群的记录
+	if _, limited := h.permissionChecker.ModeratorGroupIDs(message); limited {
+		params.Local = true
+	}
+	return params, true
+}
+
+// findModifiableRecord 查找目标用户在当前群组中生效的拉黑或禁言记录（两者都有时需要用 -ban/-mute 指定）
+func (h *Handler) findModifiableRecord(userID, chatID int64, params *CommandParams) (*models.Blacklist, *models.MuteList, error) {
+	var ban *models.Blacklist
+	var mute *models.MuteList
+	var err error
+
+	if params.RecordType != service.RecordTypeMute {
+		if ban, err = h.banService.GetActiveBan(userID, chatID, params.Local); err != nil {
+			logrus.Errorf("Failed to get active ban: %v", err)
+			return nil, nil, fmt.Errorf("查询拉黑记录失败")
+		}
+	}
+	if params.RecordType != service.RecordTypeBan {
+		if mute, err = h.muteService.GetActiveMute(userID, chatID, params.Local); err != nil {
+			logrus.Errorf("Failed to get active mute: %v", err)
+			return nil, nil, fmt.Errorf("查询禁言记录失败")
+		}
+	}
+
+	switch {
+	case ban != nil && mute != nil:
+		return nil, nil, fmt.Errorf("该用户同时被拉黑和禁言，请加上 -ban 或 -mute 指定要修改的记录")
+	case ban == nil && mute == nil:
+		switch params.RecordType {
+		case service.RecordTypeBan:
+			return nil, nil, fmt.Errorf("该用户没有生效中的拉黑记录")
+		case service.RecordTypeMute:
+			return nil, nil, fmt.Errorf("该用户没有生效中的禁言记录")
+		default:
+			return nil, nil, fmt.Errorf("该用户没有生效中的拉黑或禁言记录")
+		}
+	}
+	return ban, mute, nil
+}
+
+// newExpireTime 计算修改后的到期时间（返回空表示永久）
+func newExpireTime(current *time.Time, params *CommandParams, shorten bool) (*time.Time, error) {
+	if params.Permanent {
+		if current == nil {
+			return nil, fmt.Errorf("已经是永久")
+		}
+		return nil, nil
+	}
+
+	if params.Until {
+		expireAt := time.Now().Add(time.Duration(params.Duration) * time.Second)
+		return &expireAt, nil
+	}
+
+	if current == nil {
+		if shorten {
+			return nil, fmt.Errorf("永久记录无法缩短，请使用 /extend until 日期 指定结束时间")
+		}
+		return nil, fmt.Errorf("已经是永久，无需延长")
+	}
+
+	delta := params.Duration
+	if shorten {
+		delta = -delta
+	}
+	expireAt := utils.ExtendExpireTime(*current, delta)
+
+	// Telegram 只接受 30 秒到 366 天的限制时长
+	remaining := int(time.Until(expireAt).Seconds())
+	if remaining < utils.MinDurationSeconds {
+		return nil, fmt.Errorf("修改后剩余时间不足 30 秒，请直接解除")
+	}
+	if remaining > utils.MaxDurationSeconds {
+		return nil, fmt.Errorf("修改后剩余时间超过 366 天，永久请使用 perm")
+	}
+	return &expireAt, nil
+}
+
+// checkModifiedDuration 检查修改后的剩余时长是否在操作人角色允许的范围内（expireAt 为空表示永久）
+func (h *Handler) checkModifiedDuration(message *tgbotapi.Message, permission string, expireAt *time.Time) error {
+	if ok, denial := h.permissionChecker.AuthorizeDuration(message, permission, remainingSeconds(expireAt)); !ok {
+		return fmt.Errorf("%s", denial)
+	}
+	return nil
+}
+
+// modifyResultLine 生成修改结果（多个目标时加上用户ID）
+func modifyResultLine(userID int64, isBan bool, note string, err error, withUser bool) string {
+	prefix := ""
+	if withUser {
+		prefix = fmt.Sprintf("%d：", userID)
+	}
+	if err != nil {
+		return fmt.Sprintf("❌ %s%s", prefix, err.Error())
+	}
+	kind := "禁言"
+	if isBan {
+		kind = "拉黑"
+	}
+	return fmt.Sprintf("✅ %s已修改%s，%s", prefix, kind, note)
+}
+
+// changeBanExpiry 修改拉黑到期时间并重新执行 Telegram 限制，返回修改说明和修改案件编号
+func (h *Handler) changeBanExpiry(ban *models.Blacklist, expireAt *time.Time, operatorID int64, operatorName string) (string, int64, error) {
+	oldExpireAt := ban.ExpireAt
+	records := h.caseBans(ban)
+	if err := h.banService.UpdateBanExpiry(ban, expireAt); err != nil {
+		logrus.Errorf("Failed to update ban expiry: %v", err)
+		return "", 0, fmt.Errorf("保存失败")
+	}
+	for _, record := range records[1:] {
+		if err := h.banService.UpdateBanExpiry(record, expireAt); err != nil {
+			logrus.Errorf("Failed to update ban expiry of record %d: %v", record.ID, err)
+		}
+	}
+
+	// Telegram 中的封禁到期时间也需要同步修改
+	for _, record := range records {
+		h.reapplyBanExpiry(record)
+	}
+
+	note := fmt.Sprintf("到期时间：%s → %s", utils.FormatExpireAt(oldExpireAt), utils.FormatExpireAt(expireAt))
+	return note, h.afterBanChange(ban, operatorID, operatorName, note), nil
+}
+
+// changeMuteExpiry 修改禁言到期时间并重新执行 Telegram 限制，返回修改说明和修改案件编号
+func (h *Handler) changeMuteExpiry(mute *models.MuteList, expireAt *time.Time, operatorID int64, operatorName string) (string, int64, error) {
+	oldExpireAt := mute.ExpireAt
+	records := h.caseMutes(mute)
+	if err := h.muteService.UpdateMuteExpiry(mute, expireAt); err != nil {
+		logrus.Errorf("Failed to update mute expiry: %v", err)
+		return "", 0, fmt.Errorf("保存失败")
+	}
+	for _, record := range records[1:] {
+		if err := h.muteService.UpdateMuteExpiry(record, expireAt); err != nil {
+			logrus.Errorf("Failed to update mute expiry of record %d: %v", record.ID, err)
+		}
+	}
+
+	// Telegram 中的禁言到期时间也需要同步修改
+	for _, record := range records {
+		h.reapplyMuteExpiry(record)
+	}
+
+	note := fmt.Sprintf("到期时间：%s → %s", utils.FormatExpireAt(oldExpireAt), utils.FormatExpireAt(expireAt))
+	return note, h.afterMuteChange(mute, operatorID, operatorName, note), nil
+}
+
+// reapplyBanExpiry 按记录当前的到期时间在 Telegram 中重新封禁
+func (h *Handler) reapplyBanExpiry(ban *models.Blacklist) {
+	untilDate := telegramUntilDate(ban.ExpireAt)
+	h.applyToRecordGroups(ban.GroupID, ban.IsLocal(), func(groupID int64) tgbotapi.Chattable {
+		return tgbotapi.KickChatMemberConfig{
+			ChatMemberConfig: tgbotapi.ChatMemberConfig{
+				ChatID: groupID,
+				UserID: ban.UserID,
+			},
+			UntilDate: untilDate,
+		}
+	})
+}
+
+// reapplyMuteExpiry 按记录当前的到期时间在 Telegram 中重新禁言
+func (h *Handler) reapplyMuteExpiry(mute *models.MuteList) {
+	untilDate := telegramUntilDate(mute.ExpireAt)
+	h.applyToRecordGroups(mute.GroupID, mute.IsLocal(), func(groupID int64) tgbotapi.Chattable {
+		return tgbotapi.RestrictChatMemberConfig{
+			ChatMemberConfig: tgbotapi.ChatMemberConfig{
+				ChatID: groupID,
+				UserID: mute.UserID,
+			},
+			Permissions: service.GetMutePermissions(h.bot, groupID, mute.Mode),
+			UntilDate:   untilDate,
+		}
+	})
+}
+
+// changeBanReason 修改拉黑理由，返回修改说明和修改案件编号
+func (h *Handler) changeBanReason(ban *models.Blacklist, reason, reasonCode string, operatorID int64, operatorName string) (string, int64, error) {
+	oldReason := ban.Reason
+	records := h.caseBans(ban)
+	if err := h.banService.UpdateBanReason(ban, reason, reasonCode); err != nil {
+		logrus.Errorf("Failed to update ban reason: %v", err)
+		return "", 0, fmt.Errorf("保存失败")
+	}
+	for _, record := range records[1:] {
+		if err := h.banService.UpdateBanReason(record, reason, reasonCode); err != nil {
+			logrus.Errorf("Failed to update ban reason of record %d: %v", record.ID, err)
+		}
+	}
+
+	note := fmt.Sprintf("理由：%s → %s", reasonOrNone(oldReason), ban.Reason)
+	return note, h.afterBanChange(ban, operatorID, operatorName, note), nil
+}
+
+// changeMuteReason 修改禁言理由，返回修改说明和修改案件编号
+func (h *Handler) changeMuteReason(mute *models.MuteList, reason, reasonCode string, operatorID int64, operatorName string) (string, int64, error) {
+	oldReason := mute.Reason
+	records := h.caseMutes(mute)
+	if err := h.muteService.UpdateMuteReason(mute, reason, reasonCode); err != nil {
+		logrus.Errorf("Failed to update mute reason: %v", err)
+		return "", 0, fmt.Errorf("保存失败")
+	}
+	for _, record := range records[1:] {
+		if err := h.muteService.UpdateMuteReason(record, reason, reasonCode); err != nil {
+			logrus.Errorf("Failed to update mute reason of record %d: %v", record.ID, err)
+		}
+	}
+
+	note := fmt.Sprintf("理由：%s → %s", reasonOrNone(oldReason), mute.Reason)
+	return note, h.afterMuteChange(mute, operatorID, operatorName, note), nil
+}
+
+// reasonOrNone 空理由显示为"无"
+func reasonOrNone(reason string) string {
+	if reason == "" {
+		return "无"
+	}
+	return reason
+}
+
+// caseBans 与 ban 属于同一案件的生效中拉黑记录（群组版主的多群操作在每个群组各保存一条本群记录），ban 排在最前
+func (h *Handler) caseBans(ban *models.Blacklist) []*models.Blacklist {
+	records := []*models.Blacklist{ban}
+	if !ban.IsLocal() || ban.CaseID == 0 {
+		return records
+	}
+	bans, err := h.banService.GetActiveBansByCase(ban.CaseID)
+	if err != nil {
+		logrus.Errorf("Failed to get bans of case %d: %v", ban.CaseID, err)
+		return records
+	}
+	for i := range bans {
+		if bans[i].ID != ban.ID {
+			records = append(records, &bans[i])
+		}
+	}
+	return records
+}
+
+// caseMutes 与 mute 属于同一案件的生效中禁言记录（群组版主的多群操作在每个群组各保存一条本群记录），mute 排在最前
+func (h *Handler) caseMutes(mute *models.MuteList) []*models.MuteList {
+	records := []*models.MuteList{mute}
+	if !mute.IsLocal() || mute.CaseID == 0 {
+		return records
+	}
+	mutes, err := h.muteService.GetActiveMutesByCase(mute.CaseID)
+	if err != nil {
+		logrus.Errorf("Failed to get mutes of case %d: %v", mute.CaseID, err)
+		return records
+	}
+	for i := range mutes {
+		if mutes[i].ID != mute.ID {
+			records = append(records, &mutes[i])
+		}
+	}
+	return records
+}
+
+// applyToRecordGroups 在记录作用的群组中重新执行限制（仅本群记录只作用于记录所在群组），build 返回 nil 时跳过该群组
+func (h *Handler) applyToRecordGroups(groupID int64, local bool, build func(groupID int64) tgbotapi.Chattable) {
+	groupIDs := []int64{groupID}
+	if !local {
+		groups, err := h.groupService.GetAuthorizedGroups()
+		if err != nil {
+			logrus.Errorf("Failed to get authorized groups: %v", err)
+			return
+		}
+		groupIDs = make([]int64, 0, len(groups))
+		for _, group := range groups {
+			groupIDs = append(groupIDs, group.GroupID)
+		}
+	}
+
+	tasks := make([]func(), 0, len(groupIDs))
+	for _, id := range groupIDs {
+		gid := id // 捕获变量
+		tasks = append(tasks, func() {
+			c := build(gid)
+			if c == nil {
+				return
+			}
+			h.rateLimiter.Wait(gid)
+			if _, err := h.bot.Request(c); err != nil {
+				logrus.Errorf("Failed to reapply restriction in group %d: %v", gid, err)
+			}
+		})
+	}
+	utils.ParallelExecuteWithLimit(tasks, 5)
+}
+
+// afterBanChange 记录拉黑修改日志并同步编辑频道通知，返回修改案件编号
+func (h *Handler) afterBanChange(ban *models.Blacklist, operatorID int64, operatorName, note string) int64 {
+	caseID := h.logCase(&models.OperationLog{
+		OperationType:  models.OpTypeBanEdit,
+		TargetUserID:   ban.UserID,
+		TargetUsername: ban.Username,
+		GroupID:        ban.GroupID,
+		GroupName:      ban.GroupName,
+		OperatorID:     operatorID,
+		OperatorName:   operatorName,
+		Reason:         note,
+		Duration:       ban.Duration,
+		Scope:          ban.Scope,
+		RecordID:       ban.ID,
+	})
+
+	if err := h.notificationService.UpdateBanNotification(ban, h.groupUsername(ban.GroupID),
+		fmt.Sprintf("%s（%s 修改）", note, operatorName)); err != nil {
+		logrus.Warnf("Failed to edit ban notification: %v", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"记录ID": ban.ID,
+		"用户ID": ban.UserID,
+		"操作人":  operatorID,
+		"修改内容": note,
+	}).Info("✏️ 拉黑记录已修改")
+	return caseID
+}
+
+// afterMuteChange 记录禁言修改日志并同步编辑频道通知，返回修改案件编号
+func (h *Handler) afterMuteChange(mute *models.MuteList, operatorID int64, operatorName, note string) int64 {
+	caseID := h.logCase(&models.OperationLog{
+		OperationType:  models.OpTypeMuteEdit,
+		TargetUserID:   mute.UserID,
+		TargetUsername: mute.Username,
+		GroupID:        mute.GroupID,
+		GroupName:      mute.GroupName,
+		OperatorID:     operatorID,
+		OperatorName:   operatorName,
+		Reason:         note,
+		Duration:       mute.Duration,
+		Scope:          mute.Scope,
+		RecordID:       mute.ID,
+	})
+
+	if err := h.notificationService.UpdateMuteNotification(mute, h.groupUsername(mute.GroupID),
+		fmt.Sprintf("%s（%s 修改）", note, operatorName)); err != nil {
+		logrus.Warnf("Failed to edit mute notification: %v", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"记录ID": mute.ID,
+		"用户ID": mute.UserID,
+		"操作人":  operatorID,
+		"修改内容": note,
+	}).Info("✏️ 禁言记录已修改")
+	return caseID
+}
+
+// groupUsername 获取授权群组的公开用户名（用于生成通知中的群组链接）
+func (h *Handler) groupUsername(groupID int64) string {
+	group, err := h.groupService.GetAuthorizedGroup(groupID)
+	if err != nil || group == nil {
+		return ""
+	}
+	return group.Username
+}
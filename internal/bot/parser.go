@@ -1,43 +1,89 @@
 package bot
 
 import (
+	"admin-bot/internal/config"
+	"admin-bot/internal/models"
 	"admin-bot/internal/service"
 	"admin-bot/internal/utils"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf16"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 // CommandParams 命令参数
 type CommandParams struct {
-	TargetUsers []int64 // 目标用户ID列表
-	Duration    int     // 时长（秒）
-	Reason      string  // 理由
-	IsBatch     bool    // 是否为批量操作
+	TargetUsers   []int64 // 目标用户ID列表
+	Duration      int     // 时长（秒），0 表示永久
+	Permanent     bool    // 是否明确指定为永久（perm/永久）
+	Reason        string  // 理由
+	ReasonCode    string  // 预设理由代码（选择预设或理由与预设一致时设置）
+	IsBatch       bool    // 是否为批量操作
+	Local         bool    // 是否仅作用于当前群组（-local）
+	MuteMode      string  // 禁言模式（-media/-links/-stickers/-readonly）
+	ChooseMode    bool    // 是否通过内联菜单选择禁言模式（-mode）
+	DeleteMessage bool    // 是否删除被回复的消息（-d）
+	PurgeCount    int     // 清理目标用户最近消息的数量（-purge N），0 表示不清理
+	Silent        bool    // 静默执行，成功时不在群内回复（-silent）
+	NotifyTarget  bool    // 私聊通知目标用户（-notify）
+	Until         bool    // 时长通过 until 指定了结束时间
+	RecordType    string  // 修改记录时指定的记录类型（-ban/-mute），为空时自动判断
+	Groups        []int64 // 群组版主的全局操作只在这些群组执行（记录按仅本群保存）
+
+	reasonChosen      bool    // 已通过预设菜单选择过理由（包括不填写理由）
+	durationChosen    bool    // 已通过预设菜单选择过时长
+	protectionChecked bool    // 已检查过受保护的目标用户
+	protectedUsers    []int64 // 等待作者确认的受保护用户
+}
+
+// 清理消息数量
+const (
+	DefaultPurgeCount = 50  // -purge 未指定数量时的默认值
+	MaxPurgeCount     = 500 // 单次最多清理的消息数
+)
+
+// Scope 返回记录使用的作用范围（限定群组时按仅本群记录，避免在其他群组生效）
+func (p *CommandParams) Scope() string {
+	if p.Local || len(p.Groups) > 0 {
+		return models.ScopeLocal
+	}
+	return models.ScopeGlobal
 }
 
 // ParseCommand 解析命令
-func ParseCommand(message *tgbotapi.Message, bot *tgbotapi.BotAPI, userCacheService *service.UserCacheService) (*CommandParams, error) {
+// 目标用户可以通过引用回复、@username、数字用户ID、id:用户ID、文本提及（没有用户名的用户）或 tg://user?id= 链接指定
+// 理由开始后的纯数字（例如理由中的手机号）作为理由，此后需要用 id:用户ID 等明确形式指定目标
+func ParseCommand(message *tgbotapi.Message, userCacheService *service.UserCacheService) (*CommandParams, error) {
 	params := &CommandParams{
 		TargetUsers: make([]int64, 0),
 		IsBatch:     false,
+		MuteMode:    models.MuteModeReadOnly,
 	}
 
 	// 1. 检查是否有引用回复
 	if message.ReplyToMessage != nil {
-		params.TargetUsers = append(params.TargetUsers, message.ReplyToMessage.From.ID)
+		targetUserID, err := replyTargetUser(message)
+		if err != nil {
+			return nil, err
+		}
+		params.TargetUsers = append(params.TargetUsers, targetUserID)
 		// 引用回复不算批量操作
 		params.IsBatch = false
 	}
 
-	// 2. 解析命令参数
-	args := strings.Fields(message.Text)
+	// 2. 解析命令参数（文本提及先替换为 tg://user?id= 形式，以便按空格切分）
+	args, err := tokenize(normalizeMentions(message.Text, message.Entities))
+	if err != nil {
+		return nil, err
+	}
 	if len(args) <= 1 {
 		// 只有命令本身，没有其他参数
 		if len(params.TargetUsers) == 0 {
-			return nil, fmt.Errorf("请指定目标用户（引用回复或@用户名）")
+			return nil, fmt.Errorf("请指定目标用户（引用回复、@用户名或用户ID）")
 		}
 		return params, nil
 	}
@@ -46,35 +92,104 @@ func ParseCommand(message *tgbotapi.Message, bot *tgbotapi.BotAPI, userCacheServ
 	remainingArgs := args[1:]
 	var timeStr string
 	var reasonParts []string
+	var targetErrors []string
 	userCount := 0
+	reasonStarted := false
 
-	for _, arg := range remainingArgs {
-		if strings.HasPrefix(arg, "@") {
-			// 用户名
+	for i := 0; i < len(remainingArgs); i++ {
+		token := remainingArgs[i]
+		arg := token.Value
+		if token.Quoted {
+			// 引号内的内容整体作为理由
+			reasonParts = append(reasonParts, arg)
+			reasonStarted = true
+		} else if strings.HasPrefix(arg, "@") {
+			// 用户名（只能从缓存中查询，Bot API 无法通过用户名查找用户）
 			username := strings.TrimPrefix(arg, "@")
-
-			// 优先从缓存中查询
 			userID, err := userCacheService.GetUserIDByUsername(username)
 			if err != nil {
-				// 缓存中没有，尝试通过API获取
-				userID, err = GetUserIDByUsername(bot, message.Chat.ID, username)
-				if err != nil {
-					return nil, fmt.Errorf("暂无 @%s 的用户信息，请尝试使用引用回复", username)
-				}
+				targetErrors = append(targetErrors, fmt.Sprintf("%s：暂无该用户信息，请使用引用回复或用户ID", arg))
+				continue
 			}
-
-			params.TargetUsers = append(params.TargetUsers, userID)
+			params.TargetUsers = appendTarget(params.TargetUsers, userID)
 			userCount++
-		} else if isDurationString(arg) {
+		} else if idStr, ok := explicitUserID(arg); ok {
+			// tg://user?id= 链接（包括文本提及）或 id:用户ID
+			userID, err := strconv.ParseInt(idStr, 10, 64)
+			if err != nil || userID <= 0 {
+				targetErrors = append(targetErrors, fmt.Sprintf("%s：无效的用户ID", arg))
+				continue
+			}
+			params.TargetUsers = appendTarget(params.TargetUsers, userID)
+			userCount++
+		} else if userIDPattern.MatchString(arg) && !reasonStarted {
+			// 数字用户ID
+			userID, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil {
+				targetErrors = append(targetErrors, fmt.Sprintf("%s：无效的用户ID", arg))
+				continue
+			}
+			params.TargetUsers = appendTarget(params.TargetUsers, userID)
+			userCount++
+		} else if arg == "-purge" {
+			// 清理目标用户最近的消息，可跟数量
+			params.PurgeCount = DefaultPurgeCount
+			if i+1 < len(remainingArgs) && !remainingArgs[i+1].Quoted {
+				if count, err := strconv.Atoi(remainingArgs[i+1].Value); err == nil {
+					if count <= 0 || count > MaxPurgeCount {
+						return nil, fmt.Errorf("-purge 数量必须在 1 到 %d 之间", MaxPurgeCount)
+					}
+					params.PurgeCount = count
+					i++
+				}
+			}
+		} else if apply, ok := commandFlags[arg]; ok {
+			// 选项
+			apply(params)
+		} else if isFlag(arg) {
+			return nil, fmt.Errorf("未知选项：%s（理由中包含 - 开头的内容时请用引号括起来）", arg)
+		} else if utils.IsDurationString(arg) {
 			// 时间
+			if timeStr != "" || params.Permanent {
+				return nil, fmt.Errorf("时长重复：%s", arg)
+			}
 			timeStr = arg
+		} else if utils.IsPermanentKeyword(arg) {
+			// 明确指定永久
+			if timeStr != "" || params.Permanent {
+				return nil, fmt.Errorf("时长重复：%s", arg)
+			}
+			params.Permanent = true
+		} else if strings.EqualFold(arg, "until") && i+1 < len(remainingArgs) {
+			// 绝对结束时间：until 2026-11-01 [18:00]
+			if timeStr != "" || params.Permanent {
+				return nil, fmt.Errorf("时长重复：until")
+			}
+			untilStr := remainingArgs[i+1].Value
+			i++
+			if i+1 < len(remainingArgs) && clockPattern.MatchString(remainingArgs[i+1].Value) {
+				untilStr += " " + remainingArgs[i+1].Value
+				i++
+			}
+			duration, err := utils.ParseUntil(untilStr, utils.LoadLocation(config.GetConfig().System.Timezone))
+			if err != nil {
+				return nil, fmt.Errorf("结束时间格式错误（示例：until 2026-11-01 18:00）: %v", err)
+			}
+			params.Duration = duration
+			params.Until = true
 		} else {
-			// 理由
+			// 理由（理由开始后的纯数字不再作为目标用户）
 			reasonParts = append(reasonParts, arg)
+			reasonStarted = true
 		}
 	}
 
-	// 判断是否为批量操作：只有通过@username指定多个用户才算批量
+	// 任何一个目标无法识别都不执行，避免只处理了部分用户
+	if len(targetErrors) > 0 {
+		return nil, fmt.Errorf("无法识别目标用户\n%s", strings.Join(targetErrors, "\n"))
+	}
+
+	// 判断是否为批量操作：只有通过参数指定多个用户才算批量
 	if userCount > 1 {
 		params.IsBatch = true
 	}
@@ -87,6 +202,14 @@ func ParseCommand(message *tgbotapi.Message, bot *tgbotapi.BotAPI, userCacheServ
 		}
 		params.Duration = duration
 	}
+	if params.Until && (timeStr != "" || params.Permanent) {
+		return nil, fmt.Errorf("时长重复：until")
+	}
+
+	// Telegram 只接受 30 秒到 366 天的限制时长，超出范围会被当作永久
+	if !utils.IsValidDuration(params.Duration) {
+		return nil, fmt.Errorf("时长必须在 30 秒到 366 天之间（当前为 %s），永久请使用 perm", utils.FormatDuration(params.Duration))
+	}
 
 	// 5. 组合理由
 	if len(reasonParts) > 0 {
@@ -95,35 +218,171 @@ func ParseCommand(message *tgbotapi.Message, bot *tgbotapi.BotAPI, userCacheServ
 
 	// 6. 检查是否有目标用户
 	if len(params.TargetUsers) == 0 {
-		return nil, fmt.Errorf("请指定目标用户（引用回复或@用户名）")
+		return nil, fmt.Errorf("请指定目标用户（引用回复、@用户名或用户ID）")
+	}
+
+	// 7. 仅本群模式只能在群组中使用
+	if params.Local && !message.Chat.IsGroup() && !message.Chat.IsSuperGroup() {
+		return nil, fmt.Errorf("-local 只能在群组中使用")
 	}
 
 	return params, nil
 }
 
-// isDurationString 判断是否为时间字符串
-func isDurationString(s string) bool {
-	matched, _ := regexp.MatchString(`^\d+[smhd]$`, strings.ToLower(s))
-	return matched
+// userLinkPrefix 用户链接前缀
+const userLinkPrefix = "tg://user?id="
+
+// userIDPrefix 明确指定用户ID的前缀（理由之后的用户ID需要使用）
+const userIDPrefix = "id:"
+
+// userIDPattern 数字用户ID（至少 5 位，避免与 /purge 20 这类数量参数混淆）
+var userIDPattern = regexp.MustCompile(`^\d{5,}$`)
+
+// explicitUserID 提取 tg://user?id= 链接或 id:用户ID 中的用户ID，不是这两种形式时返回 false
+func explicitUserID(arg string) (string, bool) {
+	if strings.HasPrefix(arg, userLinkPrefix) {
+		return strings.TrimPrefix(arg, userLinkPrefix), true
+	}
+	if len(arg) > len(userIDPrefix) && strings.EqualFold(arg[:len(userIDPrefix)], userIDPrefix) {
+		return arg[len(userIDPrefix):], true
+	}
+	return "", false
 }
 
-// GetUserIDByUsername 通过用户名获取用户ID
-func GetUserIDByUsername(bot *tgbotapi.BotAPI, chatID int64, username string) (int64, error) {
-	// 尝试获取聊天成员信息
-	member, err := bot.GetChatMember(tgbotapi.GetChatMemberConfig{
-		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
-			ChatID: chatID,
-			UserID: 0, // 这里需要用户ID，但我们只有用户名
-		},
-	})
+// replyTargetUser 获取引用回复的目标用户
+// 回复自己转发的消息（例如在私聊中把违规消息转发给机器人）时，目标为原消息发送者
+func replyTargetUser(message *tgbotapi.Message) (int64, error) {
+	reply := message.ReplyToMessage
+	forwarded := reply.ForwardDate != 0
+	ownForward := reply.From != nil && reply.From.ID == message.From.ID
 
-	if err != nil {
-		return 0, err
+	if forwarded && (ownForward || message.Chat.IsPrivate()) {
+		if reply.ForwardFrom != nil {
+			return reply.ForwardFrom.ID, nil
+		}
+		return 0, fmt.Errorf("该转发消息隐藏了原发送者，无法识别目标用户，请使用用户ID")
 	}
 
-	return member.User.ID, nil
+	if reply.From == nil {
+		return 0, fmt.Errorf("无法识别被回复消息的发送者")
+	}
+	return reply.From.ID, nil
 }
 
+// appendTarget 添加目标用户（去重）
+func appendTarget(targets []int64, userID int64) []int64 {
+	for _, id := range targets {
+		if id == userID {
+			return targets
+		}
+	}
+	return append(targets, userID)
+}
+
+// normalizeMentions 将文本提及（text_mention）和指向用户的文字链接替换为 tg://user?id= 参数
+// 实体偏移量以 UTF-16 编码单元计算
+func normalizeMentions(text string, entities []tgbotapi.MessageEntity) string {
+	if len(entities) == 0 {
+		return text
+	}
+
+	units := utf16.Encode([]rune(text))
+	var sb strings.Builder
+	pos := 0
+	for _, entity := range entities {
+		var replacement string
+		switch {
+		case entity.Type == "text_mention" && entity.User != nil:
+			replacement = fmt.Sprintf("%s%d", userLinkPrefix, entity.User.ID)
+		case entity.Type == "text_link" && strings.HasPrefix(entity.URL, userLinkPrefix):
+			replacement = entity.URL
+		default:
+			continue
+		}
+		if entity.Offset < pos || entity.Offset+entity.Length > len(units) {
+			continue
+		}
+		sb.WriteString(string(utf16.Decode(units[pos:entity.Offset])))
+		sb.WriteString(" " + replacement + " ")
+		pos = entity.Offset + entity.Length
+	}
+	sb.WriteString(string(utf16.Decode(units[pos:])))
+	return sb.String()
+}
+
+// commandFlags 不带参数的命令选项（-purge 可跟数量，单独处理）
+var commandFlags = map[string]func(p *CommandParams){
+	"-local":    func(p *CommandParams) { p.Local = true },
+	"-d":        func(p *CommandParams) { p.DeleteMessage = true },
+	"-silent":   func(p *CommandParams) { p.Silent = true },
+	"-notify":   func(p *CommandParams) { p.NotifyTarget = true },
+	"-mode":     func(p *CommandParams) { p.ChooseMode = true },
+	"-readonly": func(p *CommandParams) { p.MuteMode = models.MuteModeReadOnly },
+	"-media":    func(p *CommandParams) { p.MuteMode = models.MuteModeMedia },
+	"-links":    func(p *CommandParams) { p.MuteMode = models.MuteModeLinks },
+	"-stickers": func(p *CommandParams) { p.MuteMode = models.MuteModeStickers },
+	"-ban":      func(p *CommandParams) { p.RecordType = service.RecordTypeBan },
+	"-mute":     func(p *CommandParams) { p.RecordType = service.RecordTypeMute },
+}
+
+// isFlag 判断参数是否为选项（- 开头且后面是字母）
+func isFlag(arg string) bool {
+	return len(arg) > 1 && arg[0] == '-' && unicode.IsLetter(rune(arg[1]))
+}
+
+// commandToken 命令参数
+type commandToken struct {
+	Value  string
+	Quoted bool // 是否来自引号内（引号内的内容不解析为用户、选项或时长）
+}
+
+// quotePairs 支持的引号（英文双引号和中文双引号）
+var quotePairs = map[rune]rune{
+	'"': '"',
+	'“': '”',
+}
+
+// tokenize 按空白切分命令文本，引号内的内容作为一个参数
+func tokenize(text string) ([]commandToken, error) {
+	tokens := make([]commandToken, 0)
+	var current strings.Builder
+	var closing rune
+	inQuote := false
+
+	flush := func(quoted bool) {
+		if current.Len() > 0 {
+			tokens = append(tokens, commandToken{Value: current.String(), Quoted: quoted})
+		}
+		current.Reset()
+	}
+
+	for _, r := range text {
+		switch {
+		case inQuote && r == closing:
+			inQuote = false
+			flush(true)
+		case inQuote:
+			current.WriteRune(r)
+		case quotePairs[r] != 0 && current.Len() == 0:
+			inQuote = true
+			closing = quotePairs[r]
+		case unicode.IsSpace(r):
+			flush(false)
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	if inQuote {
+		return nil, fmt.Errorf("引号没有闭合")
+	}
+	flush(false)
+	return tokens, nil
+}
+
+// clockPattern until 后可选的时刻，如 18:00
+var clockPattern = regexp.MustCompile(`^\d{1,2}:\d{2}$`)
+
 // GetUserInfo 获取用户信息
 func GetUserInfo(user *tgbotapi.User) (username, fullName string) {
 	username = user.UserName
@@ -134,8 +393,14 @@ func GetUserInfo(user *tgbotapi.User) (username, fullName string) {
 	return
 }
 
-// GetChatTitle 获取聊天标题
+// PrivateConsoleName 在私聊中执行操作时记录和通知显示的来源名称
+const PrivateConsoleName = "私聊控制台"
+
+// GetChatTitle 获取聊天标题（私聊中执行的操作显示为私聊控制台）
 func GetChatTitle(chat *tgbotapi.Chat) string {
+	if chat.IsPrivate() {
+		return PrivateConsoleName
+	}
 	if chat.Title != "" {
 		return chat.Title
 	}
@@ -145,8 +410,11 @@ func GetChatTitle(chat *tgbotapi.Chat) string {
 	return "未知群组"
 }
 
-// GetChatUsername 获取聊天用户名（公开群组/频道）
+// GetChatUsername 获取聊天用户名（公开群组/频道，私聊返回空，避免通知中链接到操作人）
 func GetChatUsername(chat *tgbotapi.Chat) string {
+	if chat.IsPrivate() {
+		return ""
+	}
 	return chat.UserName
 }
 
@@ -159,34 +427,3 @@ func ExtractUserFromMessage(message *tgbotapi.Message) (userID int64, username,
 	}
 	return 0, "", ""
 }
-
-// ExtractUsersFromEntities 从消息实体中提取用户
-func ExtractUsersFromEntities(message *tgbotapi.Message, bot *tgbotapi.BotAPI) ([]int64, error) {
-	userIDs := make([]int64, 0)
-
-	if message.Entities == nil || len(message.Entities) == 0 {
-		return userIDs, nil
-	}
-
-	for _, entity := range message.Entities {
-		if entity.Type == "mention" {
-			// @username 格式
-			start := entity.Offset
-			length := entity.Length
-			username := message.Text[start+1 : start+length] // 跳过@符号
-
-			userID, err := GetUserIDByUsername(bot, message.Chat.ID, username)
-			if err != nil {
-				return nil, err
-			}
-			userIDs = append(userIDs, userID)
-		} else if entity.Type == "text_mention" {
-			// 文本提及（对于没有用户名的用户）
-			if entity.User != nil {
-				userIDs = append(userIDs, entity.User.ID)
-			}
-		}
-	}
-
-	return userIDs, nil
-}
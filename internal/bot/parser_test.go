@@ -0,0 +1,432 @@
+package bot
+
+import (
+	"admin-bot/internal/config"
+	"admin-bot/internal/models"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestMain(m *testing.M) {
+	// until 按配置的时区解析
+	config.GlobalConfig = &config.Config{System: config.SystemConfig{Timezone: "UTC"}}
+	os.Exit(m.Run())
+}
+
+// testMessage 构造群组中的命令消息
+func testMessage(text string) *tgbotapi.Message {
+	return &tgbotapi.Message{
+		Text: text,
+		From: &tgbotapi.User{ID: 1},
+		Chat: &tgbotapi.Chat{ID: -100, Type: "supergroup"},
+		Entities: []tgbotapi.MessageEntity{
+			{Type: "bot_command", Offset: 0, Length: len(strings.Fields(text)[0])},
+		},
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	future := time.Now().UTC().AddDate(0, 0, 10)
+	futureDate := future.Format("2006-01-02")
+	untilDay := int(time.Until(time.Date(future.Year(), future.Month(), future.Day(), 0, 0, 0, 0, time.UTC)).Seconds())
+	untilClock := int(time.Until(time.Date(future.Year(), future.Month(), future.Day(), 18, 0, 0, 0, time.UTC)).Seconds())
+
+	tests := []struct {
+		name     string
+		message  *tgbotapi.Message
+		targets  []int64
+		duration int
+		reason   string
+		wantErr  string
+		check    func(t *testing.T, p *CommandParams)
+	}{
+		{
+			name:     "数字用户ID、时长和理由",
+			message:  testMessage("/t 12345678 1d 刷单"),
+			targets:  []int64{12345678},
+			duration: 86400,
+			reason:   "刷单",
+		},
+		{
+			name:    "多个用户为批量操作",
+			message: testMessage("/t 12345678 87654321 id:11111"),
+			targets: []int64{12345678, 87654321, 11111},
+			check: func(t *testing.T, p *CommandParams) {
+				if !p.IsBatch {
+					t.Error("多个目标用户应为批量操作")
+				}
+			},
+		},
+		{
+			name:    "重复的用户去重",
+			message: testMessage("/t 12345678 tg://user?id=12345678"),
+			targets: []int64{12345678},
+		},
+		{
+			name:    "引号内的理由不解析选项和时长",
+			message: testMessage(`/t 12345678 "广告 -d 1d"`),
+			targets: []int64{12345678},
+			reason:  "广告 -d 1d",
+			check: func(t *testing.T, p *CommandParams) {
+				if p.DeleteMessage {
+					t.Error("引号内的 -d 不应作为选项")
+				}
+			},
+		},
+		{
+			name:    "中文引号",
+			message: testMessage("/t 12345678 “刷 单” 其他"),
+			targets: []int64{12345678},
+			reason:  "刷 单 其他",
+		},
+		{
+			name:    "引号中的数字不作为目标",
+			message: testMessage(`/t 12345678 "13800138000"`),
+			targets: []int64{12345678},
+			reason:  "13800138000",
+		},
+		{
+			name:    "引号没有闭合",
+			message: testMessage(`/t 12345678 "刷单`),
+			wantErr: "引号没有闭合",
+		},
+		{
+			name:    "已知选项",
+			message: testMessage("/jy 12345678 -d -silent -notify -local -media"),
+			targets: []int64{12345678},
+			check: func(t *testing.T, p *CommandParams) {
+				if !p.DeleteMessage || !p.Silent || !p.NotifyTarget || !p.Local {
+					t.Errorf("选项未生效: %+v", p)
+				}
+				if p.MuteMode != models.MuteModeMedia {
+					t.Errorf("禁言模式 = %s，期望 %s", p.MuteMode, models.MuteModeMedia)
+				}
+				if p.Scope() != models.ScopeLocal {
+					t.Errorf("作用范围 = %s，期望 %s", p.Scope(), models.ScopeLocal)
+				}
+			},
+		},
+		{
+			name:    "未知选项",
+			message: testMessage("/t 12345678 -foo"),
+			wantErr: "未知选项：-foo",
+		},
+		{
+			name:    "负数不是选项",
+			message: testMessage("/t 12345678 -5"),
+			targets: []int64{12345678},
+			reason:  "-5",
+		},
+		{
+			name:    "-purge 默认数量",
+			message: testMessage("/t 12345678 -purge"),
+			targets: []int64{12345678},
+			check: func(t *testing.T, p *CommandParams) {
+				if p.PurgeCount != DefaultPurgeCount {
+					t.Errorf("PurgeCount = %d，期望 %d", p.PurgeCount, DefaultPurgeCount)
+				}
+			},
+		},
+		{
+			name:    "-purge 指定数量",
+			message: testMessage("/t 12345678 -purge 20"),
+			targets: []int64{12345678},
+			check: func(t *testing.T, p *CommandParams) {
+				if p.PurgeCount != 20 {
+					t.Errorf("PurgeCount = %d，期望 20", p.PurgeCount)
+				}
+			},
+		},
+		{
+			name:    "-purge 数量超出范围",
+			message: testMessage("/t 12345678 -purge 501"),
+			wantErr: "-purge 数量必须在",
+		},
+		{
+			name:    "-local 不能在私聊中使用",
+			message: &tgbotapi.Message{Text: "/t 12345678 -local", From: &tgbotapi.User{ID: 1}, Chat: &tgbotapi.Chat{ID: 1, Type: "private"}},
+			wantErr: "-local 只能在群组中使用",
+		},
+		{
+			name:    "明确指定永久",
+			message: testMessage("/t 12345678 perm"),
+			targets: []int64{12345678},
+			check: func(t *testing.T, p *CommandParams) {
+				if !p.Permanent {
+					t.Error("perm 应设置 Permanent")
+				}
+			},
+		},
+		{
+			name:    "时长重复",
+			message: testMessage("/t 12345678 1d perm"),
+			wantErr: "时长重复",
+		},
+		{
+			name:    "时长超出 Telegram 范围",
+			message: testMessage("/jy 12345678 10s"),
+			wantErr: "时长必须在 30 秒到 366 天之间",
+		},
+		{
+			name:     "until 只有日期",
+			message:  testMessage("/jy 12345678 until " + futureDate + " 刷屏"),
+			targets:  []int64{12345678},
+			duration: untilDay,
+			reason:   "刷屏",
+			check: func(t *testing.T, p *CommandParams) {
+				if !p.Until {
+					t.Error("until 应设置 Until")
+				}
+			},
+		},
+		{
+			name:     "until 日期和时刻",
+			message:  testMessage("/jy 12345678 until " + futureDate + " 18:00 刷屏"),
+			targets:  []int64{12345678},
+			duration: untilClock,
+			reason:   "刷屏",
+		},
+		{
+			name:    "until 与时长重复",
+			message: testMessage("/jy 12345678 1d until " + futureDate),
+			wantErr: "时长重复：until",
+		},
+		{
+			name:    "until 时间格式错误",
+			message: testMessage("/jy 12345678 until tomorrow"),
+			wantErr: "结束时间格式错误",
+		},
+		// @用户名需要查询数据库中的用户缓存，这里用 id: 和引用回复指定目标（与 /lh @spam 1d 刷单 13800138000 的解析路径相同）
+		{
+			name:     "理由之后的数字作为理由",
+			message:  testMessage("/lh id:12345678 1d 刷单 13800138000"),
+			targets:  []int64{12345678},
+			duration: 86400,
+			reason:   "刷单 13800138000",
+		},
+		{
+			name: "引用回复时理由之后的数字作为理由",
+			message: func() *tgbotapi.Message {
+				m := testMessage("/lh 1d 刷单 13800138000")
+				m.ReplyToMessage = &tgbotapi.Message{From: &tgbotapi.User{ID: 999999}}
+				return m
+			}(),
+			targets:  []int64{999999},
+			duration: 86400,
+			reason:   "刷单 13800138000",
+			check: func(t *testing.T, p *CommandParams) {
+				if p.IsBatch {
+					t.Error("引用回复不应为批量操作")
+				}
+			},
+		},
+		{
+			name:    "理由之后用 id: 指定用户",
+			message: testMessage("/lh 12345678 刷单 id:87654321"),
+			targets: []int64{12345678, 87654321},
+			reason:  "刷单",
+		},
+		{
+			name:    "无效的用户ID",
+			message: testMessage("/t id:abc"),
+			wantErr: "无法识别目标用户",
+		},
+		{
+			name:    "没有目标用户",
+			message: testMessage("/t 1d 刷单"),
+			wantErr: "请指定目标用户",
+		},
+		{
+			name: "文本提及（UTF-16 偏移）",
+			message: func() *tgbotapi.Message {
+				m := testMessage("/t 😀小明 1d")
+				m.Entities = append(m.Entities, tgbotapi.MessageEntity{
+					Type: "text_mention", Offset: 3, Length: 4, User: &tgbotapi.User{ID: 424242},
+				})
+				return m
+			}(),
+			targets:  []int64{424242},
+			duration: 86400,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := ParseCommand(tt.message, nil)
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Fatalf("期望返回包含 %q 的错误，实际成功: %+v", tt.wantErr, params)
+				}
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("错误 = %q，期望包含 %q", err.Error(), tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCommand 返回错误: %v", err)
+			}
+			if !reflect.DeepEqual(params.TargetUsers, tt.targets) {
+				t.Errorf("TargetUsers = %v，期望 %v", params.TargetUsers, tt.targets)
+			}
+			if diff := params.Duration - tt.duration; diff < -2 || diff > 2 {
+				t.Errorf("Duration = %d，期望 %d", params.Duration, tt.duration)
+			}
+			if params.Reason != tt.reason {
+				t.Errorf("Reason = %q，期望 %q", params.Reason, tt.reason)
+			}
+			if tt.check != nil {
+				tt.check(t, params)
+			}
+		})
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    []commandToken
+		wantErr bool
+	}{
+		{
+			name: "按空白切分",
+			text: "/t  12345\t1d\n刷单",
+			want: []commandToken{{Value: "/t"}, {Value: "12345"}, {Value: "1d"}, {Value: "刷单"}},
+		},
+		{
+			name: "英文引号",
+			text: `/t "a b" c`,
+			want: []commandToken{{Value: "/t"}, {Value: "a b", Quoted: true}, {Value: "c"}},
+		},
+		{
+			name: "中文引号",
+			text: "/t “刷 单”",
+			want: []commandToken{{Value: "/t"}, {Value: "刷 单", Quoted: true}},
+		},
+		{
+			name: "参数中间的引号不作为引号",
+			text: `/t a"b c`,
+			want: []commandToken{{Value: "/t"}, {Value: `a"b`}, {Value: "c"}},
+		},
+		{
+			name: "空引号",
+			text: `/t "" c`,
+			want: []commandToken{{Value: "/t"}, {Value: "c"}},
+		},
+		{
+			name:    "引号没有闭合",
+			text:    `/t "a b`,
+			wantErr: true,
+		},
+		{
+			name:    "中文引号不能用英文引号闭合",
+			text:    `/t “a b"`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tokenize(tt.text)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("tokenize(%q) = %v，期望返回错误", tt.text, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("tokenize(%q) 返回错误: %v", tt.text, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenize(%q) = %v，期望 %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeMentions(t *testing.T) {
+	user := &tgbotapi.User{ID: 42}
+
+	tests := []struct {
+		name     string
+		text     string
+		entities []tgbotapi.MessageEntity
+		want     string
+	}{
+		{
+			name: "没有实体",
+			text: "/t 12345",
+			want: "/t 12345",
+		},
+		{
+			name:     "其他实体保持不变",
+			text:     "/t @spam",
+			entities: []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: 2}, {Type: "mention", Offset: 3, Length: 5}},
+			want:     "/t @spam",
+		},
+		{
+			name:     "文本提及",
+			text:     "/t 小明 1d",
+			entities: []tgbotapi.MessageEntity{{Type: "text_mention", Offset: 3, Length: 2, User: user}},
+			want:     "/t  tg://user?id=42  1d",
+		},
+		{
+			name:     "前面有表情时按 UTF-16 偏移",
+			text:     "/t 😀 小明 1d",
+			entities: []tgbotapi.MessageEntity{{Type: "text_mention", Offset: 6, Length: 2, User: user}},
+			want:     "/t 😀  tg://user?id=42  1d",
+		},
+		{
+			name:     "提及文字包含表情",
+			text:     "/t 😀小明 1d",
+			entities: []tgbotapi.MessageEntity{{Type: "text_mention", Offset: 3, Length: 4, User: user}},
+			want:     "/t  tg://user?id=42  1d",
+		},
+		{
+			name:     "文本提及紧贴理由",
+			text:     "/t 小明刷单",
+			entities: []tgbotapi.MessageEntity{{Type: "text_mention", Offset: 3, Length: 2, User: user}},
+			want:     "/t  tg://user?id=42 刷单",
+		},
+		{
+			name: "多个提及",
+			text: "/t 小明 小红",
+			entities: []tgbotapi.MessageEntity{
+				{Type: "text_mention", Offset: 3, Length: 2, User: user},
+				{Type: "text_mention", Offset: 6, Length: 2, User: &tgbotapi.User{ID: 43}},
+			},
+			want: "/t  tg://user?id=42   tg://user?id=43 ",
+		},
+		{
+			name:     "指向用户的文字链接",
+			text:     "/t 小明",
+			entities: []tgbotapi.MessageEntity{{Type: "text_link", Offset: 3, Length: 2, URL: "tg://user?id=42"}},
+			want:     "/t  tg://user?id=42 ",
+		},
+		{
+			name:     "普通文字链接保持不变",
+			text:     "/t 小明",
+			entities: []tgbotapi.MessageEntity{{Type: "text_link", Offset: 3, Length: 2, URL: "https://example.com"}},
+			want:     "/t 小明",
+		},
+		{
+			name:     "超出文本范围的实体被忽略",
+			text:     "/t 小明",
+			entities: []tgbotapi.MessageEntity{{Type: "text_mention", Offset: 3, Length: 10, User: user}},
+			want:     "/t 小明",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeMentions(tt.text, tt.entities); got != tt.want {
+				t.Errorf("normalizeMentions(%q) = %q，期望 %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package bot
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// pendingActionTTL 待选择操作的有效期
+const pendingActionTTL = 10 * time.Minute
+
+// PendingAction 等待操作人通过内联键盘补充选择的命令
+type PendingAction struct {
+	Message   *tgbotapi.Message // 原始命令消息
+	Params    *CommandParams    // 已解析的命令参数
+	CreatedAt time.Time
+}
+
+var (
+	pendingActions = make(map[string]*PendingAction)
+	pendingSeq     int64
+	pendingMutex   sync.Mutex
+)
+
+// addPendingAction 保存待选择操作并返回其ID（用于回调数据）
+func addPendingAction(message *tgbotapi.Message, params *CommandParams) string {
+	pendingMutex.Lock()
+	defer pendingMutex.Unlock()
+
+	// 顺便清理过期的操作
+	now := time.Now()
+	for id, action := range pendingActions {
+		if now.Sub(action.CreatedAt) > pendingActionTTL {
+			delete(pendingActions, id)
+		}
+	}
+
+	pendingSeq++
+	id := strconv.FormatInt(pendingSeq, 36)
+	pendingActions[id] = &PendingAction{
+		Message:   message,
+		Params:    params,
+		CreatedAt: now,
+	}
+	return id
+}
+
+// getPendingAction 获取待选择操作（已过期返回 nil）
+func getPendingAction(id string) *PendingAction {
+	pendingMutex.Lock()
+	defer pendingMutex.Unlock()
+
+	action, exists := pendingActions[id]
+	if !exists {
+		return nil
+	}
+	if time.Since(action.CreatedAt) > pendingActionTTL {
+		delete(pendingActions, id)
+		return nil
+	}
+	return action
+}
+
+// removePendingAction 移除待选择操作，返回是否由本次调用移除（用于防止重复点击重复执行）
+func removePendingAction(id string) bool {
+	pendingMutex.Lock()
+	defer pendingMutex.Unlock()
+
+	if _, exists := pendingActions[id]; !exists {
+		return false
+	}
+	delete(pendingActions, id)
+	return true
+}
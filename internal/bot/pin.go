@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"admin-bot/internal/models"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// handlePin 处理 /pin 命令：置顶被回复的消息（-silent 不通知群成员）
+func (h *Handler) handlePin(message *tgbotapi.Message) {
+	if !h.authorize(message, models.PermPin) {
+		return
+	}
+
+	if !message.Chat.IsGroup() && !message.Chat.IsSuperGroup() {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ /pin 只能在群组中使用")
+		return
+	}
+	if message.ReplyToMessage == nil {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 请引用回复要置顶的消息")
+		return
+	}
+
+	silent := strings.TrimSpace(message.CommandArguments()) == "-silent"
+	_, err := h.bot.Request(tgbotapi.PinChatMessageConfig{
+		ChatID:              message.Chat.ID,
+		MessageID:           message.ReplyToMessage.MessageID,
+		DisableNotification: silent,
+	})
+	if err != nil {
+		logrus.Errorf("Failed to pin message: %v", err)
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 置顶失败，请确认机器人有置顶消息的权限")
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"操作人":  message.From.ID,
+		"群组ID": message.Chat.ID,
+		"消息ID": message.ReplyToMessage.MessageID,
+	}).Info("📌 已置顶消息")
+
+	// 置顶成功后删除命令消息，避免刷屏
+	h.deleteMessage(message.Chat.ID, message.MessageID)
+}
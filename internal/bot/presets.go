@@ -0,0 +1,303 @@
+package bot
+
+import (
+	"admin-bot/internal/models"
+	"admin-bot/internal/utils"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// muteDurationPresets 禁言时长预设（秒，0 表示永久）
+var muteDurationPresets = []int{600, 3600, 86400, 7 * 86400, 30 * 86400, 0}
+
+// matchReasonPreset 手动输入的理由与预设一致时记录对应的理由代码
+func (h *Handler) matchReasonPreset(params *CommandParams) {
+	if params.Reason == "" || params.ReasonCode != "" {
+		return
+	}
+	if preset := h.reasonPresetService.MatchPreset(params.Reason); preset != nil {
+		params.ReasonCode = preset.Code
+	}
+}
+
+// nextModerationStep 依次补全拉黑/禁言缺少的理由、禁言时长和禁言模式，全部确定后执行操作
+// menuMsg 为上一步的菜单消息，后续菜单和执行结果都在这条消息上编辑
+func (h *Handler) nextModerationStep(message *tgbotapi.Message, params *CommandParams, menuMsg *tgbotapi.Message) {
+	isMute := message.Command() == "jy"
+
+	// 先检查受保护的目标用户，避免选完理由后才被拒绝
+	if !h.checkProtectedTargets(message, params, menuMsg) {
+		return
+	}
+
+	if params.Reason == "" && !params.reasonChosen {
+		if h.showReasonMenu(message, params, menuMsg) {
+			return
+		}
+		// 没有预设理由时直接继续
+		params.reasonChosen = true
+	}
+
+	if isMute && params.Duration == 0 && !params.Permanent && !params.durationChosen {
+		h.showDurationMenu(message, params, menuMsg)
+		return
+	}
+
+	if isMute && params.ChooseMode {
+		h.showMuteModeMenu(message, params, menuMsg)
+		return
+	}
+
+	// 时长确定后检查操作人角色允许的最长时长
+	permission := models.PermBan
+	if isMute {
+		permission = models.PermMute
+	}
+	if ok, denial := h.permissionChecker.AuthorizeDuration(message, permission, params.Duration); !ok {
+		h.denyPermission(message, menuMsg, permission, denial)
+		return
+	}
+
+	// 符合审批策略的操作需要另一名有权限的用户批准后才执行
+	if !h.checkApproval(message, params, menuMsg) {
+		return
+	}
+
+	if isMute {
+		h.executeMute(message, params, menuMsg)
+	} else {
+		h.executeBan(message, params, menuMsg)
+	}
+}
+
+// showReasonMenu 显示预设理由菜单，没有预设理由时返回 false
+func (h *Handler) showReasonMenu(message *tgbotapi.Message, params *CommandParams, menuMsg *tgbotapi.Message) bool {
+	presets, err := h.reasonPresetService.GetPresets()
+	if err != nil {
+		logrus.Errorf("Failed to get reason presets: %v", err)
+		return false
+	}
+	if len(presets) == 0 {
+		return false
+	}
+
+	actionID := addPendingAction(message, params)
+
+	// 每行两个按钮
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, preset := range presets {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(preset.Label,
+			fmt.Sprintf("preset:%s:r:%d", actionID, preset.ID)))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("➖ 不填写理由", fmt.Sprintf("preset:%s:r:0", actionID)),
+		tgbotapi.NewInlineKeyboardButtonData("❌ 取消", fmt.Sprintf("preset:%s:cancel", actionID)),
+	))
+
+	if !h.sendMenu(message, menuMsg, "📝 请选择理由：", tgbotapi.NewInlineKeyboardMarkup(rows...)) {
+		removePendingAction(actionID)
+		return false
+	}
+	return true
+}
+
+// showDurationMenu 显示禁言时长预设菜单
+func (h *Handler) showDurationMenu(message *tgbotapi.Message, params *CommandParams, menuMsg *tgbotapi.Message) {
+	actionID := addPendingAction(message, params)
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, seconds := range muteDurationPresets {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(utils.FormatDuration(seconds),
+			fmt.Sprintf("preset:%s:d:%d", actionID, seconds)))
+		if len(row) == 3 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("❌ 取消", fmt.Sprintf("preset:%s:cancel", actionID)),
+	))
+
+	if !h.sendMenu(message, menuMsg, "⏱ 请选择禁言时长：", tgbotapi.NewInlineKeyboardMarkup(rows...)) {
+		removePendingAction(actionID)
+	}
+}
+
+// handlePresetCallback 处理预设理由/时长选择回调（preset:<操作ID>:r:<预设ID>、preset:<操作ID>:d:<秒数>、preset:<操作ID>:cancel）
+func (h *Handler) handlePresetCallback(callback *tgbotapi.CallbackQuery) {
+	parts := strings.Split(callback.Data, ":")
+	if len(parts) < 3 {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 无效的操作", true)
+		return
+	}
+	actionID := parts[1]
+
+	action := getPendingAction(actionID)
+	if action == nil {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 操作已过期，请重新发送命令", true)
+		return
+	}
+
+	// 只有发起命令的操作人可以选择
+	if action.Message.From.ID != callback.From.ID {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 只有发起命令的管理员可以选择", true)
+		return
+	}
+
+	// 防止重复点击重复执行
+	if !removePendingAction(actionID) {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 操作已处理", true)
+		return
+	}
+
+	if parts[2] == "cancel" || len(parts) != 4 {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "已取消", false)
+		h.editMessage(callback.Message.Chat.ID, callback.Message.MessageID, "已取消操作")
+		return
+	}
+
+	params := action.Params
+	value, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 无效的操作", true)
+		return
+	}
+
+	switch parts[2] {
+	case "r":
+		params.reasonChosen = true
+		if value != 0 {
+			preset, err := h.reasonPresetService.GetPreset(value)
+			if err != nil {
+				h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 预设理由不存在", true)
+				h.editMessage(callback.Message.Chat.ID, callback.Message.MessageID, "❌ 预设理由不存在，请重新发送命令")
+				return
+			}
+			params.Reason = preset.Label
+			params.ReasonCode = preset.Code
+		}
+		h.notificationService.AnswerCallbackQuery(callback.ID, "", false)
+	case "d":
+		if !utils.IsValidDuration(int(value)) {
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 无效的时长", true)
+			return
+		}
+		params.durationChosen = true
+		params.Duration = int(value)
+		params.Permanent = value == 0
+		h.notificationService.AnswerCallbackQuery(callback.ID, utils.FormatDuration(params.Duration), false)
+	default:
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 无效的操作", true)
+		return
+	}
+
+	h.nextModerationStep(action.Message, params, callback.Message)
+}
+
+// handleReasonPresetsCallback 显示预设理由管理页面
+func (h *Handler) handleReasonPresetsCallback(callback *tgbotapi.CallbackQuery) {
+	presets, err := h.reasonPresetService.GetPresets()
+	if err != nil {
+		logrus.Errorf("Failed to get reason presets: %v", err)
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 获取预设理由失败", true)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("📝 *预设理由*\n\n")
+	if len(presets) == 0 {
+		sb.WriteString("暂无预设理由\n")
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, preset := range presets {
+		sb.WriteString(fmt.Sprintf("%d. %s（`%s`）\n", i+1, utils.EscapeMarkdown(preset.Label), preset.Code))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🗑 %s", preset.Label),
+				fmt.Sprintf("config:del_reason_%d", preset.ID)),
+		))
+	}
+	sb.WriteString("\n使用 /lh 或 /jy 且未填写理由时会显示这些选项")
+
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("➕ 添加预设理由", "config:add_reason"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ 返回", "config:back"),
+		),
+	)
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	h.notificationService.EditMessage(callback.Message.Chat.ID, callback.Message.MessageID, sb.String(), &keyboard)
+	h.notificationService.AnswerCallbackQuery(callback.ID, "", false)
+}
+
+// handleAddReasonCallback 处理添加预设理由回调
+func (h *Handler) handleAddReasonCallback(callback *tgbotapi.CallbackQuery) {
+	// 设置用户状态
+	setUserState(callback.From.ID, "waiting_reason_preset", nil)
+
+	text := "请发送理由代码和显示名称，用空格分隔\n\n格式示例：`spam 刷屏`\n\n理由代码只能包含小写字母、数字和下划线\n\n发送 /cancel 取消操作"
+	h.notificationService.EditMessage(callback.Message.Chat.ID, callback.Message.MessageID, text, nil)
+	h.notificationService.AnswerCallbackQuery(callback.ID, "请发送理由", false)
+}
+
+// handleDelReasonCallback 删除预设理由
+func (h *Handler) handleDelReasonCallback(callback *tgbotapi.CallbackQuery, action string) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(action, "del_reason_"), 10, 64)
+	if err != nil {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 无效的预设理由", true)
+		return
+	}
+
+	if err := h.reasonPresetService.DeletePreset(id); err != nil {
+		logrus.Errorf("Failed to delete reason preset: %v", err)
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 删除失败", true)
+		return
+	}
+
+	// 刷新列表
+	h.handleReasonPresetsCallback(callback)
+}
+
+// handleWaitingReasonPreset 处理等待输入预设理由（仅私聊）
+func (h *Handler) handleWaitingReasonPreset(message *tgbotapi.Message) {
+	parts := strings.SplitN(strings.TrimSpace(message.Text), " ", 2)
+	if len(parts) != 2 {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 格式错误，请发送：理由代码 显示名称\n\n例如：spam 刷屏")
+		return
+	}
+
+	if err := h.reasonPresetService.AddPreset(parts[0], parts[1]); err != nil {
+		h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("❌ %s", err.Error()))
+		return
+	}
+
+	clearUserState(message.From.ID)
+	h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("✅ 已添加预设理由：%s", strings.TrimSpace(parts[1])))
+
+	logrus.WithFields(logrus.Fields{
+		"操作人":  message.From.ID,
+		"理由代码": parts[0],
+	}).Info("📝 已添加预设理由")
+
+	// 显示配置菜单
+	h.showConfigMenu(message.Chat.ID)
+}
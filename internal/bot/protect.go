@@ -0,0 +1,200 @@
+package bot
+
+import (
+	"admin-bot/internal/service"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// protectedTarget 受保护的目标用户
+type protectedTarget struct {
+	UserID int64
+	Name   string
+	Reason string // 受保护的原因，如 "全局管理员"
+}
+
+// protectionReason 返回用户受保护的原因，不受保护时返回空字符串
+func (h *Handler) protectionReason(userID int64) string {
+	if userID == h.bot.Self.ID {
+		return "机器人自身"
+	}
+	if h.cfg.Telegram.IsAuthor(userID) {
+		return "作者"
+	}
+	if isAdmin, err := h.adminService.IsGlobalAdmin(userID); err != nil {
+		logrus.Errorf("Failed to check global admin: %v", err)
+	} else if isAdmin {
+		return "全局管理员"
+	}
+	if h.cfg.System.IsProtectedUser(userID) {
+		return "保护名单中的用户"
+	}
+
+	// 任一授权群组的 Telegram 管理员（使用群管理员名单缓存）
+	groups, err := h.groupService.GetAuthorizedGroups()
+	if err != nil {
+		logrus.Errorf("Failed to get authorized groups: %v", err)
+		return ""
+	}
+	for _, group := range groups {
+		member, err := service.GetGroupAdmin(h.bot, group.GroupID, userID)
+		if err == nil && member != nil {
+			return fmt.Sprintf("群组「%s」的管理员", group.GroupName)
+		}
+	}
+	return ""
+}
+
+// checkProtectedTargets 检查踢出、拉黑和禁言的目标中是否有受保护的用户（作者、全局管理员、授权群组的管理员、保护名单和机器人自身）
+// 作者可以确认后继续对其执行（机器人自身除外），其他操作人会跳过受保护的用户
+// 返回 false 表示没有可执行的目标或正在等待作者确认
+func (h *Handler) checkProtectedTargets(message *tgbotapi.Message, params *CommandParams, menuMsg *tgbotapi.Message) bool {
+	if params.protectionChecked {
+		return true
+	}
+	params.protectionChecked = true
+
+	var protected []protectedTarget
+	var allowed, confirmable []int64
+	for _, userID := range params.TargetUsers {
+		reason := h.protectionReason(userID)
+		if reason == "" {
+			allowed = append(allowed, userID)
+			continue
+		}
+		_, name := h.resolveTargetUser(message.Chat.ID, userID)
+		protected = append(protected, protectedTarget{UserID: userID, Name: name, Reason: reason})
+		if userID != h.bot.Self.ID {
+			confirmable = append(confirmable, userID)
+		}
+	}
+	if len(protected) == 0 {
+		return true
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"操作人":   message.From.ID,
+		"群组ID":  message.Chat.ID,
+		"命令":    message.Command(),
+		"受保护用户": len(protected),
+	}).Warn("🛡 目标中有受保护的用户")
+
+	text := "🛡 以下用户受保护：\n" + formatProtectedTargets(protected)
+
+	// 作者确认后可以继续执行
+	if h.cfg.Telegram.IsAuthor(message.From.ID) && len(confirmable) > 0 {
+		params.TargetUsers = allowed
+		params.protectedUsers = confirmable
+		h.showProtectConfirmMenu(message, params, menuMsg, text)
+		return false
+	}
+
+	params.TargetUsers = allowed
+	if len(allowed) == 0 {
+		text += "\n\n❌ 已拒绝执行"
+	} else {
+		text += fmt.Sprintf("\n\n已跳过以上用户，继续对其余 %d 名用户执行", len(allowed))
+	}
+	if menuMsg != nil {
+		h.editMessage(menuMsg.Chat.ID, menuMsg.MessageID, text)
+	} else {
+		h.sendReply(message.Chat.ID, message.MessageID, text)
+	}
+	return len(allowed) > 0
+}
+
+// formatProtectedTargets 受保护用户列表（纯文本）
+func formatProtectedTargets(targets []protectedTarget) string {
+	lines := make([]string, 0, len(targets))
+	for _, target := range targets {
+		lines = append(lines, fmt.Sprintf("• %s（%d）：%s", target.Name, target.UserID, target.Reason))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// showProtectConfirmMenu 请作者确认是否对受保护的用户执行操作
+func (h *Handler) showProtectConfirmMenu(message *tgbotapi.Message, params *CommandParams, menuMsg *tgbotapi.Message, text string) {
+	actionID := addPendingAction(message, params)
+
+	buttons := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("⚠️ 仍然执行", fmt.Sprintf("protect:%s:all", actionID)),
+	}
+	if len(params.TargetUsers) > 0 {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData("⏭ 跳过受保护用户", fmt.Sprintf("protect:%s:skip", actionID)))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		buttons,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("❌ 取消", fmt.Sprintf("protect:%s:cancel", actionID)),
+		),
+	)
+
+	if !h.sendMenu(message, menuMsg, text+"\n\n是否仍要执行？", keyboard) {
+		removePendingAction(actionID)
+	}
+}
+
+// handleProtectCallback 处理受保护用户的确认回调（protect:<操作ID>:all|skip|cancel）
+func (h *Handler) handleProtectCallback(callback *tgbotapi.CallbackQuery) {
+	parts := strings.Split(callback.Data, ":")
+	if len(parts) != 3 {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 无效的操作", true)
+		return
+	}
+	actionID := parts[1]
+
+	action := getPendingAction(actionID)
+	if action == nil {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 操作已过期，请重新发送命令", true)
+		return
+	}
+
+	// 只有发起命令的作者可以确认
+	if action.Message.From.ID != callback.From.ID {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 只有发起命令的作者可以确认", true)
+		return
+	}
+
+	// 防止重复点击重复执行
+	if !removePendingAction(actionID) {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 操作已处理", true)
+		return
+	}
+
+	params := action.Params
+	switch parts[2] {
+	case "all":
+		params.TargetUsers = append(params.TargetUsers, params.protectedUsers...)
+		logrus.WithFields(logrus.Fields{
+			"操作人":   callback.From.ID,
+			"命令":    action.Message.Command(),
+			"受保护用户": params.protectedUsers,
+		}).Warn("🛡 作者确认对受保护的用户执行操作")
+	case "skip":
+	default:
+		h.notificationService.AnswerCallbackQuery(callback.ID, "已取消", false)
+		h.editMessage(callback.Message.Chat.ID, callback.Message.MessageID, "已取消操作")
+		return
+	}
+	params.protectedUsers = nil
+	if len(params.TargetUsers) == 0 {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "没有可执行的用户", false)
+		h.editMessage(callback.Message.Chat.ID, callback.Message.MessageID, "已取消操作")
+		return
+	}
+	h.notificationService.AnswerCallbackQuery(callback.ID, "", false)
+
+	// 踢出直接执行，拉黑和禁言继续补全理由和时长
+	if action.Message.Command() == "t" {
+		if !h.checkApproval(action.Message, params, callback.Message) {
+			return
+		}
+		h.editMessage(callback.Message.Chat.ID, callback.Message.MessageID, "✅ 已确认，正在执行踢出操作")
+		h.executeKick(action.Message, params)
+		return
+	}
+	h.nextModerationStep(action.Message, params, callback.Message)
+}
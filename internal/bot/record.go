@@ -0,0 +1,383 @@
+package bot
+
+import (
+	"admin-bot/internal/models"
+	"admin-bot/internal/service"
+	"admin-bot/internal/utils"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// recordHistoryLimit 历史记录按钮私聊发送的最近记录条数
+const recordHistoryLimit = 10
+
+// handleRecordCallback 处理频道通知上的按钮（record:<类型>:<记录ID>:<操作>[:参数]）
+// 只有作者和全局管理员可以操作
+func (h *Handler) handleRecordCallback(callback *tgbotapi.CallbackQuery) {
+	operatorID := callback.From.ID
+	if !h.cfg.Telegram.IsAuthor(operatorID) && !h.permissionChecker.IsGlobalAdmin(operatorID) {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 只有作者和全局管理员可以操作", true)
+		return
+	}
+
+	parts := strings.Split(callback.Data, ":")
+	if len(parts) < 4 {
+		return
+	}
+	recordID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return
+	}
+	action := parts[3]
+	_, operatorName := GetUserInfo(callback.From)
+
+	logrus.WithFields(logrus.Fields{
+		"记录类型": parts[1],
+		"记录ID": recordID,
+		"操作":   action,
+		"操作人":  operatorID,
+	}).Info("🔘 收到通知按钮操作")
+
+	switch parts[1] {
+	case service.RecordTypeBan:
+		ban, err := h.banService.GetBan(recordID)
+		if err != nil {
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 记录不存在", true)
+			return
+		}
+		if !h.authorizeCallback(callback, recordActionPermission(action, models.PermUnban)) {
+			return
+		}
+		if action == "history" {
+			h.sendRecordHistory(callback, ban.UserID)
+			return
+		}
+		if !ban.IsActive() {
+			h.refreshBanNotification(ban)
+			h.notificationService.AnswerCallbackQuery(callback.ID, "该记录已失效："+recordStatus(ban.Status, ban.ExpireAt, ban.UnbanAt, ban.UnbanReason), true)
+			return
+		}
+		h.handleBanRecordAction(callback, ban, action, parts[4:], operatorName)
+	case service.RecordTypeMute:
+		mute, err := h.muteService.GetMute(recordID)
+		if err != nil {
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 记录不存在", true)
+			return
+		}
+		if !h.authorizeCallback(callback, recordActionPermission(action, models.PermUnmute)) {
+			return
+		}
+		if action == "history" {
+			h.sendRecordHistory(callback, mute.UserID)
+			return
+		}
+		if !mute.IsActive() {
+			h.refreshMuteNotification(mute)
+			h.notificationService.AnswerCallbackQuery(callback.ID, "该记录已失效："+recordStatus(mute.Status, mute.ExpireAt, mute.UnmuteAt, mute.UnmuteReason), true)
+			return
+		}
+		h.handleMuteRecordAction(callback, mute, action, parts[4:], operatorName)
+	}
+}
+
+// recordActionPermission 通知按钮操作需要的权限（lift 为解除权限）
+func recordActionPermission(action, liftPermission string) string {
+	switch action {
+	case "extend":
+		return models.PermModify
+	case "history":
+		return models.PermInfo
+	default:
+		return liftPermission
+	}
+}
+
+// handleBanRecordAction 执行拉黑通知上的延长和解除操作
+func (h *Handler) handleBanRecordAction(callback *tgbotapi.CallbackQuery, ban *models.Blacklist, action string, args []string, operatorName string) {
+	switch action {
+	case "extend":
+		expireAt, err := extendExpireTime(ban.ExpireAt, args)
+		if err != nil {
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ "+err.Error(), true)
+			return
+		}
+		if err := h.checkModifiedDuration(callbackMessage(callback), models.PermBan, expireAt); err != nil {
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ "+err.Error(), true)
+			return
+		}
+		// 通知按钮无法提交审批，改为永久或超过审批天数时需要通过 /extend 命令提交审批
+		if reason := h.banExpiryApproval(callback.From.ID, expireAt); reason != "" {
+			h.notificationService.AnswerCallbackQuery(callback.ID,
+				fmt.Sprintf("❌ 该修改需要审批（%s），请在群组中使用 /extend 命令提交", reason), true)
+			return
+		}
+		note, _, err := h.changeBanExpiry(ban, expireAt, callback.From.ID, operatorName)
+		if err != nil {
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ "+err.Error(), true)
+			return
+		}
+		h.notificationService.AnswerCallbackQuery(callback.ID, "✅ "+note, false)
+	case "lift":
+		caseID, err := h.liftBan(ban, callback.From.ID, operatorName)
+		if err != nil {
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ "+err.Error(), true)
+			return
+		}
+		h.notificationService.AnswerCallbackQuery(callback.ID, fmt.Sprintf("✅ 已解除拉黑（案件 #%d）", caseID), false)
+	}
+}
+
+// handleMuteRecordAction 执行禁言通知上的延长和解除操作
+func (h *Handler) handleMuteRecordAction(callback *tgbotapi.CallbackQuery, mute *models.MuteList, action string, args []string, operatorName string) {
+	switch action {
+	case "extend":
+		expireAt, err := extendExpireTime(mute.ExpireAt, args)
+		if err != nil {
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ "+err.Error(), true)
+			return
+		}
+		if err := h.checkModifiedDuration(callbackMessage(callback), models.PermMute, expireAt); err != nil {
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ "+err.Error(), true)
+			return
+		}
+		note, _, err := h.changeMuteExpiry(mute, expireAt, callback.From.ID, operatorName)
+		if err != nil {
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ "+err.Error(), true)
+			return
+		}
+		h.notificationService.AnswerCallbackQuery(callback.ID, "✅ "+note, false)
+	case "lift":
+		caseID, err := h.liftMute(mute, callback.From.ID, operatorName)
+		if err != nil {
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ "+err.Error(), true)
+			return
+		}
+		h.notificationService.AnswerCallbackQuery(callback.ID, fmt.Sprintf("✅ 已解除禁言（案件 #%d）", caseID), false)
+	}
+}
+
+// extendExpireTime 根据延长按钮参数计算新的到期时间（0 秒表示改为永久）
+func extendExpireTime(current *time.Time, args []string) (*time.Time, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("无效的操作")
+	}
+	seconds, err := strconv.Atoi(args[0])
+	if err != nil || seconds < 0 {
+		return nil, fmt.Errorf("无效的时长")
+	}
+	return newExpireTime(current, &CommandParams{Duration: seconds, Permanent: seconds == 0}, false)
+}
+
+// liftBan 解除拉黑记录并在 Telegram 中解封，返回解除操作的案件编号
+func (h *Handler) liftBan(ban *models.Blacklist, operatorID int64, operatorName string) (int64, error) {
+	if ban.IsLocal() {
+		if globalBanned, err := h.banService.HasActiveGlobalBan(ban.UserID); err == nil && globalBanned {
+			return 0, fmt.Errorf("该用户仍处于全局拉黑中，请先解除全局拉黑")
+		}
+	}
+
+	// 同一案件在其他群组保存的本群记录一并解除
+	records := h.caseBans(ban)
+	groupIDs := make([]int64, 0, len(records))
+	for _, record := range records {
+		groupIDs = append(groupIDs, record.GroupID)
+	}
+	if err := h.banService.UnbanUser(ban.UserID, "", operatorID, groupIDs, ban.Scope); err != nil {
+		logrus.Errorf("Failed to unban user %d: %v", ban.UserID, err)
+		return 0, fmt.Errorf("保存失败")
+	}
+
+	for _, record := range records {
+		h.applyToRecordGroups(record.GroupID, record.IsLocal(), func(groupID int64) tgbotapi.Chattable {
+			return tgbotapi.UnbanChatMemberConfig{
+				ChatMemberConfig: tgbotapi.ChatMemberConfig{
+					ChatID: groupID,
+					UserID: ban.UserID,
+				},
+				OnlyIfBanned: true,
+			}
+		})
+	}
+
+	caseID := h.logCase(&models.OperationLog{
+		OperationType:  models.OpTypeUnban,
+		TargetUserID:   ban.UserID,
+		TargetUsername: ban.Username,
+		GroupID:        ban.GroupID,
+		GroupName:      ban.GroupName,
+		OperatorID:     operatorID,
+		OperatorName:   operatorName,
+		Scope:          ban.Scope,
+		RecordID:       ban.ID,
+	})
+
+	groupUsername := h.groupUsername(ban.GroupID)
+	h.notificationService.SendUnbanNotification(ban.GroupID, ban.GroupName, groupUsername,
+		recordName(ban.FullName, ban.Username), ban.UserID, "", operatorName, operatorID, ban.IsLocal(), caseID)
+
+	if updated, err := h.banService.GetBan(ban.ID); err == nil {
+		ban = updated
+	}
+	if err := h.notificationService.UpdateBanNotification(ban, groupUsername,
+		fmt.Sprintf("已由 %s 解除（案件 #%d）", operatorName, caseID)); err != nil {
+		logrus.Warnf("Failed to edit ban notification: %v", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"记录ID": ban.ID,
+		"用户ID": ban.UserID,
+		"操作人":  operatorID,
+	}).Info("🔓 已通过按钮解除拉黑")
+	return caseID, nil
+}
+
+// liftMute 解除禁言记录并在 Telegram 中恢复权限，返回解除操作的案件编号
+func (h *Handler) liftMute(mute *models.MuteList, operatorID int64, operatorName string) (int64, error) {
+	if mute.IsLocal() {
+		if globalMuted, err := h.muteService.HasActiveGlobalMute(mute.UserID); err == nil && globalMuted {
+			return 0, fmt.Errorf("该用户仍处于全局禁言中，请先解除全局禁言")
+		}
+	}
+
+	// 同一案件在其他群组保存的本群记录一并解除
+	records := h.caseMutes(mute)
+	groupIDs := make([]int64, 0, len(records))
+	for _, record := range records {
+		groupIDs = append(groupIDs, record.GroupID)
+	}
+	if err := h.muteService.UnmuteUser(mute.UserID, "", operatorID, groupIDs, mute.Scope); err != nil {
+		logrus.Errorf("Failed to unmute user %d: %v", mute.UserID, err)
+		return 0, fmt.Errorf("保存失败")
+	}
+
+	for _, record := range records {
+		h.applyToRecordGroups(record.GroupID, record.IsLocal(), func(groupID int64) tgbotapi.Chattable {
+			return tgbotapi.RestrictChatMemberConfig{
+				ChatMemberConfig: tgbotapi.ChatMemberConfig{
+					ChatID: groupID,
+					UserID: mute.UserID,
+				},
+				// 恢复为群组当前的默认权限
+				Permissions: service.GetGroupDefaultPermissions(h.bot, groupID),
+			}
+		})
+	}
+
+	caseID := h.logCase(&models.OperationLog{
+		OperationType:  models.OpTypeUnmute,
+		TargetUserID:   mute.UserID,
+		TargetUsername: mute.Username,
+		GroupID:        mute.GroupID,
+		GroupName:      mute.GroupName,
+		OperatorID:     operatorID,
+		OperatorName:   operatorName,
+		Scope:          mute.Scope,
+		RecordID:       mute.ID,
+	})
+
+	groupUsername := h.groupUsername(mute.GroupID)
+	h.notificationService.SendUnmuteNotification(mute.GroupID, mute.GroupName, groupUsername,
+		recordName(mute.FullName, mute.Username), mute.UserID, "", operatorName, operatorID, mute.IsLocal(), caseID)
+
+	if updated, err := h.muteService.GetMute(mute.ID); err == nil {
+		mute = updated
+	}
+	if err := h.notificationService.UpdateMuteNotification(mute, groupUsername,
+		fmt.Sprintf("已由 %s 解除（案件 #%d）", operatorName, caseID)); err != nil {
+		logrus.Warnf("Failed to edit mute notification: %v", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"记录ID": mute.ID,
+		"用户ID": mute.UserID,
+		"操作人":  operatorID,
+	}).Info("🔈 已通过按钮解除禁言")
+	return caseID, nil
+}
+
+// refreshBanNotification 按记录当前状态刷新频道通知（移除已失效的按钮）
+func (h *Handler) refreshBanNotification(ban *models.Blacklist) {
+	status := recordStatus(ban.Status, ban.ExpireAt, ban.UnbanAt, ban.UnbanReason)
+	if err := h.notificationService.UpdateBanNotification(ban, h.groupUsername(ban.GroupID), "当前状态："+status); err != nil {
+		logrus.Warnf("Failed to refresh ban notification: %v", err)
+	}
+}
+
+// refreshMuteNotification 按记录当前状态刷新频道通知（移除已失效的按钮）
+func (h *Handler) refreshMuteNotification(mute *models.MuteList) {
+	status := recordStatus(mute.Status, mute.ExpireAt, mute.UnmuteAt, mute.UnmuteReason)
+	if err := h.notificationService.UpdateMuteNotification(mute, h.groupUsername(mute.GroupID), "当前状态："+status); err != nil {
+		logrus.Warnf("Failed to refresh mute notification: %v", err)
+	}
+}
+
+// sendRecordHistory 私聊发送用户最近的处罚记录（频道中不展开，避免刷屏）
+func (h *Handler) sendRecordHistory(callback *tgbotapi.CallbackQuery, userID int64) {
+	logs, err := h.logService.GetUserLogs(userID, recordHistoryLimit)
+	if err != nil {
+		logrus.Errorf("Failed to get user logs: %v", err)
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 获取历史记录失败", true)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(callback.From.ID, formatUserHistory(userID, logs))
+	msg.DisableWebPagePreview = true
+	if _, err := h.bot.Send(msg); err != nil {
+		logrus.Warnf("Failed to send history to user %d: %v", callback.From.ID, err)
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 无法私聊发送，请先私聊机器人发送 /start", true)
+		return
+	}
+	h.notificationService.AnswerCallbackQuery(callback.ID, "📜 已私聊发送历史记录", false)
+}
+
+// formatUserHistory 生成用户最近操作记录（纯文本）
+func formatUserHistory(userID int64, logs []models.OperationLog) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📜 用户 %d 的最近记录\n\n", userID))
+	if len(logs) == 0 {
+		sb.WriteString("暂无记录")
+		return sb.String()
+	}
+	for _, log := range logs {
+		sb.WriteString(formatLogLine(&log) + "\n")
+	}
+	sb.WriteString("\n使用 /case 编号 查看详情")
+	return sb.String()
+}
+
+// formatLogLine 操作记录中的一行：案件编号、时间、类型、范围、时长、操作人和理由
+func formatLogLine(log *models.OperationLog) string {
+	line := fmt.Sprintf("%s %s %s", log.CaseNumber(), utils.FormatTimestamp(log.CreatedAt), models.OperationName(log.OperationType))
+	if log.Scope == models.ScopeLocal {
+		line += "（" + log.GroupName + "）"
+	}
+	if log.Duration != nil && (log.OperationType == models.OpTypeBan || log.OperationType == models.OpTypeMute) {
+		line += " " + utils.FormatDuration(*log.Duration)
+	}
+	if log.OperatorName != "" {
+		line += " · " + log.OperatorName
+	}
+	if log.Reason != "" {
+		line += "：" + utils.TruncateString(log.Reason, 100)
+	}
+	if log.Success != 1 {
+		line += "（失败）"
+	}
+	if log.IsReverted() {
+		line += "（已撤销）"
+	}
+	return line
+}
+
+// recordName 记录中显示的用户名称
+func recordName(fullName, username string) string {
+	if fullName != "" {
+		return fullName
+	}
+	return username
+}
@@ -0,0 +1,278 @@
+package bot
+
+import (
+	"admin-bot/internal/models"
+	"admin-bot/internal/utils"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// authorize 检查操作人是否有执行命令的权限，没有时回复缺少哪项权限
+func (h *Handler) authorize(message *tgbotapi.Message, permission string) bool {
+	ok, denial := h.permissionChecker.Authorize(message, permission)
+	if !ok {
+		h.denyPermission(message, nil, permission, denial)
+	}
+	return ok
+}
+
+// authorizeCallback 按按钮所在聊天检查点击者是否有某项权限，没有时弹出缺少哪项权限
+func (h *Handler) authorizeCallback(callback *tgbotapi.CallbackQuery, permission string) bool {
+	if callback.Message == nil {
+		return false
+	}
+	ok, denial := h.permissionChecker.Authorize(callbackMessage(callback), permission)
+	if !ok {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ "+denial, true)
+	}
+	return ok
+}
+
+// callbackMessage 把按钮所在的消息换成点击者发送，用于按聊天检查权限
+func callbackMessage(callback *tgbotapi.CallbackQuery) *tgbotapi.Message {
+	msg := *callback.Message
+	msg.From = callback.From
+	return &msg
+}
+
+// denyPermission 记录权限检查失败并回复原因（menuMsg 不为空时在菜单消息上显示）
+func (h *Handler) denyPermission(message, menuMsg *tgbotapi.Message, permission, denial string) {
+	logrus.WithFields(logrus.Fields{
+		"用户ID": message.From.ID,
+		"群组ID": message.Chat.ID,
+		"权限":   permission,
+		"原因":   denial,
+	}).Warn("⛔ 权限检查失败")
+	if menuMsg != nil {
+		h.editMessage(menuMsg.Chat.ID, menuMsg.MessageID, "❌ "+denial)
+		return
+	}
+	h.sendReply(message.Chat.ID, message.MessageID, "❌ "+denial)
+}
+
+// handleRolesCallback 显示角色管理页面：角色分配和各角色的权限
+func (h *Handler) handleRolesCallback(callback *tgbotapi.CallbackQuery) {
+	userRoles, err := h.roleService.ListUserRoles()
+	if err != nil {
+		logrus.Errorf("Failed to list user roles: %v", err)
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 获取角色失败", true)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🎭 *角色管理*\n\n")
+	sb.WriteString("未分配角色时：作者为所有者，全局管理员为" + models.RoleName(models.DefaultGlobalAdminRole) +
+		"，群管理员和群组版主为" + models.RoleName(models.DefaultGroupAdminRole) + "\n" +
+		"分配的角色只替代默认角色，用户仍需是全局管理员、群管理员或群组版主，且只在对应范围内有效\n\n")
+
+	sb.WriteString("*已分配的角色*\n")
+	if len(userRoles) == 0 {
+		sb.WriteString("暂无\n")
+	}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, userRole := range userRoles {
+		name := userRole.FullName
+		if name == "" {
+			name = fmt.Sprintf("用户 %d", userRole.UserID)
+		}
+		sb.WriteString(fmt.Sprintf("• %s（`%d`）：%s\n", utils.EscapeMarkdown(name), userRole.UserID, models.RoleName(userRole.Role)))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🗑 %s", name),
+				fmt.Sprintf("config:del_role_%d", userRole.UserID)),
+		))
+	}
+
+	sb.WriteString("\n*角色权限*\n")
+	sb.WriteString(models.RoleName(models.RoleOwner) + "：全部权限\n")
+	for _, role := range models.Roles {
+		if role == models.RoleOwner {
+			continue
+		}
+		perms, err := h.roleService.GetRolePermissions(role)
+		if err != nil {
+			logrus.Errorf("Failed to get role permissions: %v", err)
+			continue
+		}
+		sb.WriteString(models.RoleName(role) + "：" + formatRolePermissions(perms) + "\n")
+	}
+
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("➕ 分配角色", "config:add_role"),
+			tgbotapi.NewInlineKeyboardButtonData("⚙️ 修改权限", "config:set_role_perm"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ 返回", "config:back"),
+		),
+	)
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	h.notificationService.EditMessage(callback.Message.Chat.ID, callback.Message.MessageID, sb.String(), &keyboard)
+	h.notificationService.AnswerCallbackQuery(callback.ID, "", false)
+}
+
+// formatRolePermissions 权限列表说明，如 "警告、禁言（最长 1 天）"
+func formatRolePermissions(perms []models.RolePermission) string {
+	if len(perms) == 0 {
+		return "无"
+	}
+	names := make([]string, 0, len(perms))
+	for _, perm := range perms {
+		name := models.PermissionName(perm.Permission)
+		if perm.MaxDuration > 0 {
+			name += "（最长 " + utils.FormatDuration(perm.MaxDuration) + "）"
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, "、")
+}
+
+// handleAddRoleCallback 处理分配角色回调
+func (h *Handler) handleAddRoleCallback(callback *tgbotapi.CallbackQuery) {
+	setUserState(callback.From.ID, "waiting_role", nil)
+
+	text := fmt.Sprintf("请发送用户ID和角色，用空格分隔\n\n格式示例：`123456789 %s`\n\n可用角色：%s\n\n发送 /cancel 取消操作",
+		models.RoleModerator, roleChoices())
+	h.notificationService.EditMessage(callback.Message.Chat.ID, callback.Message.MessageID, text, nil)
+	h.notificationService.AnswerCallbackQuery(callback.ID, "请发送用户ID和角色", false)
+}
+
+// handleSetRolePermCallback 处理修改角色权限回调
+func (h *Handler) handleSetRolePermCallback(callback *tgbotapi.CallbackQuery) {
+	setUserState(callback.From.ID, "waiting_role_perm", nil)
+
+	perms := make([]string, 0, len(models.Permissions))
+	for _, perm := range models.Permissions {
+		perms = append(perms, fmt.Sprintf("`%s` %s", perm, models.PermissionName(perm)))
+	}
+	text := "请发送角色、权限和设置，用空格分隔\n\n" +
+		"格式示例：\n`moderator mute 2h` 允许禁言，最长 2 小时\n`moderator ban on` 允许拉黑，不限时长\n`moderator ban off` 收回拉黑权限\n\n" +
+		"可用角色：" + roleChoices() + "\n\n可用权限：\n" + strings.Join(perms, "\n") + "\n\n发送 /cancel 取消操作"
+	h.notificationService.EditMessage(callback.Message.Chat.ID, callback.Message.MessageID, text, nil)
+	h.notificationService.AnswerCallbackQuery(callback.ID, "请发送权限设置", false)
+}
+
+// handleDelRoleCallback 取消用户的角色分配
+func (h *Handler) handleDelRoleCallback(callback *tgbotapi.CallbackQuery, action string) {
+	userID, err := strconv.ParseInt(strings.TrimPrefix(action, "del_role_"), 10, 64)
+	if err != nil {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 无效的用户", true)
+		return
+	}
+
+	if err := h.roleService.RemoveUserRole(userID); err != nil {
+		logrus.Errorf("Failed to remove user role: %v", err)
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 删除失败", true)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"操作人":  callback.From.ID,
+		"用户ID": userID,
+	}).Info("🎭 已取消角色分配")
+
+	// 刷新页面
+	h.handleRolesCallback(callback)
+}
+
+// handleWaitingRole 处理等待输入角色分配（仅私聊）
+func (h *Handler) handleWaitingRole(message *tgbotapi.Message) {
+	parts := strings.Fields(message.Text)
+	if len(parts) != 2 {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 格式错误，请发送：用户ID 角色\n\n例如：123456789 "+models.RoleModerator)
+		return
+	}
+
+	userID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 无效的用户ID格式，请重新输入或发送 /cancel 取消")
+		return
+	}
+	role := strings.ToLower(parts[1])
+	if !models.IsValidRole(role) {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 无效的角色，可用角色："+roleChoices())
+		return
+	}
+
+	// 尽量从用户缓存获取名字
+	fullName := ""
+	if cached, err := h.userCacheService.GetUserByID(userID); err == nil {
+		fullName = strings.TrimSpace(cached.FirstName + " " + cached.LastName)
+	}
+
+	if err := h.roleService.SetUserRole(userID, role, fullName, message.From.ID); err != nil {
+		logrus.Errorf("Failed to set user role: %v", err)
+		h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("❌ 分配失败：%v", err))
+		return
+	}
+
+	clearUserState(message.From.ID)
+	h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("✅ 已将用户 %d 设为%s", userID, models.RoleName(role)))
+
+	logrus.WithFields(logrus.Fields{
+		"操作人":  message.From.ID,
+		"用户ID": userID,
+		"角色":   role,
+	}).Info("🎭 已分配角色")
+
+	h.showConfigMenu(message.Chat.ID)
+}
+
+// handleWaitingRolePerm 处理等待输入角色权限设置（仅私聊）
+func (h *Handler) handleWaitingRolePerm(message *tgbotapi.Message) {
+	parts := strings.Fields(strings.ToLower(message.Text))
+	if len(parts) != 3 {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ 格式错误，请发送：角色 权限 时长/on/off\n\n例如：moderator mute 2h")
+		return
+	}
+	role, permission, setting := parts[0], parts[1], parts[2]
+
+	var err error
+	var result string
+	switch {
+	case setting == "off":
+		err = h.roleService.RevokePermission(role, permission)
+		result = "已收回"
+	case setting == "on" || utils.IsPermanentKeyword(setting):
+		err = h.roleService.SetPermission(role, permission, 0)
+		result = "已授予（不限时长）"
+	default:
+		seconds, parseErr := utils.ParseDuration(setting)
+		if parseErr != nil || seconds <= 0 {
+			h.sendReply(message.Chat.ID, message.MessageID, "❌ 无效的设置，请使用时长（如 2h、1d）、on 或 off")
+			return
+		}
+		err = h.roleService.SetPermission(role, permission, seconds)
+		result = "已授予（最长 " + utils.FormatDuration(seconds) + "）"
+	}
+	if err != nil {
+		h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("❌ %s", err.Error()))
+		return
+	}
+
+	clearUserState(message.From.ID)
+	h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("✅ %s「%s」权限%s",
+		models.RoleName(role), models.PermissionName(permission), result))
+
+	logrus.WithFields(logrus.Fields{
+		"操作人": message.From.ID,
+		"角色":  role,
+		"权限":  permission,
+		"设置":  setting,
+	}).Info("🎭 已修改角色权限")
+
+	h.showConfigMenu(message.Chat.ID)
+}
+
+// roleChoices 可用角色说明，如 "`moderator` 版主、`senior` 高级管理员"
+func roleChoices() string {
+	choices := make([]string, 0, len(models.Roles))
+	for _, role := range models.Roles {
+		choices = append(choices, fmt.Sprintf("`%s` %s", role, models.RoleName(role)))
+	}
+	return strings.Join(choices, "、")
+}
@@ -0,0 +1,585 @@
+package bot
+
+import (
+	"admin-bot/internal/models"
+	"admin-bot/internal/service"
+	"admin-bot/internal/utils"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// UndoBatch 群组回复上的撤销按钮对应的一次命令（批量操作包含多个案件）
+type UndoBatch struct {
+	OperatorID int64
+	CaseIDs    []int64
+	Snapshots  map[int64]*recordSnapshot // 修改案件编号 -> 修改前的记录状态（仅 /extend、/shorten、/editreason）
+	CreatedAt  time.Time
+}
+
+// recordSnapshot 修改拉黑或禁言记录前后的状态，撤销修改时据此恢复
+type recordSnapshot struct {
+	IsBan      bool
+	RecordID   int64
+	CaseID     int64 // 修改时记录对应的处罚案件，重新处罚后不再允许撤销修改
+	ExpireAt   *time.Time
+	Reason     string
+	ReasonCode string
+
+	// 修改后的状态，记录之后又被修改时不再允许撤销
+	EditedExpireAt *time.Time
+	EditedReason   string
+}
+
+// banSnapshot 记录修改前的拉黑状态
+func banSnapshot(ban *models.Blacklist) *recordSnapshot {
+	return &recordSnapshot{
+		IsBan:      true,
+		RecordID:   ban.ID,
+		CaseID:     ban.CaseID,
+		ExpireAt:   ban.ExpireAt,
+		Reason:     ban.Reason,
+		ReasonCode: ban.ReasonCode,
+	}
+}
+
+// muteSnapshot 记录修改前的禁言状态
+func muteSnapshot(mute *models.MuteList) *recordSnapshot {
+	return &recordSnapshot{
+		RecordID:   mute.ID,
+		CaseID:     mute.CaseID,
+		ExpireAt:   mute.ExpireAt,
+		Reason:     mute.Reason,
+		ReasonCode: mute.ReasonCode,
+	}
+}
+
+// edited 补充修改后的状态（ban 和 mute 只有一个不为空）
+func (s *recordSnapshot) edited(ban *models.Blacklist, mute *models.MuteList) *recordSnapshot {
+	if ban != nil {
+		s.EditedExpireAt, s.EditedReason = ban.ExpireAt, ban.Reason
+	} else {
+		s.EditedExpireAt, s.EditedReason = mute.ExpireAt, mute.Reason
+	}
+	return s
+}
+
+// unchangedSince 记录当前状态是否仍是修改后的状态
+func (s *recordSnapshot) unchangedSince(expireAt *time.Time, reason string, caseID int64) bool {
+	return caseID == s.CaseID && reason == s.EditedReason && sameExpireAt(expireAt, s.EditedExpireAt)
+}
+
+// sameExpireAt 比较到期时间（空表示永久）
+func sameExpireAt(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Unix() == b.Unix()
+}
+
+var (
+	undoBatches    = make(map[string]*UndoBatch)
+	undoBatchSeq   int64
+	undoBatchMutex sync.Mutex
+)
+
+// undoWindow 撤销时限（0 表示关闭撤销）
+func (h *Handler) undoWindow() time.Duration {
+	if h.cfg.System.UndoWindow <= 0 {
+		return 0
+	}
+	return time.Duration(h.cfg.System.UndoWindow) * time.Second
+}
+
+// undoKeyboard 生成群组回复上的撤销按钮（undo:batch:<ID>），没有可撤销的案件或已关闭撤销时返回 nil
+func (h *Handler) undoKeyboard(operatorID int64, caseIDs []int64) *tgbotapi.InlineKeyboardMarkup {
+	return h.newUndoKeyboard(operatorID, caseIDs, nil)
+}
+
+// undoEditKeyboard 生成修改命令回复上的撤销按钮，撤销时按快照恢复修改前的状态
+func (h *Handler) undoEditKeyboard(operatorID int64, snapshots map[int64]*recordSnapshot) *tgbotapi.InlineKeyboardMarkup {
+	caseIDs := make([]int64, 0, len(snapshots))
+	for caseID := range snapshots {
+		caseIDs = append(caseIDs, caseID)
+	}
+	sort.Slice(caseIDs, func(i, j int) bool { return caseIDs[i] < caseIDs[j] })
+	return h.newUndoKeyboard(operatorID, caseIDs, snapshots)
+}
+
+// newUndoKeyboard 登记撤销批次并生成撤销按钮
+func (h *Handler) newUndoKeyboard(operatorID int64, caseIDs []int64, snapshots map[int64]*recordSnapshot) *tgbotapi.InlineKeyboardMarkup {
+	window := h.undoWindow()
+	if window == 0 {
+		return nil
+	}
+	ids := make([]int64, 0, len(caseIDs))
+	for _, id := range caseIDs {
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	undoBatchMutex.Lock()
+	// 顺便清理过期的批次
+	now := time.Now()
+	for id, batch := range undoBatches {
+		if now.Sub(batch.CreatedAt) > window {
+			delete(undoBatches, id)
+		}
+	}
+	undoBatchSeq++
+	batchID := strconv.FormatInt(undoBatchSeq, 36)
+	undoBatches[batchID] = &UndoBatch{
+		OperatorID: operatorID,
+		CaseIDs:    ids,
+		Snapshots:  snapshots,
+		CreatedAt:  now,
+	}
+	undoBatchMutex.Unlock()
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("↩️ 撤销（%s内有效）", utils.FormatDuration(h.cfg.System.UndoWindow)), "undo:batch:"+batchID),
+	))
+	return &keyboard
+}
+
+// takeUndoBatch 取出撤销批次（已过期或已被撤销返回 nil），remove 为 true 时同时移除
+func takeUndoBatch(batchID string, window time.Duration, remove bool) *UndoBatch {
+	undoBatchMutex.Lock()
+	defer undoBatchMutex.Unlock()
+
+	batch, exists := undoBatches[batchID]
+	if !exists {
+		return nil
+	}
+	if time.Since(batch.CreatedAt) > window {
+		delete(undoBatches, batchID)
+		return nil
+	}
+	if remove {
+		delete(undoBatches, batchID)
+	}
+	return batch
+}
+
+// handleUndoCallback 处理撤销按钮（undo:batch:<ID> 位于群组回复，undo:case:<案件编号> 位于频道通知）
+// 操作人本人可以撤销；作者和全局管理员还需要角色有对应的解除权限
+func (h *Handler) handleUndoCallback(callback *tgbotapi.CallbackQuery) {
+	parts := strings.Split(callback.Data, ":")
+	if len(parts) != 3 {
+		return
+	}
+	window := h.undoWindow()
+	if window == 0 {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 撤销功能已关闭", true)
+		return
+	}
+
+	operatorID := callback.From.ID
+	_, operatorName := GetUserInfo(callback.From)
+
+	var caseIDs []int64
+	var snapshots map[int64]*recordSnapshot
+	switch parts[1] {
+	case "batch":
+		batch := takeUndoBatch(parts[2], window, false)
+		if batch == nil {
+			h.removeUndoButton(callback.Message, "")
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 已超过撤销时限", true)
+			return
+		}
+		if batch.OperatorID != operatorID && !h.authorizeUndo(callback, batch.CaseIDs) {
+			return
+		}
+		// 移除批次，防止重复点击重复撤销
+		if takeUndoBatch(parts[2], window, true) == nil {
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 已撤销", true)
+			return
+		}
+		caseIDs = batch.CaseIDs
+		snapshots = batch.Snapshots
+	case "case":
+		caseID, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			return
+		}
+		log, err := h.logService.GetCase(caseID)
+		if err != nil {
+			h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 案件不存在", true)
+			return
+		}
+		if log.OperatorID != operatorID && !h.authorizeUndo(callback, []int64{caseID}) {
+			return
+		}
+		caseIDs = []int64{caseID}
+	default:
+		return
+	}
+
+	reverted := make([]int64, 0, len(caseIDs))
+	var failures []string
+	for _, caseID := range caseIDs {
+		if err := h.revertCase(caseID, snapshots[caseID], window, operatorID, operatorName); err != nil {
+			failures = append(failures, fmt.Sprintf("#%d：%s", caseID, err.Error()))
+			continue
+		}
+		reverted = append(reverted, caseID)
+	}
+
+	if parts[1] == "batch" && len(reverted) > 0 {
+		h.removeUndoButton(callback.Message, fmt.Sprintf("↩️ 已由 %s 撤销%s", operatorName, caseSuffix(reverted)))
+	}
+
+	switch {
+	case len(failures) == 0:
+		h.notificationService.AnswerCallbackQuery(callback.ID, fmt.Sprintf("✅ 已撤销 %d 个案件", len(reverted)), false)
+	case len(reverted) == 0:
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 撤销失败\n"+strings.Join(failures, "\n"), true)
+	default:
+		h.notificationService.AnswerCallbackQuery(callback.ID, fmt.Sprintf("⚠️ 已撤销 %d 个案件，失败：\n%s", len(reverted), strings.Join(failures, "\n")), true)
+	}
+}
+
+// authorizeUndo 检查撤销他人操作的权限：需要是作者或全局管理员，且角色有解除对应处罚的权限（与解除按钮一致）
+func (h *Handler) authorizeUndo(callback *tgbotapi.CallbackQuery, caseIDs []int64) bool {
+	userID := callback.From.ID
+	if !h.cfg.Telegram.IsAuthor(userID) && !h.permissionChecker.IsGlobalAdmin(userID) {
+		h.notificationService.AnswerCallbackQuery(callback.ID, "❌ 只有操作人本人、作者和全局管理员可以撤销", true)
+		return false
+	}
+
+	checked := make(map[string]bool)
+	for _, caseID := range caseIDs {
+		log, err := h.logService.GetCase(caseID)
+		if err != nil {
+			continue
+		}
+		permission := models.PermUnmute
+		if log.OperationType == models.OpTypeBan || log.OperationType == models.OpTypeBanEdit {
+			permission = models.PermUnban
+		}
+		if checked[permission] {
+			continue
+		}
+		if !h.authorizeCallback(callback, permission) {
+			return false
+		}
+		checked[permission] = true
+	}
+	return true
+}
+
+// revertCase 撤销拉黑或禁言案件：记录和案件标记为已撤销，恢复被该案件替代的上一条记录，
+// 在 Telegram 中按恢复后的状态重新限制或解除限制并编辑频道通知；撤销不产生新的解除案件和解除通知
+// 修改案件（snapshot 不为空）按快照恢复修改前的到期时间和理由
+func (h *Handler) revertCase(caseID int64, snapshot *recordSnapshot, window time.Duration, operatorID int64, operatorName string) error {
+	log, err := h.logService.GetCase(caseID)
+	if err != nil {
+		return fmt.Errorf("案件不存在")
+	}
+	if log.IsReverted() {
+		return fmt.Errorf("案件已被撤销")
+	}
+	if time.Since(log.CreatedAt) > window {
+		return fmt.Errorf("已超过撤销时限")
+	}
+	if snapshot != nil && log.RecordID == snapshot.RecordID &&
+		(log.OperationType == models.OpTypeBanEdit || log.OperationType == models.OpTypeMuteEdit) {
+		return h.revertEdit(log, snapshot, operatorID, operatorName)
+	}
+	if log.RecordID == 0 || (log.OperationType != models.OpTypeBan && log.OperationType != models.OpTypeMute) {
+		return fmt.Errorf("只能撤销拉黑和禁言")
+	}
+
+	revertReason := fmt.Sprintf("撤销案件 #%d", caseID)
+	if log.OperationType == models.OpTypeBan {
+		ban, err := h.banService.GetBan(log.RecordID)
+		if err != nil {
+			return fmt.Errorf("记录不存在")
+		}
+		if ban.CaseID != log.ID {
+			return fmt.Errorf("记录已被案件 #%d 更新，无法撤销", ban.CaseID)
+		}
+		records := h.caseBans(ban)
+		if err := h.banService.RevertBan(ban.ID, revertReason, operatorID); err != nil {
+			return err
+		}
+		for _, record := range records[1:] {
+			if err := h.banService.RevertBan(record.ID, revertReason, operatorID); err != nil {
+				logrus.Warnf("Failed to revert ban record %d: %v", record.ID, err)
+			}
+		}
+		h.markCaseReverted(caseID, operatorID, operatorName)
+		for _, record := range records {
+			h.revertBanRecord(record, caseID)
+		}
+
+		if updated, err := h.banService.GetBan(ban.ID); err == nil {
+			ban = updated
+		}
+		if err := h.notificationService.UpdateBanNotification(ban, h.groupUsername(ban.GroupID),
+			fmt.Sprintf("已由 %s 撤销", operatorName)); err != nil {
+			logrus.Warnf("Failed to edit ban notification: %v", err)
+		}
+	} else {
+		mute, err := h.muteService.GetMute(log.RecordID)
+		if err != nil {
+			return fmt.Errorf("记录不存在")
+		}
+		if mute.CaseID != log.ID {
+			return fmt.Errorf("记录已被案件 #%d 更新，无法撤销", mute.CaseID)
+		}
+		records := h.caseMutes(mute)
+		if err := h.muteService.RevertMute(mute.ID, revertReason, operatorID); err != nil {
+			return err
+		}
+		for _, record := range records[1:] {
+			if err := h.muteService.RevertMute(record.ID, revertReason, operatorID); err != nil {
+				logrus.Warnf("Failed to revert mute record %d: %v", record.ID, err)
+			}
+		}
+		h.markCaseReverted(caseID, operatorID, operatorName)
+		for _, record := range records {
+			h.revertMuteRecord(record, caseID)
+		}
+
+		if updated, err := h.muteService.GetMute(mute.ID); err == nil {
+			mute = updated
+		}
+		if err := h.notificationService.UpdateMuteNotification(mute, h.groupUsername(mute.GroupID),
+			fmt.Sprintf("已由 %s 撤销", operatorName)); err != nil {
+			logrus.Warnf("Failed to edit mute notification: %v", err)
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"案件编号": caseID,
+		"操作类型": log.OperationType,
+		"用户ID": log.TargetUserID,
+		"操作人":  operatorID,
+	}).Info("↩️ 已撤销案件")
+	return nil
+}
+
+// revertBanRecord 恢复被已撤销记录替代的上一条记录，该群仍有生效中的拉黑记录（包括恢复的上一条记录或其他记录）时
+// 按该记录的到期时间重新封禁，否则解除
+func (h *Handler) revertBanRecord(ban *models.Blacklist, caseID int64) {
+	h.restoreReplacedBan(ban, caseID)
+	h.applyToRecordGroups(ban.GroupID, ban.IsLocal(), func(groupID int64) tgbotapi.Chattable {
+		if stillBanned, active, err := h.banService.IsUserBanned(ban.UserID, groupID); err == nil && stillBanned {
+			return tgbotapi.KickChatMemberConfig{
+				ChatMemberConfig: tgbotapi.ChatMemberConfig{
+					ChatID: groupID,
+					UserID: ban.UserID,
+				},
+				UntilDate: telegramUntilDate(active.ExpireAt),
+			}
+		}
+		return tgbotapi.UnbanChatMemberConfig{
+			ChatMemberConfig: tgbotapi.ChatMemberConfig{
+				ChatID: groupID,
+				UserID: ban.UserID,
+			},
+			OnlyIfBanned: true,
+		}
+	})
+}
+
+// revertMuteRecord 恢复被已撤销记录替代的上一条记录，该群仍有生效中的禁言记录（包括恢复的上一条记录或其他记录）时
+// 按该记录的到期时间和禁言模式重新禁言，否则解除
+func (h *Handler) revertMuteRecord(mute *models.MuteList, caseID int64) {
+	h.restoreReplacedMute(mute, caseID)
+	h.applyToRecordGroups(mute.GroupID, mute.IsLocal(), func(groupID int64) tgbotapi.Chattable {
+		if stillMuted, active, err := h.muteService.IsUserMuted(mute.UserID, groupID); err == nil && stillMuted {
+			return tgbotapi.RestrictChatMemberConfig{
+				ChatMemberConfig: tgbotapi.ChatMemberConfig{
+					ChatID: groupID,
+					UserID: mute.UserID,
+				},
+				Permissions: service.GetMutePermissions(h.bot, groupID, active.Mode),
+				UntilDate:   telegramUntilDate(active.ExpireAt),
+			}
+		}
+		return tgbotapi.RestrictChatMemberConfig{
+			ChatMemberConfig: tgbotapi.ChatMemberConfig{
+				ChatID: groupID,
+				UserID: mute.UserID,
+			},
+			// 恢复为群组当前的默认权限
+			Permissions: service.GetGroupDefaultPermissions(h.bot, groupID),
+		}
+	})
+}
+
+// revertEdit 撤销对拉黑或禁言记录的修改：恢复修改前的到期时间和理由，在 Telegram 中按恢复后的到期时间重新限制
+// 记录已解除、已被重新处罚或之后又被修改时拒绝撤销
+func (h *Handler) revertEdit(log *models.OperationLog, snapshot *recordSnapshot, operatorID int64, operatorName string) error {
+	note := fmt.Sprintf("已由 %s 撤销修改", operatorName)
+	if snapshot.IsBan {
+		ban, err := h.banService.GetBan(snapshot.RecordID)
+		if err != nil {
+			return fmt.Errorf("记录不存在")
+		}
+		if !ban.IsActive() {
+			return fmt.Errorf("记录已不在生效中")
+		}
+		if !snapshot.unchangedSince(ban.ExpireAt, ban.Reason, ban.CaseID) {
+			return fmt.Errorf("记录之后已被再次修改，无法撤销")
+		}
+		expiryChanged := !sameExpireAt(ban.ExpireAt, snapshot.ExpireAt)
+		reasonChanged := ban.Reason != snapshot.Reason || ban.ReasonCode != snapshot.ReasonCode
+		records := h.caseBans(ban)
+		for i, record := range records {
+			if expiryChanged {
+				err = h.banService.UpdateBanExpiry(record, snapshot.ExpireAt)
+			}
+			if err == nil && reasonChanged {
+				err = h.banService.UpdateBanReason(record, snapshot.Reason, snapshot.ReasonCode)
+			}
+			if err != nil {
+				logrus.Errorf("Failed to restore ban record %d: %v", record.ID, err)
+				if i == 0 {
+					return fmt.Errorf("保存失败")
+				}
+				err = nil
+			}
+		}
+		h.markCaseReverted(log.ID, operatorID, operatorName)
+
+		// 恢复的到期时间已过时由定时任务解除，这里不再封禁
+		if expiryChanged && !ban.IsExpired() {
+			for _, record := range records {
+				h.reapplyBanExpiry(record)
+			}
+		}
+		if err := h.notificationService.UpdateBanNotification(ban, h.groupUsername(ban.GroupID), note); err != nil {
+			logrus.Warnf("Failed to edit ban notification: %v", err)
+		}
+	} else {
+		mute, err := h.muteService.GetMute(snapshot.RecordID)
+		if err != nil {
+			return fmt.Errorf("记录不存在")
+		}
+		if !mute.IsActive() {
+			return fmt.Errorf("记录已不在生效中")
+		}
+		if !snapshot.unchangedSince(mute.ExpireAt, mute.Reason, mute.CaseID) {
+			return fmt.Errorf("记录之后已被再次修改，无法撤销")
+		}
+		expiryChanged := !sameExpireAt(mute.ExpireAt, snapshot.ExpireAt)
+		reasonChanged := mute.Reason != snapshot.Reason || mute.ReasonCode != snapshot.ReasonCode
+		records := h.caseMutes(mute)
+		for i, record := range records {
+			if expiryChanged {
+				err = h.muteService.UpdateMuteExpiry(record, snapshot.ExpireAt)
+			}
+			if err == nil && reasonChanged {
+				err = h.muteService.UpdateMuteReason(record, snapshot.Reason, snapshot.ReasonCode)
+			}
+			if err != nil {
+				logrus.Errorf("Failed to restore mute record %d: %v", record.ID, err)
+				if i == 0 {
+					return fmt.Errorf("保存失败")
+				}
+				err = nil
+			}
+		}
+		h.markCaseReverted(log.ID, operatorID, operatorName)
+
+		// 恢复的到期时间已过时由定时任务解除，这里不再禁言
+		if expiryChanged && !mute.IsExpired() {
+			for _, record := range records {
+				h.reapplyMuteExpiry(record)
+			}
+		}
+		if err := h.notificationService.UpdateMuteNotification(mute, h.groupUsername(mute.GroupID), note); err != nil {
+			logrus.Warnf("Failed to edit mute notification: %v", err)
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"案件编号": log.ID,
+		"操作类型": log.OperationType,
+		"用户ID": log.TargetUserID,
+		"操作人":  operatorID,
+	}).Info("↩️ 已撤销修改")
+	return nil
+}
+
+// restoreReplacedBan 撤销的拉黑记录替代了上一条记录时恢复该记录并编辑其频道通知
+func (h *Handler) restoreReplacedBan(ban *models.Blacklist, caseID int64) {
+	if ban.ReplacesID == 0 {
+		return
+	}
+	if err := h.banService.RestoreReplacedBan(ban.ReplacesID); err != nil {
+		logrus.Warnf("Failed to restore replaced ban %d: %v", ban.ReplacesID, err)
+		return
+	}
+	previous, err := h.banService.GetBan(ban.ReplacesID)
+	if err != nil {
+		return
+	}
+	if err := h.notificationService.UpdateBanNotification(previous, h.groupUsername(previous.GroupID),
+		fmt.Sprintf("案件 #%d 已撤销，恢复本记录", caseID)); err != nil {
+		logrus.Warnf("Failed to edit ban notification: %v", err)
+	}
+}
+
+// restoreReplacedMute 撤销的禁言记录替代了上一条记录时恢复该记录并编辑其频道通知
+func (h *Handler) restoreReplacedMute(mute *models.MuteList, caseID int64) {
+	if mute.ReplacesID == 0 {
+		return
+	}
+	if err := h.muteService.RestoreReplacedMute(mute.ReplacesID); err != nil {
+		logrus.Warnf("Failed to restore replaced mute %d: %v", mute.ReplacesID, err)
+		return
+	}
+	previous, err := h.muteService.GetMute(mute.ReplacesID)
+	if err != nil {
+		return
+	}
+	if err := h.notificationService.UpdateMuteNotification(previous, h.groupUsername(previous.GroupID),
+		fmt.Sprintf("案件 #%d 已撤销，恢复本记录", caseID)); err != nil {
+		logrus.Warnf("Failed to edit mute notification: %v", err)
+	}
+}
+
+// telegramUntilDate 到期时间对应的 Telegram until_date（永久为 0）
+func telegramUntilDate(expireAt *time.Time) int64 {
+	if expireAt == nil {
+		return 0
+	}
+	return expireAt.Unix()
+}
+
+// markCaseReverted 将案件标记为已撤销（记录已撤销后标记失败只记录日志）
+func (h *Handler) markCaseReverted(caseID, operatorID int64, operatorName string) {
+	if err := h.logService.MarkCaseReverted(caseID, operatorID, operatorName); err != nil {
+		logrus.Errorf("Failed to mark case %d reverted: %v", caseID, err)
+	}
+}
+
+// removeUndoButton 移除群组回复上的撤销按钮，note 不为空时追加到消息末尾
+func (h *Handler) removeUndoButton(message *tgbotapi.Message, note string) {
+	if message == nil {
+		return
+	}
+	text := message.Text
+	if note != "" {
+		text += "\n\n" + note
+	}
+	edit := tgbotapi.NewEditMessageText(message.Chat.ID, message.MessageID, text)
+	edit.DisableWebPagePreview = true
+	if _, err := h.bot.Send(edit); err != nil {
+		logrus.Errorf("Failed to edit message: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package bot
+
+import (
+	"admin-bot/internal/models"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// handleWarn 处理 /warn 命令：警告用户（只记录案件并发送通知，不限制用户）
+func (h *Handler) handleWarn(message *tgbotapi.Message) {
+	if !h.authorize(message, models.PermWarn) {
+		return
+	}
+
+	if !message.Chat.IsGroup() && !message.Chat.IsSuperGroup() {
+		h.sendReply(message.Chat.ID, message.MessageID, "❌ /warn 只能在群组中使用")
+		return
+	}
+
+	params, err := ParseCommand(message, h.userCacheService)
+	if err != nil {
+		h.sendReply(message.Chat.ID, message.MessageID, fmt.Sprintf("❌ %s", err.Error()))
+		return
+	}
+	// 警告只针对当前群组
+	params.Local = true
+
+	_, operatorName := GetUserInfo(message.From)
+	groupName := GetChatTitle(message.Chat)
+	groupUsername := GetChatUsername(message.Chat)
+
+	lines := make([]string, 0, len(params.TargetUsers))
+	caseIDs := make([]int64, 0, len(params.TargetUsers))
+	for _, targetUserID := range params.TargetUsers {
+		targetUsername, targetName := h.resolveTargetUser(message.Chat.ID, targetUserID)
+
+		// 存档被回复的消息作为证据（必须在删除消息之前）
+		evidence := h.captureEvidence(message, targetUserID)
+		h.cleanupTargetMessages(message, params, targetUserID, h.getTargetGroups(message.Chat, params))
+
+		caseID := h.logCase(&models.OperationLog{
+			OperationType:  models.OpTypeWarn,
+			TargetUserID:   targetUserID,
+			TargetUsername: targetUsername,
+			GroupID:        message.Chat.ID,
+			GroupName:      groupName,
+			OperatorID:     message.From.ID,
+			OperatorName:   operatorName,
+			Reason:         params.Reason,
+			ReasonCode:     params.ReasonCode,
+			Evidence:       evidence,
+			Scope:          models.ScopeLocal,
+		})
+		caseIDs = append(caseIDs, caseID)
+
+		warnCount, err := h.logService.CountUserWarnings(targetUserID)
+		if err != nil {
+			logrus.Errorf("Failed to count user warnings: %v", err)
+		}
+
+		h.notificationService.SendWarnNotification(message.Chat.ID, groupName, groupUsername,
+			targetName, targetUserID, params.Reason, warnCount, operatorName, message.From.ID, evidence.ArchiveLink(), caseID)
+		h.notifyTarget(params, targetUserID, "警告", groupName, false)
+
+		lines = append(lines, fmt.Sprintf("%s（%d）：累计警告 %d 次", targetName, targetUserID, warnCount))
+
+		logrus.WithFields(logrus.Fields{
+			"用户ID": targetUserID,
+			"案件编号": caseID,
+			"警告次数": warnCount,
+		}).Info("⚠️ 已警告用户")
+	}
+
+	if params.Silent {
+		return
+	}
+	text := "⚠️ 已警告\n" + strings.Join(lines, "\n")
+	if params.Reason != "" {
+		text += "\n理由：" + params.Reason
+	}
+	h.sendReply(message.Chat.ID, message.MessageID, text+caseSuffix(caseIDs))
+}
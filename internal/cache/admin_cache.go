@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// AdminCache 群管理员名单缓存（每个群组一份 getChatAdministrators 的结果，权限检查不再逐次调用 API）
+type AdminCache struct {
+	rosters map[int64]*adminRoster // 群组ID -> 管理员名单
+	ttl     time.Duration          // 名单过期时间（过期后权限检查时重新获取）
+	mutex   sync.RWMutex
+}
+
+// adminRoster 单个群组的管理员名单
+type adminRoster struct {
+	members   map[int64]tgbotapi.ChatMember // 用户ID -> 成员信息（包括具体权限）
+	updatedAt time.Time
+}
+
+var (
+	globalAdminCache *AdminCache
+	adminCacheOnce   sync.Once
+)
+
+// InitAdminCache 初始化群管理员名单缓存
+func InitAdminCache(ttl time.Duration) *AdminCache {
+	adminCacheOnce.Do(func() {
+		globalAdminCache = &AdminCache{
+			rosters: make(map[int64]*adminRoster),
+			ttl:     ttl,
+		}
+		logrus.WithField("TTL", ttl).Info("✅ 群管理员名单缓存已初始化")
+	})
+	return globalAdminCache
+}
+
+// GetAdminCache 获取全局群管理员名单缓存实例
+func GetAdminCache() *AdminCache {
+	if globalAdminCache == nil {
+		// 默认 1 小时（定时同步和 chat_member 更新会提前刷新）
+		return InitAdminCache(time.Hour)
+	}
+	return globalAdminCache
+}
+
+// GetMember 查询用户在群组中的管理员信息
+// cached 为 false 表示该群组没有名单或名单已过期，需要重新获取；cached 为 true 且 member 为空表示不是管理员
+func (c *AdminCache) GetMember(groupID, userID int64) (member *tgbotapi.ChatMember, cached bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	roster, exists := c.rosters[groupID]
+	if !exists || time.Since(roster.updatedAt) > c.ttl {
+		return nil, false
+	}
+	if m, ok := roster.members[userID]; ok {
+		return &m, true
+	}
+	return nil, true
+}
+
+// SetRoster 替换群组的管理员名单，返回新增和移除的管理员（首次加载时不算变化）
+func (c *AdminCache) SetRoster(groupID int64, members []tgbotapi.ChatMember) (added, removed []tgbotapi.ChatMember) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	roster := &adminRoster{
+		members:   make(map[int64]tgbotapi.ChatMember, len(members)),
+		updatedAt: time.Now(),
+	}
+	for _, member := range members {
+		if member.User != nil {
+			roster.members[member.User.ID] = member
+		}
+	}
+
+	if old, exists := c.rosters[groupID]; exists {
+		for userID, member := range roster.members {
+			if _, ok := old.members[userID]; !ok {
+				added = append(added, member)
+			}
+		}
+		for userID, member := range old.members {
+			if _, ok := roster.members[userID]; !ok {
+				removed = append(removed, member)
+			}
+		}
+	}
+
+	c.rosters[groupID] = roster
+	return added, removed
+}
+
+// UpdateMember 根据 chat_member 更新修改名单中的单个成员（群组没有名单时忽略，等待下次完整获取）
+func (c *AdminCache) UpdateMember(groupID int64, member tgbotapi.ChatMember) {
+	if member.User == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	roster, exists := c.rosters[groupID]
+	if !exists {
+		return
+	}
+	if member.Status == "creator" || member.Status == "administrator" {
+		roster.members[member.User.ID] = member
+	} else {
+		delete(roster.members, member.User.ID)
+	}
+}
+
+// RemoveGroup 移除群组的名单（群组取消授权时调用）
+func (c *AdminCache) RemoveGroup(groupID int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.rosters, groupID)
+}
@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MessageCache 最近消息缓存（每个群组一个环形缓冲区，记录消息ID和发送者，用于清理目标用户的消息）
+type MessageCache struct {
+	chats  map[int64]*messageRing // 群组ID -> 环形缓冲区
+	size   int                    // 每个群组保留的消息数
+	maxAge time.Duration          // 消息最大保留时间（机器人只能删除 48 小时内的消息）
+	mutex  sync.Mutex
+}
+
+// messageRing 单个群组的环形缓冲区
+type messageRing struct {
+	entries []cachedMessage
+	next    int
+}
+
+// cachedMessage 缓存的消息
+type cachedMessage struct {
+	UserID    int64
+	MessageID int
+	Date      time.Time
+}
+
+var (
+	globalMessageCache *MessageCache
+	messageCacheOnce   sync.Once
+)
+
+// InitMessageCache 初始化最近消息缓存
+func InitMessageCache(size int, maxAge time.Duration) *MessageCache {
+	messageCacheOnce.Do(func() {
+		globalMessageCache = &MessageCache{
+			chats:  make(map[int64]*messageRing),
+			size:   size,
+			maxAge: maxAge,
+		}
+		logrus.WithFields(logrus.Fields{
+			"每群容量": size,
+			"保留时间": maxAge,
+		}).Info("✅ 最近消息缓存已初始化")
+	})
+	return globalMessageCache
+}
+
+// GetMessageCache 获取全局最近消息缓存实例
+func GetMessageCache() *MessageCache {
+	if globalMessageCache == nil {
+		// 默认每群 500 条，保留 48 小时
+		return InitMessageCache(500, 48*time.Hour)
+	}
+	return globalMessageCache
+}
+
+// Add 记录一条消息
+func (c *MessageCache) Add(chatID, userID int64, messageID int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	ring, exists := c.chats[chatID]
+	if !exists {
+		ring = &messageRing{entries: make([]cachedMessage, 0, c.size)}
+		c.chats[chatID] = ring
+	}
+
+	entry := cachedMessage{UserID: userID, MessageID: messageID, Date: time.Now()}
+	if len(ring.entries) < c.size {
+		ring.entries = append(ring.entries, entry)
+	} else {
+		ring.entries[ring.next] = entry
+	}
+	ring.next = (ring.next + 1) % c.size
+}
+
+// GetUserMessages 获取用户在群组中最近的消息ID（从新到旧，最多 limit 条）
+func (c *MessageCache) GetUserMessages(chatID, userID int64, limit int) []int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	ring, exists := c.chats[chatID]
+	if !exists {
+		return nil
+	}
+
+	messageIDs := make([]int, 0)
+	count := len(ring.entries)
+	for i := 1; i <= count && len(messageIDs) < limit; i++ {
+		entry := ring.entries[(ring.next-i+count)%count]
+		if time.Since(entry.Date) > c.maxAge {
+			break
+		}
+		if entry.UserID == userID {
+			messageIDs = append(messageIDs, entry.MessageID)
+		}
+	}
+	return messageIDs
+}
+
+// RemoveMessages 从缓存中移除已删除的消息
+func (c *MessageCache) RemoveMessages(chatID int64, messageIDs []int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	ring, exists := c.chats[chatID]
+	if !exists {
+		return
+	}
+
+	removed := make(map[int]bool, len(messageIDs))
+	for _, id := range messageIDs {
+		removed[id] = true
+	}
+	for i := range ring.entries {
+		if removed[ring.entries[i].MessageID] {
+			// 置零用户ID，保留位置以维持环形顺序
+			ring.entries[i].UserID = 0
+		}
+	}
+}
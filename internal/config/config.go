@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/spf13/viper"
 )
@@ -17,12 +18,32 @@ type Config struct {
 // TelegramConfig Telegram配置
 type TelegramConfig struct {
 	BotToken              string  `mapstructure:"bot_token"`
-	AuthorIDs             []int64 `mapstructure:"author_ids"`
+	AuthorIDs             []int64 `mapstructure:"author_ids"` // 配置文件中的作者（只能通过修改配置文件移除）
 	NotificationChannelID int64   `mapstructure:"notification_channel_id"`
+	EvidenceChatID        int64   `mapstructure:"evidence_chat_id"` // 证据存档聊天，0 表示使用通知频道
+
+	managedAuthorIDs []int64 // 通过 /config 添加的作者（保存在数据库）
+	authorMutex      sync.RWMutex
 }
 
-// IsAuthor 检查用户ID是否在作者列表中
+// IsAuthor 检查用户ID是否在作者列表中（包括配置文件和 /config 添加的作者）
 func (t *TelegramConfig) IsAuthor(userID int64) bool {
+	if t.IsConfigAuthor(userID) {
+		return true
+	}
+
+	t.authorMutex.RLock()
+	defer t.authorMutex.RUnlock()
+	for _, authorID := range t.managedAuthorIDs {
+		if authorID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// IsConfigAuthor 检查用户ID是否为配置文件中的作者
+func (t *TelegramConfig) IsConfigAuthor(userID int64) bool {
 	for _, authorID := range t.AuthorIDs {
 		if authorID == userID {
 			return true
@@ -31,6 +52,28 @@ func (t *TelegramConfig) IsAuthor(userID int64) bool {
 	return false
 }
 
+// GetAuthorIDs 获取所有作者ID（配置文件中的作者在前，去重）
+func (t *TelegramConfig) GetAuthorIDs() []int64 {
+	t.authorMutex.RLock()
+	defer t.authorMutex.RUnlock()
+
+	ids := make([]int64, 0, len(t.AuthorIDs)+len(t.managedAuthorIDs))
+	ids = append(ids, t.AuthorIDs...)
+	for _, authorID := range t.managedAuthorIDs {
+		if !t.IsConfigAuthor(authorID) {
+			ids = append(ids, authorID)
+		}
+	}
+	return ids
+}
+
+// SetManagedAuthorIDs 更新通过 /config 添加的作者（从数据库加载后调用）
+func (t *TelegramConfig) SetManagedAuthorIDs(ids []int64) {
+	t.authorMutex.Lock()
+	defer t.authorMutex.Unlock()
+	t.managedAuthorIDs = ids
+}
+
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
 	Host            string `mapstructure:"host"`
@@ -47,15 +90,56 @@ type DatabaseConfig struct {
 
 // SystemConfig 系统配置
 type SystemConfig struct {
-	RateLimitPerGroup int    `mapstructure:"rate_limit_per_group"`
-	AdminEnabled      bool   `mapstructure:"admin_enabled"`
-	LogLevel          string `mapstructure:"log_level"`
-	Timezone          string `mapstructure:"timezone"`
+	RateLimitPerGroup int            `mapstructure:"rate_limit_per_group"`
+	AdminEnabled      bool           `mapstructure:"admin_enabled"`
+	LogLevel          string         `mapstructure:"log_level"`
+	Timezone          string         `mapstructure:"timezone"`
+	UndoWindow        int            `mapstructure:"undo_window"`        // 拉黑/禁言后可撤销的时限（秒），0 表示关闭撤销
+	ProtectedUserIDs  []int64        `mapstructure:"protected_user_ids"` // 受保护的用户（不能被踢出、拉黑或禁言，作者确认后除外）
+	Approval          ApprovalConfig `mapstructure:"approval"`
+}
+
+// ApprovalConfig 双人审批配置：符合条件的踢出、拉黑和禁言需要另一名有权限的用户批准后才执行
+type ApprovalConfig struct {
+	Enabled       bool `mapstructure:"enabled"`
+	PermanentBan  bool `mapstructure:"permanent_ban"`  // 永久拉黑需要审批
+	BanDaysOver   int  `mapstructure:"ban_days_over"`  // 拉黑超过 N 天需要审批，0 表示不检查
+	BatchOver     int  `mapstructure:"batch_over"`     // 一次操作超过 N 名用户需要审批，0 表示不检查
+	Timeout       int  `mapstructure:"timeout"`        // 审批超时（秒），超时后自动取消
+	ExemptAuthors bool `mapstructure:"exempt_authors"` // 作者发起的操作不需要审批
+}
+
+// Reason 操作需要审批的原因，不需要审批时返回空字符串（seconds 为 0 表示永久）
+func (a *ApprovalConfig) Reason(isBan bool, seconds, targetCount int) string {
+	if !a.Enabled {
+		return ""
+	}
+	if isBan && a.PermanentBan && seconds == 0 {
+		return "永久拉黑"
+	}
+	if isBan && a.BanDaysOver > 0 && (seconds == 0 || seconds > a.BanDaysOver*86400) {
+		return fmt.Sprintf("拉黑超过 %d 天", a.BanDaysOver)
+	}
+	if a.BatchOver > 0 && targetCount > a.BatchOver {
+		return fmt.Sprintf("一次操作超过 %d 名用户", a.BatchOver)
+	}
+	return ""
+}
+
+// IsProtectedUser 检查用户ID是否在保护名单中
+func (s *SystemConfig) IsProtectedUser(userID int64) bool {
+	for _, protectedID := range s.ProtectedUserIDs {
+		if protectedID == userID {
+			return true
+		}
+	}
+	return false
 }
 
 // SchedulerConfig 调度器配置
 type SchedulerConfig struct {
 	CheckExpireInterval string `mapstructure:"check_expire_interval"`
+	AdminSyncInterval   string `mapstructure:"admin_sync_interval"` // 同步群管理员名单的间隔（cron 表达式）
 }
 
 var GlobalConfig *Config
@@ -95,8 +179,14 @@ func setDefaults() {
 	viper.SetDefault("system.admin_enabled", true)
 	viper.SetDefault("system.log_level", "info")
 	viper.SetDefault("system.timezone", "Asia/Shanghai")
+	viper.SetDefault("system.undo_window", 300)
+	viper.SetDefault("system.approval.enabled", false)
+	viper.SetDefault("system.approval.permanent_ban", true)
+	viper.SetDefault("system.approval.timeout", 3600)
+	viper.SetDefault("system.approval.exempt_authors", true)
 
 	viper.SetDefault("scheduler.check_expire_interval", "*/1 * * * *")
+	viper.SetDefault("scheduler.admin_sync_interval", "*/30 * * * *")
 }
 
 // GetConfig 获取全局配置
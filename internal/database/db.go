@@ -99,6 +99,12 @@ func AutoMigrate() error {
 		&models.OperationLog{},    // 操作日志表
 		&models.SystemConfig{},    // 系统配置表
 		&models.UserCache{},       // 用户缓存表
+		&models.ReasonPreset{},    // 预设理由表
+		&models.CaseNote{},        // 案件备注表
+		&models.RolePermission{},  // 角色权限表
+		&models.UserRole{},        // 用户角色表
+		&models.GroupModerator{},  // 群组版主表
+		&models.Author{},          // 作者表
 	}
 
 	// 批量迁移所有表结构（GORM 会自动处理表的创建和更新）
@@ -6,12 +6,20 @@ import (
 
 // GlobalAdmin 全局管理员表
 type GlobalAdmin struct {
-	ID       int64     `gorm:"primaryKey;autoIncrement" json:"id"`
-	UserID   int64     `gorm:"uniqueIndex;not null" json:"user_id"`
-	Username string    `gorm:"type:varchar(255)" json:"username"`
-	FullName string    `gorm:"type:varchar(255)" json:"full_name"`
-	AddedAt  time.Time `gorm:"autoCreateTime" json:"added_at"`
-	AddedBy  int64     `json:"added_by"`
+	ID          int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID      int64      `gorm:"uniqueIndex;not null" json:"user_id"`
+	Username    string     `gorm:"type:varchar(255)" json:"username"`
+	FullName    string     `gorm:"type:varchar(255)" json:"full_name"`
+	Reason      string     `gorm:"type:varchar(255)" json:"reason"` // 授权理由
+	ExpireAt    *time.Time `gorm:"index" json:"expire_at"`          // 到期时间，NULL 表示永久
+	AddedAt     time.Time  `gorm:"autoCreateTime" json:"added_at"`
+	AddedBy     int64      `json:"added_by"`
+	AddedByName string     `gorm:"type:varchar(255)" json:"added_by_name"`
+}
+
+// IsExpired 授权是否已到期（到期后由定时任务移除）
+func (a *GlobalAdmin) IsExpired() bool {
+	return a.ExpireAt != nil && !a.ExpireAt.After(time.Now())
 }
 
 // TableName 指定表名
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// Author 通过 /config 添加的作者表（配置文件中的作者不保存在这里）
+type Author struct {
+	ID       int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID   int64     `gorm:"uniqueIndex;not null" json:"user_id"`
+	Username string    `gorm:"type:varchar(255)" json:"username"`
+	FullName string    `gorm:"type:varchar(255)" json:"full_name"`
+	AddedAt  time.Time `gorm:"autoCreateTime" json:"added_at"`
+	AddedBy  int64     `json:"added_by"`
+}
+
+// TableName 指定表名
+func (Author) TableName() string {
+	return "authors"
+}
@@ -6,22 +6,30 @@ import (
 
 // Blacklist 拉黑记录表
 type Blacklist struct {
-	ID           int64      `gorm:"primaryKey;autoIncrement" json:"id"`
-	UserID       int64      `gorm:"index;not null" json:"user_id"`
-	Username     string     `gorm:"type:varchar(255)" json:"username"`
-	FullName     string     `gorm:"type:varchar(255)" json:"full_name"`
-	GroupID      int64      `gorm:"not null" json:"group_id"`
-	GroupName    string     `gorm:"type:varchar(255)" json:"group_name"`
-	OperatorID   int64      `gorm:"not null" json:"operator_id"`
-	OperatorName string     `gorm:"type:varchar(255)" json:"operator_name"`
-	Reason       string     `gorm:"type:text" json:"reason"`
-	Duration     *int       `json:"duration"` // 秒数，NULL 表示永久
-	ExpireAt     *time.Time `gorm:"index" json:"expire_at"`
-	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"created_at"`
-	Status       int8       `gorm:"default:1;index" json:"status"` // 1=生效中，0=已解除
-	UnbanReason  string     `gorm:"type:text" json:"unban_reason"`
-	UnbanAt      *time.Time `json:"unban_at"`
-	UnbanBy      *int64     `json:"unban_by"`
+	ID              int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID          int64      `gorm:"index;not null" json:"user_id"`
+	Username        string     `gorm:"type:varchar(255)" json:"username"`
+	FullName        string     `gorm:"type:varchar(255)" json:"full_name"`
+	GroupID         int64      `gorm:"not null" json:"group_id"`
+	GroupName       string     `gorm:"type:varchar(255)" json:"group_name"`
+	OperatorID      int64      `gorm:"not null" json:"operator_id"`
+	OperatorName    string     `gorm:"type:varchar(255)" json:"operator_name"`
+	Reason          string     `gorm:"type:text" json:"reason"`
+	ReasonCode      string     `gorm:"type:varchar(50);index" json:"reason_code"`          // 预设理由代码，自定义理由为空
+	Evidence        Evidence   `gorm:"embedded;embeddedPrefix:evidence_" json:"evidence"`  // 违规消息证据
+	Scope           string     `gorm:"type:varchar(20);default:global;index" json:"scope"` // global=全部授权群组，local=仅 GroupID 所在群组
+	Duration        *int       `json:"duration"`                                           // 秒数，NULL 表示永久
+	ExpireAt        *time.Time `gorm:"index" json:"expire_at"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+	Status          int8       `gorm:"default:1;index" json:"status"` // 1=生效中，0=已解除，2=已撤销，3=已被替代
+	UnbanReason     string     `gorm:"type:text" json:"unban_reason"`
+	UnbanAt         *time.Time `json:"unban_at"`
+	UnbanBy         *int64     `json:"unban_by"`
+	CaseID          int64      `json:"case_id"`           // 最近一次处罚的案件编号（操作日志ID）
+	NotifyChatID    int64      `json:"notify_chat_id"`    // 频道通知所在聊天，用于修改记录后同步编辑通知
+	NotifyMessageID int        `json:"notify_message_id"` // 频道通知消息ID
+	ReplacesID      int64      `json:"replaces_id"`       // 重新拉黑时被本记录替代的上一条记录ID，撤销本记录时恢复
 }
 
 // TableName 指定表名
@@ -29,6 +37,25 @@ func (Blacklist) TableName() string {
 	return "blacklist"
 }
 
+// Scopes 记录作用范围
+const (
+	ScopeGlobal = "global" // 全部授权群组
+	ScopeLocal  = "local"  // 仅操作所在群组
+)
+
+// Record statuses 拉黑/禁言记录状态
+const (
+	RecordStatusLifted   int8 = 0 // 已解除（手动或到期）
+	RecordStatusActive   int8 = 1 // 生效中
+	RecordStatusReverted int8 = 2 // 已撤销（误操作撤回，视为从未生效）
+	RecordStatusReplaced int8 = 3 // 已被替代（同一范围内重新拉黑/禁言时关闭的旧记录）
+)
+
+// IsLocal 是否为仅本群记录
+func (b *Blacklist) IsLocal() bool {
+	return b.Scope == ScopeLocal
+}
+
 // IsActive 是否生效中
 func (b *Blacklist) IsActive() bool {
 	if b.Status != 1 {
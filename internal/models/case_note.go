@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// CaseNote 案件备注表
+type CaseNote struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	CaseID     int64     `gorm:"index;not null" json:"case_id"` // 案件编号（操作日志ID）
+	AuthorID   int64     `gorm:"not null" json:"author_id"`
+	AuthorName string    `gorm:"type:varchar(255)" json:"author_name"`
+	Note       string    `gorm:"type:text" json:"note"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName 指定表名
+func (CaseNote) TableName() string {
+	return "case_notes"
+}
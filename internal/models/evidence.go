@@ -0,0 +1,25 @@
+package models
+
+import "admin-bot/internal/utils"
+
+// Evidence 违规消息证据（嵌入拉黑、禁言记录和操作日志中）
+type Evidence struct {
+	Text      string `gorm:"type:text" json:"text"`              // 消息文本或说明文字
+	MediaType string `gorm:"type:varchar(50)" json:"media_type"` // 消息类型：text/photo/video/sticker...
+	Link      string `gorm:"type:varchar(255)" json:"link"`      // 原消息链接
+	ChatID    int64  `json:"chat_id"`                            // 证据存档所在聊天ID
+	MessageID int    `json:"message_id"`                         // 证据存档消息ID（转发后的消息）
+}
+
+// HasEvidence 是否保存了证据
+func (e Evidence) HasEvidence() bool {
+	return e.MessageID != 0 || e.Link != "" || e.Text != ""
+}
+
+// ArchiveLink 证据存档消息的链接，没有存档时返回原消息链接（原消息可能已被删除）
+func (e Evidence) ArchiveLink() string {
+	if e.ChatID != 0 && e.MessageID != 0 {
+		return utils.FormatMessageLink(e.ChatID, "", e.MessageID)
+	}
+	return e.Link
+}
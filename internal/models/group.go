@@ -6,12 +6,44 @@ import (
 
 // AuthorizedGroup 授权群组表
 type AuthorizedGroup struct {
-	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
-	GroupID   int64     `gorm:"uniqueIndex;not null" json:"group_id"`
-	GroupName string    `gorm:"type:varchar(255)" json:"group_name"`
-	Username  string    `gorm:"type:varchar(255)" json:"username"` // 公开群组的用户名
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	GroupID      int64     `gorm:"uniqueIndex;not null" json:"group_id"`
+	GroupName    string    `gorm:"type:varchar(255)" json:"group_name"`
+	Username     string    `gorm:"type:varchar(255)" json:"username"`                     // 公开群组的用户名
+	AdminRights  string    `gorm:"type:varchar(20);default:strict" json:"admin_rights"`   // 群管理员权限检查方式，见 AdminRights* 常量
+	AdminEnabled string    `gorm:"type:varchar(20);default:inherit" json:"admin_enabled"` // 本群是否启用群管理员权限，见 AdminEnabled* 常量
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// 群管理员权限检查方式
+const (
+	AdminRightsStrict = "strict" // 按 Telegram 管理员的具体权限（封禁、删除消息、置顶）对应机器人操作
+	AdminRightsAny    = "any"    // 任何管理员都可以执行全部操作
+)
+
+// RequiresAdminRights 是否按 Telegram 管理员的具体权限检查
+func (g *AuthorizedGroup) RequiresAdminRights() bool {
+	return g.AdminRights != AdminRightsAny
+}
+
+// 本群是否启用群管理员权限
+const (
+	AdminEnabledInherit = "inherit"  // 跟随全局设置（system.admin_enabled）
+	AdminEnabledOn      = "enabled"  // 始终启用
+	AdminEnabledOff     = "disabled" // 始终关闭
+)
+
+// AdminsEnabled 本群的群管理员是否拥有权限，跟随全局设置时使用 global
+func (g *AuthorizedGroup) AdminsEnabled(global bool) bool {
+	switch g.AdminEnabled {
+	case AdminEnabledOn:
+		return true
+	case AdminEnabledOff:
+		return false
+	default:
+		return global
+	}
 }
 
 // TableName 指定表名
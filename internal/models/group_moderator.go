@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// GroupModerator 群组版主表（只能管理指定群组，不需要是该群的 Telegram 管理员）
+type GroupModerator struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	GroupID   int64     `gorm:"uniqueIndex:idx_group_moderator;not null" json:"group_id"`
+	GroupName string    `gorm:"type:varchar(255)" json:"group_name"`
+	UserID    int64     `gorm:"uniqueIndex:idx_group_moderator;index;not null" json:"user_id"`
+	Username  string    `gorm:"type:varchar(255)" json:"username"`
+	FullName  string    `gorm:"type:varchar(255)" json:"full_name"`
+	AddedAt   time.Time `gorm:"autoCreateTime" json:"added_at"`
+	AddedBy   int64     `json:"added_by"`
+}
+
+// TableName 指定表名
+func (GroupModerator) TableName() string {
+	return "group_moderators"
+}
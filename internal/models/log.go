@@ -1,24 +1,33 @@
 package models
 
 import (
+	"fmt"
 	"time"
 )
 
 // OperationLog 操作日志表
 type OperationLog struct {
-	ID             int64     `gorm:"primaryKey;autoIncrement" json:"id"`
-	OperationType  string    `gorm:"type:varchar(50);index;not null" json:"operation_type"` // ban/unban/mute/unmute/kick
-	TargetUserID   int64     `gorm:"index;not null" json:"target_user_id"`
-	TargetUsername string    `gorm:"type:varchar(255)" json:"target_username"`
-	GroupID        int64     `gorm:"not null" json:"group_id"`
-	GroupName      string    `gorm:"type:varchar(255)" json:"group_name"`
-	OperatorID     int64     `gorm:"not null" json:"operator_id"`
-	OperatorName   string    `gorm:"type:varchar(255)" json:"operator_name"`
-	Reason         string    `gorm:"type:text" json:"reason"`
-	Duration       *int      `json:"duration"`                 // 秒数
-	Success        int8      `gorm:"default:1" json:"success"` // 1=成功，0=失败
-	ErrorMsg       string    `gorm:"type:text" json:"error_msg"`
-	CreatedAt      time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+	ID             int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	OperationType  string     `gorm:"type:varchar(50);index;not null" json:"operation_type"` // ban/unban/mute/unmute/kick
+	TargetUserID   int64      `gorm:"index;not null" json:"target_user_id"`
+	TargetUsername string     `gorm:"type:varchar(255)" json:"target_username"`
+	GroupID        int64      `gorm:"not null" json:"group_id"`
+	GroupName      string     `gorm:"type:varchar(255)" json:"group_name"`
+	OperatorID     int64      `gorm:"not null" json:"operator_id"`
+	OperatorName   string     `gorm:"type:varchar(255)" json:"operator_name"`
+	Reason         string     `gorm:"type:text" json:"reason"`
+	ReasonCode     string     `gorm:"type:varchar(50);index" json:"reason_code"`         // 预设理由代码
+	Evidence       Evidence   `gorm:"embedded;embeddedPrefix:evidence_" json:"evidence"` // 违规消息证据
+	Duration       *int       `json:"duration"`                                          // 秒数
+	Scope          string     `gorm:"type:varchar(20)" json:"scope"`                     // 作用范围，见 Scope* 常量
+	GroupCount     int        `json:"group_count"`                                       // 执行成功的群组数
+	RecordID       int64      `gorm:"index" json:"record_id"`                            // 关联的拉黑/禁言记录ID
+	Success        int8       `gorm:"default:1" json:"success"`                          // 1=成功，0=失败
+	ErrorMsg       string     `gorm:"type:text" json:"error_msg"`
+	RevertedAt     *time.Time `json:"reverted_at"` // 撤销时间，NULL 表示未撤销
+	RevertedBy     int64      `json:"reverted_by"` // 撤销人
+	RevertedByName string     `gorm:"type:varchar(255)" json:"reverted_by_name"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime;index" json:"created_at"`
 }
 
 // TableName 指定表名
@@ -26,6 +35,44 @@ func (OperationLog) TableName() string {
 	return "operation_logs"
 }
 
+// CaseNumber 案件编号（即操作日志ID）
+func (l *OperationLog) CaseNumber() string {
+	return fmt.Sprintf("#%d", l.ID)
+}
+
+// IsReverted 是否已被撤销
+func (l *OperationLog) IsReverted() bool {
+	return l.RevertedAt != nil
+}
+
+// OperationName 操作类型的显示名称
+func OperationName(opType string) string {
+	switch opType {
+	case OpTypeBan:
+		return "拉黑"
+	case OpTypeUnban:
+		return "解除拉黑"
+	case OpTypeMute:
+		return "禁言"
+	case OpTypeUnmute:
+		return "解除禁言"
+	case OpTypeKick:
+		return "踢出"
+	case OpTypeWarn:
+		return "警告"
+	case OpTypeBanEdit:
+		return "修改拉黑"
+	case OpTypeMuteEdit:
+		return "修改禁言"
+	case OpTypeAuthorAdd:
+		return "添加作者"
+	case OpTypeAuthorRemove:
+		return "移除作者"
+	default:
+		return opType
+	}
+}
+
 // Operation types
 const (
 	OpTypeBan    = "ban"
@@ -33,4 +80,11 @@ const (
 	OpTypeMute   = "mute"
 	OpTypeUnmute = "unmute"
 	OpTypeKick   = "kick"
+	OpTypeWarn   = "warn"
+
+	OpTypeBanEdit  = "ban_edit"  // 修改拉黑时长或理由
+	OpTypeMuteEdit = "mute_edit" // 修改禁言时长或理由
+
+	OpTypeAuthorAdd    = "author_add"    // 通过 /config 添加作者（审计记录）
+	OpTypeAuthorRemove = "author_remove" // 通过 /config 移除作者（审计记录）
 )
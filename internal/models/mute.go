@@ -6,22 +6,31 @@ import (
 
 // MuteList 禁言记录表
 type MuteList struct {
-	ID           int64      `gorm:"primaryKey;autoIncrement" json:"id"`
-	UserID       int64      `gorm:"index;not null" json:"user_id"`
-	Username     string     `gorm:"type:varchar(255)" json:"username"`
-	FullName     string     `gorm:"type:varchar(255)" json:"full_name"`
-	GroupID      int64      `gorm:"not null" json:"group_id"`
-	GroupName    string     `gorm:"type:varchar(255)" json:"group_name"`
-	OperatorID   int64      `gorm:"not null" json:"operator_id"`
-	OperatorName string     `gorm:"type:varchar(255)" json:"operator_name"`
-	Reason       string     `gorm:"type:text" json:"reason"`
-	Duration     *int       `json:"duration"` // 秒数，NULL 表示永久
-	ExpireAt     *time.Time `gorm:"index" json:"expire_at"`
-	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"created_at"`
-	Status       int8       `gorm:"default:1;index" json:"status"` // 1=生效中，0=已解除
-	UnmuteReason string     `gorm:"type:text" json:"unmute_reason"`
-	UnmuteAt     *time.Time `json:"unmute_at"`
-	UnmuteBy     *int64     `json:"unmute_by"`
+	ID              int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID          int64      `gorm:"index;not null" json:"user_id"`
+	Username        string     `gorm:"type:varchar(255)" json:"username"`
+	FullName        string     `gorm:"type:varchar(255)" json:"full_name"`
+	GroupID         int64      `gorm:"not null" json:"group_id"`
+	GroupName       string     `gorm:"type:varchar(255)" json:"group_name"`
+	OperatorID      int64      `gorm:"not null" json:"operator_id"`
+	OperatorName    string     `gorm:"type:varchar(255)" json:"operator_name"`
+	Reason          string     `gorm:"type:text" json:"reason"`
+	ReasonCode      string     `gorm:"type:varchar(50);index" json:"reason_code"`          // 预设理由代码，自定义理由为空
+	Evidence        Evidence   `gorm:"embedded;embeddedPrefix:evidence_" json:"evidence"`  // 违规消息证据
+	Scope           string     `gorm:"type:varchar(20);default:global;index" json:"scope"` // global=全部授权群组，local=仅 GroupID 所在群组
+	Mode            string     `gorm:"type:varchar(20);default:readonly" json:"mode"`      // 禁言模式，见 MuteMode* 常量
+	Duration        *int       `json:"duration"`                                           // 秒数，NULL 表示永久
+	ExpireAt        *time.Time `gorm:"index" json:"expire_at"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+	Status          int8       `gorm:"default:1;index" json:"status"` // 1=生效中，0=已解除，2=已撤销，3=已被替代
+	UnmuteReason    string     `gorm:"type:text" json:"unmute_reason"`
+	UnmuteAt        *time.Time `json:"unmute_at"`
+	UnmuteBy        *int64     `json:"unmute_by"`
+	CaseID          int64      `json:"case_id"`           // 最近一次处罚的案件编号（操作日志ID）
+	NotifyChatID    int64      `json:"notify_chat_id"`    // 频道通知所在聊天，用于修改记录后同步编辑通知
+	NotifyMessageID int        `json:"notify_message_id"` // 频道通知消息ID
+	ReplacesID      int64      `json:"replaces_id"`       // 重新禁言时被本记录替代的上一条记录ID，撤销本记录时恢复
 }
 
 // TableName 指定表名
@@ -29,6 +38,46 @@ func (MuteList) TableName() string {
 	return "mute_list"
 }
 
+// Mute modes 禁言模式
+const (
+	MuteModeReadOnly = "readonly" // 完全只读
+	MuteModeMedia    = "media"    // 禁止媒体
+	MuteModeLinks    = "links"    // 禁止链接和网页预览
+	MuteModeStickers = "stickers" // 禁止贴纸和 GIF
+)
+
+// MuteModes 所有禁言模式（按菜单显示顺序）
+var MuteModes = []string{MuteModeReadOnly, MuteModeMedia, MuteModeLinks, MuteModeStickers}
+
+// MuteModeName 禁言模式的显示名称
+func MuteModeName(mode string) string {
+	switch mode {
+	case MuteModeMedia:
+		return "禁止媒体"
+	case MuteModeLinks:
+		return "禁止链接"
+	case MuteModeStickers:
+		return "禁止贴纸/GIF"
+	default:
+		return "完全禁言"
+	}
+}
+
+// IsValidMuteMode 是否为有效的禁言模式
+func IsValidMuteMode(mode string) bool {
+	for _, m := range MuteModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLocal 是否为仅本群记录
+func (m *MuteList) IsLocal() bool {
+	return m.Scope == ScopeLocal
+}
+
 // IsActive 是否生效中
 func (m *MuteList) IsActive() bool {
 	if m.Status != 1 {
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// ReasonPreset 预设理由表（用于内联键盘快速选择，Code 作为统一的理由代码保存到记录中）
+type ReasonPreset struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Code      string    `gorm:"type:varchar(50);uniqueIndex;not null" json:"code"` // 理由代码，如 spam
+	Label     string    `gorm:"type:varchar(255);not null" json:"label"`           // 显示名称，如 刷屏
+	SortOrder int       `gorm:"default:0" json:"sort_order"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName 指定表名
+func (ReasonPreset) TableName() string {
+	return "reason_presets"
+}
+
+// DefaultReasonPresets 首次启动时写入的默认预设理由
+var DefaultReasonPresets = []ReasonPreset{
+	{Code: "spam", Label: "刷屏", SortOrder: 1},
+	{Code: "ads", Label: "广告", SortOrder: 2},
+	{Code: "abuse", Label: "辱骂", SortOrder: 3},
+	{Code: "scam", Label: "诈骗链接", SortOrder: 4},
+}
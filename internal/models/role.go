@@ -0,0 +1,156 @@
+package models
+
+import (
+	"time"
+)
+
+// 管理角色（权限从低到高）
+const (
+	RoleModerator = "moderator" // 版主：警告、短时禁言、踢出
+	RoleSenior    = "senior"    // 高级管理员：拉黑、解除拉黑
+	RoleOwner     = "owner"     // 所有者：拥有全部权限（作者固定为所有者）
+)
+
+// Roles 可分配的角色（按权限从低到高）
+var Roles = []string{RoleModerator, RoleSenior, RoleOwner}
+
+// RoleName 角色的显示名称
+func RoleName(role string) string {
+	switch role {
+	case RoleModerator:
+		return "版主"
+	case RoleSenior:
+		return "高级管理员"
+	case RoleOwner:
+		return "所有者"
+	default:
+		return role
+	}
+}
+
+// IsValidRole 是否为有效的角色
+func IsValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// 权限（对应一类命令）
+const (
+	PermWarn   = "warn"   // /warn
+	PermKick   = "kick"   // /t
+	PermMute   = "mute"   // /jy
+	PermUnmute = "unmute" // /unjy
+	PermBan    = "ban"    // /lh
+	PermUnban  = "unban"  // /unlh
+	PermPurge  = "purge"  // /purge
+	PermPin    = "pin"    // /pin
+	PermModify = "modify" // /extend、/shorten、/editreason
+	PermCase   = "case"   // /case
+	PermInfo   = "info"   // /info、/history
+)
+
+// Permissions 全部权限（配置页面按此顺序显示）
+var Permissions = []string{PermWarn, PermKick, PermMute, PermUnmute, PermBan, PermUnban, PermPurge, PermPin, PermModify, PermCase, PermInfo}
+
+// IsValidPermission 是否为有效的权限
+func IsValidPermission(perm string) bool {
+	for _, p := range Permissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionName 权限的显示名称
+func PermissionName(perm string) string {
+	switch perm {
+	case PermWarn:
+		return "警告"
+	case PermKick:
+		return "踢出"
+	case PermMute:
+		return "禁言"
+	case PermUnmute:
+		return "解除禁言"
+	case PermBan:
+		return "拉黑"
+	case PermUnban:
+		return "解除拉黑"
+	case PermPurge:
+		return "清理消息"
+	case PermPin:
+		return "置顶消息"
+	case PermModify:
+		return "修改记录"
+	case PermCase:
+		return "案件管理"
+	case PermInfo:
+		return "查看档案"
+	default:
+		return perm
+	}
+}
+
+// RolePermission 角色权限表（每个角色可执行的命令及最长时长）
+type RolePermission struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Role        string    `gorm:"type:varchar(20);uniqueIndex:idx_role_permission;not null" json:"role"`
+	Permission  string    `gorm:"type:varchar(50);uniqueIndex:idx_role_permission;not null" json:"permission"`
+	MaxDuration int       `gorm:"default:0" json:"max_duration"` // 最长时长（秒），0 表示不限制（包括永久）
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// TableName 指定表名
+func (RolePermission) TableName() string {
+	return "role_permissions"
+}
+
+// DefaultRolePermissions 首次启动时写入的默认角色权限（所有者拥有全部权限，不需要配置）
+var DefaultRolePermissions = []RolePermission{
+	{Role: RoleModerator, Permission: PermWarn},
+	{Role: RoleModerator, Permission: PermKick},
+	{Role: RoleModerator, Permission: PermMute, MaxDuration: 86400},
+	{Role: RoleModerator, Permission: PermUnmute},
+	{Role: RoleModerator, Permission: PermPurge},
+	{Role: RoleModerator, Permission: PermPin},
+	{Role: RoleModerator, Permission: PermCase},
+	{Role: RoleModerator, Permission: PermInfo},
+
+	{Role: RoleSenior, Permission: PermWarn},
+	{Role: RoleSenior, Permission: PermKick},
+	{Role: RoleSenior, Permission: PermMute},
+	{Role: RoleSenior, Permission: PermUnmute},
+	{Role: RoleSenior, Permission: PermBan},
+	{Role: RoleSenior, Permission: PermUnban},
+	{Role: RoleSenior, Permission: PermPurge},
+	{Role: RoleSenior, Permission: PermPin},
+	{Role: RoleSenior, Permission: PermModify},
+	{Role: RoleSenior, Permission: PermCase},
+	{Role: RoleSenior, Permission: PermInfo},
+}
+
+// UserRole 用户角色分配表（未分配时全局管理员默认为高级管理员，群管理员默认为版主）
+type UserRole struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     int64     `gorm:"uniqueIndex;not null" json:"user_id"`
+	Role       string    `gorm:"type:varchar(20);not null" json:"role"`
+	FullName   string    `gorm:"type:varchar(255)" json:"full_name"`
+	AssignedBy int64     `json:"assigned_by"`
+	AssignedAt time.Time `gorm:"autoUpdateTime" json:"assigned_at"`
+}
+
+// TableName 指定表名
+func (UserRole) TableName() string {
+	return "user_roles"
+}
+
+// 未分配角色时的默认角色
+const (
+	DefaultGlobalAdminRole = RoleSenior
+	DefaultGroupAdminRole  = RoleModerator
+)
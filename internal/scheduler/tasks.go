@@ -5,6 +5,7 @@ import (
 	"admin-bot/internal/database"
 	"admin-bot/internal/service"
 	"admin-bot/internal/utils"
+	"fmt"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/robfig/cron/v3"
@@ -17,6 +18,7 @@ type Scheduler struct {
 	banService          *service.BanService
 	muteService         *service.MuteService
 	groupService        *service.GroupService
+	adminService        *service.AdminService
 	notificationService *service.NotificationService
 	bot                 *tgbotapi.BotAPI
 	rateLimiter         *utils.RateLimiter
@@ -26,6 +28,7 @@ type Scheduler struct {
 func NewScheduler(banService *service.BanService,
 	muteService *service.MuteService,
 	groupService *service.GroupService,
+	adminService *service.AdminService,
 	notificationService *service.NotificationService,
 	bot *tgbotapi.BotAPI,
 	rateLimitPerGroup int) *Scheduler {
@@ -35,6 +38,7 @@ func NewScheduler(banService *service.BanService,
 		banService:          banService,
 		muteService:         muteService,
 		groupService:        groupService,
+		adminService:        adminService,
 		notificationService: notificationService,
 		bot:                 bot,
 		rateLimiter:         utils.NewRateLimiter(rateLimitPerGroup),
@@ -42,13 +46,19 @@ func NewScheduler(banService *service.BanService,
 }
 
 // Start 启动调度器
-func (s *Scheduler) Start(checkExpireInterval string) error {
+func (s *Scheduler) Start(checkExpireInterval, adminSyncInterval string) error {
 	// 添加检查过期记录的任务
 	_, err := s.cron.AddFunc(checkExpireInterval, s.checkExpiredRecords)
 	if err != nil {
 		return err
 	}
 
+	// 添加同步群管理员名单的任务
+	_, err = s.cron.AddFunc(adminSyncInterval, s.syncAdminRosters)
+	if err != nil {
+		return err
+	}
+
 	// 添加清理限流器的任务（每5分钟）
 	_, err = s.cron.AddFunc("*/5 * * * *", s.cleanupLimiters)
 	if err != nil {
@@ -62,6 +72,9 @@ func (s *Scheduler) Start(checkExpireInterval string) error {
 	}
 
 	s.cron.Start()
+
+	// 启动时先加载一次群管理员名单
+	go s.syncAdminRosters()
 	logrus.WithField("tasks", len(s.cron.Entries())).Debug("Scheduler tasks registered")
 	return nil
 }
@@ -81,6 +94,9 @@ func (s *Scheduler) checkExpiredRecords() {
 
 	// 检查过期的禁言记录
 	s.checkExpiredMutes()
+
+	// 检查到期的全局管理员授权
+	s.checkExpiredAdmins()
 }
 
 // checkExpiredBans 检查过期的拉黑记录
@@ -104,6 +120,8 @@ func (s *Scheduler) checkExpiredBans() {
 		return
 	}
 
+	// 群组版主的多群操作在每个群组各有一条记录，同一案件只发送一次解除通知
+	notifiedCases := make(map[int64]bool)
 	for _, ban := range expiredBans {
 		// 更新数据库状态
 		err := s.banService.AutoUnban(ban.ID)
@@ -112,8 +130,15 @@ func (s *Scheduler) checkExpiredBans() {
 			continue
 		}
 
-		// 在所有授权群组中解除拉黑
+		// 在所有授权群组中解除拉黑（本群记录只在原群组解除）
 		for _, group := range authorizedGroups {
+			if ban.IsLocal() && group.GroupID != ban.GroupID {
+				continue
+			}
+			// 该群仍有其他生效中的拉黑记录时保持封禁
+			if stillBanned, _, err := s.banService.IsUserBanned(ban.UserID, group.GroupID); err == nil && stillBanned {
+				continue
+			}
 			s.rateLimiter.Wait(group.GroupID)
 
 			unbanConfig := tgbotapi.UnbanChatMemberConfig{
@@ -129,9 +154,12 @@ func (s *Scheduler) checkExpiredBans() {
 			}
 		}
 
-		// 发送自动解除通知（系统自动操作，operatorID 使用 0，groupUsername为空因为是定时任务）
-		s.notificationService.SendUnbanNotification(ban.GroupID, ban.GroupName, "",
-			ban.FullName, ban.UserID, "到期自动解除", "系统", 0)
+		if ban.CaseID == 0 || !notifiedCases[ban.CaseID] {
+			notifiedCases[ban.CaseID] = true
+			// 发送自动解除通知（系统自动操作，operatorID 使用 0，groupUsername为空因为是定时任务，案件编号为原处罚案件）
+			s.notificationService.SendUnbanNotification(ban.GroupID, ban.GroupName, "",
+				ban.FullName, ban.UserID, "到期自动解除", "系统", 0, ban.IsLocal(), ban.CaseID)
+		}
 
 		logrus.WithFields(logrus.Fields{
 			"用户ID": ban.UserID,
@@ -161,6 +189,8 @@ func (s *Scheduler) checkExpiredMutes() {
 		return
 	}
 
+	// 群组版主的多群操作在每个群组各有一条记录，同一案件只发送一次解除通知
+	notifiedCases := make(map[int64]bool)
 	for _, mute := range expiredMutes {
 		// 更新数据库状态
 		err := s.muteService.AutoUnmute(mute.ID)
@@ -169,8 +199,15 @@ func (s *Scheduler) checkExpiredMutes() {
 			continue
 		}
 
-		// 在所有授权群组中解除禁言
+		// 在所有授权群组中解除禁言（本群记录只在原群组解除）
 		for _, group := range authorizedGroups {
+			if mute.IsLocal() && group.GroupID != mute.GroupID {
+				continue
+			}
+			// 该群仍有其他生效中的禁言记录时保持禁言
+			if stillMuted, _, err := s.muteService.IsUserMuted(mute.UserID, group.GroupID); err == nil && stillMuted {
+				continue
+			}
 			s.rateLimiter.Wait(group.GroupID)
 
 			restrictConfig := tgbotapi.RestrictChatMemberConfig{
@@ -178,16 +215,8 @@ func (s *Scheduler) checkExpiredMutes() {
 					ChatID: group.GroupID,
 					UserID: mute.UserID,
 				},
-				Permissions: &tgbotapi.ChatPermissions{
-					CanSendMessages:       true,
-					CanSendMediaMessages:  true,
-					CanSendPolls:          true,
-					CanSendOtherMessages:  true,
-					CanAddWebPagePreviews: true,
-					CanChangeInfo:         false,
-					CanInviteUsers:        false,
-					CanPinMessages:        false,
-				},
+				// 恢复为群组当前的默认权限
+				Permissions: service.GetGroupDefaultPermissions(s.bot, group.GroupID),
 			}
 
 			_, err = s.bot.Request(restrictConfig)
@@ -196,9 +225,12 @@ func (s *Scheduler) checkExpiredMutes() {
 			}
 		}
 
-		// 发送自动解除通知（系统自动操作，operatorID 使用 0，groupUsername为空因为是定时任务）
-		s.notificationService.SendUnmuteNotification(mute.GroupID, mute.GroupName, "",
-			mute.FullName, mute.UserID, "到期自动解除", "系统", 0)
+		if mute.CaseID == 0 || !notifiedCases[mute.CaseID] {
+			notifiedCases[mute.CaseID] = true
+			// 发送自动解除通知（系统自动操作，operatorID 使用 0，groupUsername为空因为是定时任务，案件编号为原处罚案件）
+			s.notificationService.SendUnmuteNotification(mute.GroupID, mute.GroupName, "",
+				mute.FullName, mute.UserID, "到期自动解除", "系统", 0, mute.IsLocal(), mute.CaseID)
+		}
 
 		logrus.WithFields(logrus.Fields{
 			"用户ID": mute.UserID,
@@ -207,6 +239,45 @@ func (s *Scheduler) checkExpiredMutes() {
 	}
 }
 
+// checkExpiredAdmins 移除到期的全局管理员授权并通知作者
+func (s *Scheduler) checkExpiredAdmins() {
+	expiredAdmins, err := s.adminService.GetExpiredAdmins()
+	if err != nil {
+		logrus.Errorf("Failed to get expired admins: %v", err)
+		return
+	}
+
+	for _, admin := range expiredAdmins {
+		if err := s.adminService.RemoveGlobalAdmin(admin.UserID); err != nil {
+			logrus.Errorf("Failed to remove expired admin %d: %v", admin.UserID, err)
+			continue
+		}
+
+		name := admin.FullName
+		if name == "" {
+			name = admin.Username
+		}
+		grantor := admin.AddedByName
+		if grantor == "" {
+			grantor = fmt.Sprintf("用户 %d", admin.AddedBy)
+		}
+
+		text := fmt.Sprintf("⏰ *全局管理员授权已到期*\n\n用户：%s（`%d`）\n授权人：%s\n授权时间：%s",
+			utils.FormatUserMention(admin.UserID, name), admin.UserID,
+			utils.EscapeMarkdown(grantor), utils.FormatTimestamp(admin.AddedAt))
+		if admin.Reason != "" {
+			text += "\n理由：" + utils.EscapeMarkdown(admin.Reason)
+		}
+		s.notificationService.SendToAuthors(text)
+
+		logrus.WithFields(logrus.Fields{
+			"用户ID": admin.UserID,
+			"用户名":  name,
+			"授权人":  admin.AddedBy,
+		}).Info("✅ 已移除到期的全局管理员")
+	}
+}
+
 // cleanupLimiters 清理限流器
 func (s *Scheduler) cleanupLimiters() {
 	s.rateLimiter.CleanupOldLimiters()
@@ -243,3 +314,25 @@ func (s *Scheduler) checkDatabaseHealth() {
 		groupService.RefreshAuthCache()
 	}()
 }
+
+// syncAdminRosters 同步所有授权群组的管理员名单
+func (s *Scheduler) syncAdminRosters() {
+	logrus.Debug("🔄 正在同步群管理员名单...")
+
+	changes, err := service.RefreshAllAdminRosters(s.bot, s.groupService)
+	if err != nil {
+		logrus.Errorf("Failed to sync admin rosters: %v", err)
+		return
+	}
+
+	var added, removed int
+	for _, change := range changes {
+		added += len(change.Added)
+		removed += len(change.Removed)
+	}
+	logrus.WithFields(logrus.Fields{
+		"群组数": len(changes),
+		"新增":  added,
+		"移除":  removed,
+	}).Debug("✅ 群管理员名单同步完成")
+}
@@ -3,7 +3,9 @@ package service
 import (
 	"admin-bot/internal/database"
 	"admin-bot/internal/models"
+	"admin-bot/internal/utils"
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -16,11 +18,11 @@ func NewAdminService() *AdminService {
 	return &AdminService{}
 }
 
-// IsGlobalAdmin 检查是否为全局管理员
+// IsGlobalAdmin 检查是否为全局管理员（已到期的授权无效）
 func (s *AdminService) IsGlobalAdmin(userID int64) (bool, error) {
 	var count int64
 	err := database.DB.Model(&models.GlobalAdmin{}).
-		Where("user_id = ?", userID).
+		Where("user_id = ? AND (expire_at IS NULL OR expire_at > ?)", userID, time.Now()).
 		Count(&count).Error
 	if err != nil {
 		return false, err
@@ -28,13 +30,16 @@ func (s *AdminService) IsGlobalAdmin(userID int64) (bool, error) {
 	return count > 0, nil
 }
 
-// AddGlobalAdmin 添加全局管理员
-func (s *AdminService) AddGlobalAdmin(userID int64, username, fullName string, addedBy int64) error {
+// AddGlobalAdmin 添加全局管理员（expireAt 为空表示永久）
+func (s *AdminService) AddGlobalAdmin(userID int64, username, fullName string, addedBy int64, addedByName, reason string, expireAt *time.Time) error {
 	admin := &models.GlobalAdmin{
-		UserID:   userID,
-		Username: username,
-		FullName: fullName,
-		AddedBy:  addedBy,
+		UserID:      userID,
+		Username:    utils.SafeUsername(username),
+		FullName:    utils.SafeFullName(fullName),
+		Reason:      utils.TruncateString(reason, 255),
+		ExpireAt:    expireAt,
+		AddedBy:     addedBy,
+		AddedByName: utils.SafeFullName(addedByName),
 	}
 	return database.DB.Create(admin).Error
 }
@@ -52,6 +57,14 @@ func (s *AdminService) GetGlobalAdmins() ([]models.GlobalAdmin, error) {
 	return admins, err
 }
 
+// GetExpiredAdmins 获取已到期的全局管理员授权
+func (s *AdminService) GetExpiredAdmins() ([]models.GlobalAdmin, error) {
+	var admins []models.GlobalAdmin
+	err := database.DB.Where("expire_at IS NOT NULL AND expire_at <= ?", time.Now()).
+		Find(&admins).Error
+	return admins, err
+}
+
 // GetGlobalAdmin 获取指定全局管理员
 func (s *AdminService) GetGlobalAdmin(userID int64) (*models.GlobalAdmin, error) {
 	var admin models.GlobalAdmin
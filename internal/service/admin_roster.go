@@ -0,0 +1,101 @@
+package service
+
+import (
+	"admin-bot/internal/cache"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// AdminRosterChange 一个群组管理员名单同步的结果
+type AdminRosterChange struct {
+	GroupID   int64
+	GroupName string
+	Total     int                   // 同步后的管理员人数
+	Added     []tgbotapi.ChatMember // 新增的管理员（首次加载名单时为空）
+	Removed   []tgbotapi.ChatMember // 移除的管理员
+	Err       error
+}
+
+// RefreshAdminRoster 通过 getChatAdministrators 重新获取群组管理员名单并写入缓存，返回新增和移除的管理员
+func RefreshAdminRoster(bot *tgbotapi.BotAPI, groupID int64) (*AdminRosterChange, error) {
+	members, err := bot.GetChatAdministrators(tgbotapi.ChatAdministratorsConfig{
+		ChatConfig: tgbotapi.ChatConfig{
+			ChatID: groupID,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	added, removed := cache.GetAdminCache().SetRoster(groupID, members)
+	if len(added) > 0 || len(removed) > 0 {
+		logrus.WithFields(logrus.Fields{
+			"群组ID": groupID,
+			"新增":   len(added),
+			"移除":   len(removed),
+		}).Info("👥 群管理员名单已变化")
+	}
+	return &AdminRosterChange{
+		GroupID: groupID,
+		Total:   len(members),
+		Added:   added,
+		Removed: removed,
+	}, nil
+}
+
+// RefreshAllAdminRosters 同步所有授权群组的管理员名单
+func RefreshAllAdminRosters(bot *tgbotapi.BotAPI, groupService *GroupService) ([]AdminRosterChange, error) {
+	groups, err := groupService.GetAuthorizedGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]AdminRosterChange, 0, len(groups))
+	for _, group := range groups {
+		change, err := RefreshAdminRoster(bot, group.GroupID)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"群组ID": group.GroupID,
+				"错误":   err,
+			}).Warn("⚠️ 获取群管理员名单失败")
+			change = &AdminRosterChange{GroupID: group.GroupID, Err: err}
+		}
+		change.GroupName = group.GroupName
+		changes = append(changes, *change)
+	}
+	return changes, nil
+}
+
+// GetGroupAdmin 获取用户在群组中的管理员信息（不是管理员时返回 nil）
+// 优先使用名单缓存，没有名单或已过期时重新获取名单，获取失败时退回逐个查询 getChatMember
+func GetGroupAdmin(bot *tgbotapi.BotAPI, groupID, userID int64) (*tgbotapi.ChatMember, error) {
+	adminCache := cache.GetAdminCache()
+	if member, cached := adminCache.GetMember(groupID, userID); cached {
+		return member, nil
+	}
+
+	_, err := RefreshAdminRoster(bot, groupID)
+	if err == nil {
+		member, _ := adminCache.GetMember(groupID, userID)
+		return member, nil
+	}
+	logrus.WithFields(logrus.Fields{
+		"群组ID": groupID,
+		"错误":   err,
+	}).Warn("⚠️ 获取群管理员名单失败，改为查询单个成员")
+
+	member, err := bot.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
+			ChatID: groupID,
+			UserID: userID,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if member.Status != "creator" && member.Status != "administrator" {
+		return nil, nil
+	}
+	return &member, nil
+}
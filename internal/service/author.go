@@ -0,0 +1,54 @@
+package service
+
+import (
+	"admin-bot/internal/database"
+	"admin-bot/internal/models"
+	"admin-bot/internal/utils"
+	"fmt"
+)
+
+// AuthorService 作者服务（管理通过 /config 添加的作者）
+type AuthorService struct{}
+
+// NewAuthorService 创建作者服务
+func NewAuthorService() *AuthorService {
+	return &AuthorService{}
+}
+
+// GetAuthors 获取通过 /config 添加的作者
+func (s *AuthorService) GetAuthors() ([]models.Author, error) {
+	var authors []models.Author
+	err := database.DB.Order("added_at ASC").Find(&authors).Error
+	return authors, err
+}
+
+// GetAuthorIDs 获取通过 /config 添加的作者ID
+func (s *AuthorService) GetAuthorIDs() ([]int64, error) {
+	var ids []int64
+	err := database.DB.Model(&models.Author{}).Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// AddAuthor 添加作者
+func (s *AuthorService) AddAuthor(userID int64, username, fullName string, addedBy int64) error {
+	var count int64
+	if err := database.DB.Model(&models.Author{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("该用户已是作者")
+	}
+
+	return database.DB.Create(&models.Author{
+		UserID:   userID,
+		Username: utils.SafeUsername(username),
+		FullName: utils.SafeFullName(fullName),
+		AddedBy:  addedBy,
+	}).Error
+}
+
+// RemoveAuthor 移除作者，返回是否有记录被删除
+func (s *AuthorService) RemoveAuthor(userID int64) (bool, error) {
+	result := database.DB.Where("user_id = ?", userID).Delete(&models.Author{})
+	return result.RowsAffected > 0, result.Error
+}
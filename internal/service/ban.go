@@ -4,9 +4,11 @@ import (
 	"admin-bot/internal/database"
 	"admin-bot/internal/models"
 	"admin-bot/internal/utils"
+	"errors"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 // BanService 拉黑服务
@@ -17,9 +19,9 @@ func NewBanService() *BanService {
 	return &BanService{}
 }
 
-// BanUser 拉黑用户
+// BanUser 拉黑用户（scope 为 models.ScopeGlobal 或 models.ScopeLocal）
 func (s *BanService) BanUser(userID int64, username, fullName string, groupID int64, groupName string,
-	operatorID int64, operatorName string, reason string, duration int) error {
+	operatorID int64, operatorName string, reason, reasonCode string, duration int, scope string, evidence models.Evidence) (*models.Blacklist, error) {
 
 	// 安全处理字符串，防止编码问题
 	username = utils.SafeUsername(username)
@@ -43,12 +45,39 @@ func (s *BanService) BanUser(userID int64, username, fullName string, groupID in
 		OperatorID:   operatorID,
 		OperatorName: operatorName,
 		Reason:       reason,
+		ReasonCode:   reasonCode,
+		Scope:        scope,
+		Evidence:     evidence,
 		Duration:     durationPtr,
 		ExpireAt:     expireAt,
-		Status:       1,
+		Status:       models.RecordStatusActive,
 	}
 
-	err := database.DB.Create(ban).Error
+	// 同一范围内已有生效中的记录时关闭旧记录（标记为已被替代）再保存新记录，避免重复拉黑产生多条生效记录
+	// 旧记录和案件保留在历史中，撤销新记录时恢复旧记录；查询失败时直接保存新记录
+	previous, err := s.findActiveBan(userID, groupID, scope)
+	if err != nil {
+		logrus.Errorf("Failed to find active ban: %v", err)
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if previous != nil {
+			result := tx.Model(&models.Blacklist{}).
+				Where("id = ? AND status = ?", previous.ID, models.RecordStatusActive).
+				Updates(map[string]interface{}{
+					"status":       models.RecordStatusReplaced,
+					"unban_reason": "被新的拉黑记录替代",
+					"unban_at":     time.Now(),
+					"unban_by":     operatorID,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				ban.ReplacesID = previous.ID
+			}
+		}
+		return tx.Create(ban).Error
+	})
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"用户ID": userID,
@@ -56,44 +85,201 @@ func (s *BanService) BanUser(userID int64, username, fullName string, groupID in
 			"错误信息": err.Error(),
 		}).Error("❌ 保存拉黑记录失败")
 	}
-	return err
+	return ban, err
+}
+
+// findActiveBan 查找同一作用范围内生效中的拉黑记录（仅本群记录还需匹配群组），没有时返回 nil
+func (s *BanService) findActiveBan(userID int64, groupID int64, scope string) (*models.Blacklist, error) {
+	var ban models.Blacklist
+	query := database.DB.Where("user_id = ? AND status = 1 AND scope = ?", userID, scope)
+	if scope == models.ScopeLocal {
+		query = query.Where("group_id = ?", groupID)
+	}
+	err := query.Order("created_at DESC").First(&ban).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ban, nil
+}
+
+// GetActiveBan 获取用户在指定群组中生效的拉黑记录（全局记录或该群的本群记录），localOnly 时只查找本群记录，没有时返回 nil
+func (s *BanService) GetActiveBan(userID int64, groupID int64, localOnly bool) (*models.Blacklist, error) {
+	var ban models.Blacklist
+	query := database.DB.Where("user_id = ? AND status = 1", userID).
+		Where("expire_at IS NULL OR expire_at > ?", time.Now())
+	if localOnly {
+		query = query.Where("scope = ? AND group_id = ?", models.ScopeLocal, groupID)
+	} else {
+		query = query.Where("scope = ? OR group_id = ?", models.ScopeGlobal, groupID)
+	}
+	err := query.Order("created_at DESC").First(&ban).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ban, nil
+}
+
+// GetActiveBansByCase 获取同一案件的所有生效中拉黑记录（群组版主的多群操作在每个群组各保存一条本群记录）
+func (s *BanService) GetActiveBansByCase(caseID int64) ([]models.Blacklist, error) {
+	var bans []models.Blacklist
+	err := database.DB.Where("case_id = ? AND status = 1", caseID).
+		Order("id ASC").
+		Find(&bans).Error
+	return bans, err
+}
+
+// GetBan 根据ID获取拉黑记录
+func (s *BanService) GetBan(banID int64) (*models.Blacklist, error) {
+	var ban models.Blacklist
+	if err := database.DB.First(&ban, banID).Error; err != nil {
+		return nil, err
+	}
+	return &ban, nil
+}
+
+// UpdateBanExpiry 修改拉黑到期时间（expireAt 为空表示永久），时长按记录创建时间重新计算
+func (s *BanService) UpdateBanExpiry(ban *models.Blacklist, expireAt *time.Time) error {
+	var durationPtr *int
+	if expireAt != nil {
+		duration := int(expireAt.Sub(ban.CreatedAt).Seconds())
+		durationPtr = &duration
+	}
+
+	err := database.DB.Model(ban).Updates(map[string]interface{}{
+		"expire_at": expireAt,
+		"duration":  durationPtr,
+	}).Error
+	if err != nil {
+		return err
+	}
+	ban.ExpireAt = expireAt
+	ban.Duration = durationPtr
+	return nil
+}
+
+// UpdateBanReason 修改拉黑理由
+func (s *BanService) UpdateBanReason(ban *models.Blacklist, reason, reasonCode string) error {
+	reason = utils.SafeReason(reason)
+	err := database.DB.Model(ban).Updates(map[string]interface{}{
+		"reason":      reason,
+		"reason_code": reasonCode,
+	}).Error
+	if err != nil {
+		return err
+	}
+	ban.Reason = reason
+	ban.ReasonCode = reasonCode
+	return nil
+}
+
+// SetBanCase 记录拉黑对应的案件编号
+func (s *BanService) SetBanCase(banID int64, caseID int64) error {
+	return database.DB.Model(&models.Blacklist{}).
+		Where("id = ?", banID).
+		Update("case_id", caseID).Error
+}
+
+// SetBanNotification 记录拉黑对应的频道通知消息
+func (s *BanService) SetBanNotification(banID int64, chatID int64, messageID int) error {
+	return database.DB.Model(&models.Blacklist{}).
+		Where("id = ?", banID).
+		Updates(map[string]interface{}{
+			"notify_chat_id":    chatID,
+			"notify_message_id": messageID,
+		}).Error
 }
 
 // UnbanUser 解除拉黑
-func (s *BanService) UnbanUser(userID int64, reason string, unbanBy int64) error {
+// 全局解除会解除该用户所有生效中的记录；仅本群解除只解除 groupIDs 中各群的本群记录
+func (s *BanService) UnbanUser(userID int64, reason string, unbanBy int64, groupIDs []int64, scope string) error {
 	now := time.Now()
-	return database.DB.Model(&models.Blacklist{}).
-		Where("user_id = ? AND status = 1", userID).
+	query := database.DB.Model(&models.Blacklist{}).
+		Where("user_id = ? AND status = 1", userID)
+	if scope == models.ScopeLocal {
+		query = query.Where("scope = ? AND group_id IN ?", models.ScopeLocal, groupIDs)
+	}
+	return query.Updates(map[string]interface{}{
+		"status":       0,
+		"unban_reason": reason,
+		"unban_at":     now,
+		"unban_by":     unbanBy,
+	}).Error
+}
+
+// RestoreReplacedBan 撤销替代记录后恢复被替代的上一条拉黑记录，记录已不是已被替代状态时返回错误
+func (s *BanService) RestoreReplacedBan(banID int64) error {
+	result := database.DB.Model(&models.Blacklist{}).
+		Where("id = ? AND status = ?", banID, models.RecordStatusReplaced).
 		Updates(map[string]interface{}{
-			"status":       0,
+			"status":       models.RecordStatusActive,
+			"unban_reason": "",
+			"unban_at":     nil,
+			"unban_by":     nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("上一条记录已不是被替代状态")
+	}
+	return nil
+}
+
+// RevertBan 撤销拉黑记录（标记为已撤销而不是已解除），记录已不在生效中时返回错误
+func (s *BanService) RevertBan(banID int64, reason string, revertBy int64) error {
+	result := database.DB.Model(&models.Blacklist{}).
+		Where("id = ? AND status = ?", banID, models.RecordStatusActive).
+		Updates(map[string]interface{}{
+			"status":       models.RecordStatusReverted,
 			"unban_reason": reason,
-			"unban_at":     now,
-			"unban_by":     unbanBy,
-		}).Error
+			"unban_at":     time.Now(),
+			"unban_by":     revertBy,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("记录已不在生效中")
+	}
+	return nil
 }
 
-// IsUserBanned 检查用户是否被拉黑
-func (s *BanService) IsUserBanned(userID int64) (bool, *models.Blacklist, error) {
+// IsUserBanned 检查用户在指定群组中是否被拉黑（全局记录或该群的本群记录），返回最新的生效中记录
+func (s *BanService) IsUserBanned(userID int64, groupID int64) (bool, *models.Blacklist, error) {
 	var ban models.Blacklist
+	// 在查询中排除已到期但尚未被定时任务解除的记录，避免其遮住更早的生效中记录
 	err := database.DB.Where("user_id = ? AND status = 1", userID).
+		Where("scope = ? OR group_id = ?", models.ScopeGlobal, groupID).
+		Where("expire_at IS NULL OR expire_at > ?", time.Now()).
 		Order("created_at DESC").
 		First(&ban).Error
 
 	if err != nil {
-		if err.Error() == "record not found" {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return false, nil, nil
 		}
 		return false, nil, err
 	}
 
-	// 检查是否过期
-	if ban.IsExpired() {
-		return false, &ban, nil
-	}
-
 	return true, &ban, nil
 }
 
+// HasActiveGlobalBan 检查用户是否仍有生效中的全局拉黑记录
+func (s *BanService) HasActiveGlobalBan(userID int64) (bool, error) {
+	var count int64
+	err := database.DB.Model(&models.Blacklist{}).
+		Where("user_id = ? AND status = 1 AND scope = ?", userID, models.ScopeGlobal).
+		Where("expire_at IS NULL OR expire_at > ?", time.Now()).
+		Count(&count).Error
+	return count > 0, err
+}
+
 // GetActiveBans 获取所有生效中的拉黑记录
 func (s *BanService) GetActiveBans() ([]models.Blacklist, error) {
 	var bans []models.Blacklist
@@ -101,6 +287,22 @@ func (s *BanService) GetActiveBans() ([]models.Blacklist, error) {
 	return bans, err
 }
 
+// ListActiveBans 按筛选条件分页获取生效中的拉黑记录，同时返回总条数
+func (s *BanService) ListActiveBans(filter RecordFilter, offset, limit int) ([]models.Blacklist, int64, error) {
+	var total int64
+	if err := filter.apply(database.DB.Model(&models.Blacklist{})).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var records []models.Blacklist
+	err := filter.apply(database.DB.Model(&models.Blacklist{})).
+		Order(filter.order()).
+		Offset(offset).
+		Limit(limit).
+		Find(&records).Error
+	return records, total, err
+}
+
 // GetExpiredBans 获取已过期但状态仍为1的记录
 func (s *BanService) GetExpiredBans() ([]models.Blacklist, error) {
 	var bans []models.Blacklist
@@ -0,0 +1,399 @@
+package service
+
+import (
+	"admin-bot/internal/database"
+	"admin-bot/internal/models"
+	"admin-bot/internal/utils"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// 黑名单文件格式
+const (
+	BanListFormatCSV  = "csv"
+	BanListFormatJSON = "json"
+)
+
+// 黑名单条目状态
+const (
+	BanListStatusActive = "active" // 生效中
+	BanListStatusLifted = "lifted" // 已解除或已过期
+)
+
+// banListCSVHeader CSV 文件列（导入时按列名匹配，顺序不限）
+var banListCSVHeader = []string{"user_id", "username", "full_name", "reason", "expire_at", "status", "operator_name", "created_at"}
+
+// BanListEntry 黑名单导入导出条目
+type BanListEntry struct {
+	UserID       int64      `json:"user_id"`
+	Username     string     `json:"username,omitempty"`
+	FullName     string     `json:"full_name,omitempty"`
+	Reason       string     `json:"reason,omitempty"`
+	ExpireAt     *time.Time `json:"expire_at,omitempty"` // 为空表示永久
+	Status       string     `json:"status,omitempty"`    // active/lifted，为空视为 active
+	OperatorName string     `json:"operator_name,omitempty"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+}
+
+// BanImportPlan 导入预览（dry-run 结果）
+type BanImportPlan struct {
+	Total      int            // 文件中的条目数
+	ToImport   []BanListEntry // 将要导入的条目
+	Duplicates int            // 文件内重复或已有生效记录
+	Expired    int            // 已过期
+	Lifted     int            // 状态为已解除
+	Invalid    int            // 用户ID无效
+}
+
+// BanImportResult 导入结果
+type BanImportResult struct {
+	Imported int // 成功写入的条目数
+	Failed   int // 写入失败的条目数
+	Kicked   int // 在群组中成功执行的拉黑次数
+}
+
+// BanListService 黑名单导入导出服务
+type BanListService struct {
+	banService *BanService
+	logService *LogService
+}
+
+// NewBanListService 创建黑名单导入导出服务
+func NewBanListService() *BanListService {
+	return &BanListService{
+		banService: NewBanService(),
+		logService: NewLogService(),
+	}
+}
+
+// BanListFormatFromFilename 根据文件扩展名判断格式
+func BanListFormatFromFilename(filename string) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return BanListFormatCSV, nil
+	case ".json":
+		return BanListFormatJSON, nil
+	default:
+		return "", fmt.Errorf("不支持的文件格式：%s（仅支持 .csv 和 .json）", filepath.Ext(filename))
+	}
+}
+
+// Export 导出全局拉黑记录（本群记录只对单个群组有效，不参与交换）
+func (s *BanListService) Export(activeOnly bool) ([]BanListEntry, error) {
+	var bans []models.Blacklist
+	query := database.DB.Where("scope = ?", models.ScopeGlobal).Order("created_at ASC")
+	if activeOnly {
+		query = query.Where("status = 1").
+			Where("expire_at IS NULL OR expire_at > ?", time.Now())
+	}
+	if err := query.Find(&bans).Error; err != nil {
+		return nil, err
+	}
+
+	entries := make([]BanListEntry, 0, len(bans))
+	for _, ban := range bans {
+		status := BanListStatusActive
+		if ban.Status != 1 || ban.IsExpired() {
+			status = BanListStatusLifted
+		}
+		createdAt := ban.CreatedAt
+		entries = append(entries, BanListEntry{
+			UserID:       ban.UserID,
+			Username:     ban.Username,
+			FullName:     ban.FullName,
+			Reason:       ban.Reason,
+			ExpireAt:     ban.ExpireAt,
+			Status:       status,
+			OperatorName: ban.OperatorName,
+			CreatedAt:    &createdAt,
+		})
+	}
+	return entries, nil
+}
+
+// Encode 将条目编码为指定格式
+func (s *BanListService) Encode(entries []BanListEntry, format string) ([]byte, error) {
+	switch format {
+	case BanListFormatJSON:
+		return json.MarshalIndent(entries, "", "  ")
+	case BanListFormatCSV:
+		var buf bytes.Buffer
+		writer := csv.NewWriter(&buf)
+		if err := writer.Write(banListCSVHeader); err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			record := []string{
+				strconv.FormatInt(entry.UserID, 10),
+				entry.Username,
+				entry.FullName,
+				entry.Reason,
+				formatOptionalTime(entry.ExpireAt),
+				entry.Status,
+				entry.OperatorName,
+				formatOptionalTime(entry.CreatedAt),
+			}
+			if err := writer.Write(record); err != nil {
+				return nil, err
+			}
+		}
+		writer.Flush()
+		return buf.Bytes(), writer.Error()
+	default:
+		return nil, fmt.Errorf("不支持的格式：%s", format)
+	}
+}
+
+// Decode 解析指定格式的黑名单文件
+func (s *BanListService) Decode(data []byte, format string) ([]BanListEntry, error) {
+	switch format {
+	case BanListFormatJSON:
+		var entries []BanListEntry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("JSON 解析失败：%w", err)
+		}
+		return entries, nil
+	case BanListFormatCSV:
+		return decodeBanListCSV(data)
+	default:
+		return nil, fmt.Errorf("不支持的格式：%s", format)
+	}
+}
+
+// decodeBanListCSV 解析 CSV 黑名单（第一行为列名，必须包含 user_id）
+func decodeBanListCSV(data []byte) ([]BanListEntry, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("CSV 读取失败：%w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["user_id"]; !ok {
+		return nil, fmt.Errorf("CSV 缺少 user_id 列")
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	entries := make([]BanListEntry, 0)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("CSV 第 %d 行读取失败：%w", line, err)
+		}
+
+		entry := BanListEntry{
+			Username:     strings.TrimPrefix(field(record, "username"), "@"),
+			FullName:     field(record, "full_name"),
+			Reason:       field(record, "reason"),
+			Status:       field(record, "status"),
+			OperatorName: field(record, "operator_name"),
+		}
+		// 无效的用户ID保留为 0，在生成导入计划时计入无效条目
+		entry.UserID, _ = strconv.ParseInt(field(record, "user_id"), 10, 64)
+		if entry.ExpireAt, err = parseOptionalTime(field(record, "expire_at")); err != nil {
+			return nil, fmt.Errorf("CSV 第 %d 行 expire_at 格式错误：%w", line, err)
+		}
+		if entry.CreatedAt, err = parseOptionalTime(field(record, "created_at")); err != nil {
+			return nil, fmt.Errorf("CSV 第 %d 行 created_at 格式错误：%w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Plan 生成导入计划：跳过无效、已解除、已过期、文件内重复以及已有生效全局记录的用户
+func (s *BanListService) Plan(entries []BanListEntry) (*BanImportPlan, error) {
+	plan := &BanImportPlan{
+		Total:    len(entries),
+		ToImport: make([]BanListEntry, 0),
+	}
+
+	now := time.Now()
+	seen := make(map[int64]bool)
+	candidates := make([]BanListEntry, 0, len(entries))
+	for _, entry := range entries {
+		switch {
+		case entry.UserID <= 0:
+			plan.Invalid++
+		case entry.Status != "" && entry.Status != BanListStatusActive:
+			plan.Lifted++
+		case entry.ExpireAt != nil && !entry.ExpireAt.After(now):
+			plan.Expired++
+		case seen[entry.UserID]:
+			plan.Duplicates++
+		default:
+			seen[entry.UserID] = true
+			candidates = append(candidates, entry)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return plan, nil
+	}
+
+	// 查询已有生效全局记录的用户
+	userIDs := make([]int64, 0, len(candidates))
+	for _, entry := range candidates {
+		userIDs = append(userIDs, entry.UserID)
+	}
+	var bannedIDs []int64
+	err := database.DB.Model(&models.Blacklist{}).
+		Where("status = 1 AND scope = ? AND user_id IN ?", models.ScopeGlobal, userIDs).
+		Where("expire_at IS NULL OR expire_at > ?", now).
+		Distinct().Pluck("user_id", &bannedIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	banned := make(map[int64]bool, len(bannedIDs))
+	for _, id := range bannedIDs {
+		banned[id] = true
+	}
+
+	for _, entry := range candidates {
+		if banned[entry.UserID] {
+			plan.Duplicates++
+			continue
+		}
+		plan.ToImport = append(plan.ToImport, entry)
+	}
+	return plan, nil
+}
+
+// Apply 写入导入计划中的条目，在所有授权群组中执行拉黑并为每条记录建立案件；
+// 案件建立后调用 onImported（可为 nil，机器人内导入时用于发送频道通知）
+func (s *BanListService) Apply(bot *tgbotapi.BotAPI, rateLimiter *utils.RateLimiter, entries []BanListEntry,
+	groups []models.AuthorizedGroup, operatorID int64, operatorName string, onImported func(ban *models.Blacklist)) *BanImportResult {
+
+	result := &BanImportResult{}
+	for _, entry := range entries {
+		duration := 0
+		if entry.ExpireAt != nil {
+			duration = int(time.Until(*entry.ExpireAt).Seconds())
+			if duration <= 0 {
+				// 导入过程中到期
+				continue
+			}
+		}
+
+		reason := entry.Reason
+		if reason == "" {
+			reason = "黑名单导入"
+		}
+
+		ban, err := s.banService.BanUser(entry.UserID, entry.Username, entry.FullName,
+			0, "黑名单导入", operatorID, operatorName, reason, "", duration, models.ScopeGlobal, models.Evidence{})
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"用户ID": entry.UserID,
+				"错误":   err.Error(),
+			}).Error("❌ 导入拉黑记录失败")
+			result.Failed++
+			continue
+		}
+		result.Imported++
+
+		for _, group := range groups {
+			rateLimiter.Wait(group.GroupID)
+			kickConfig := tgbotapi.KickChatMemberConfig{
+				ChatMemberConfig: tgbotapi.ChatMemberConfig{
+					ChatID: group.GroupID,
+					UserID: entry.UserID,
+				},
+			}
+			if entry.ExpireAt != nil {
+				kickConfig.UntilDate = entry.ExpireAt.Unix()
+			}
+			if _, err := bot.Request(kickConfig); err != nil {
+				logrus.Debugf("Failed to ban imported user %d in group %d: %v", entry.UserID, group.GroupID, err)
+				continue
+			}
+			result.Kicked++
+		}
+
+		caseLog := &models.OperationLog{
+			OperationType:  models.OpTypeBan,
+			TargetUserID:   entry.UserID,
+			TargetUsername: entry.Username,
+			GroupName:      "黑名单导入",
+			OperatorID:     operatorID,
+			OperatorName:   operatorName,
+			Reason:         reason,
+			Duration:       ban.Duration,
+			Scope:          models.ScopeGlobal,
+			GroupCount:     len(groups),
+			RecordID:       ban.ID,
+		}
+		if err := s.logService.LogModeration(caseLog); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"用户ID": entry.UserID,
+				"错误":   err.Error(),
+			}).Error("❌ 记录导入案件失败")
+		} else {
+			ban.CaseID = caseLog.ID
+			s.banService.SetBanCase(ban.ID, caseLog.ID)
+		}
+
+		if onImported != nil {
+			onImported(ban)
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"导入数量": result.Imported,
+		"失败数量": result.Failed,
+		"群组拉黑": result.Kicked,
+		"群组数量": len(groups),
+	}).Info("📥 黑名单导入完成")
+
+	return result
+}
+
+// Summary 导入预览摘要
+func (p *BanImportPlan) Summary() string {
+	return fmt.Sprintf("文件条目：%d\n将导入：%d\n重复/已拉黑：%d\n已过期：%d\n已解除：%d\n无效：%d",
+		p.Total, len(p.ToImport), p.Duplicates, p.Expired, p.Lifted, p.Invalid)
+}
+
+// formatOptionalTime 格式化可为空的时间（RFC3339）
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// parseOptionalTime 解析可为空的时间（RFC3339）
+func parseOptionalTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"admin-bot/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// fallbackPermissions 无法获取群组默认权限时使用的普通成员权限
+func fallbackPermissions() *tgbotapi.ChatPermissions {
+	return &tgbotapi.ChatPermissions{
+		CanSendMessages:       true,
+		CanSendMediaMessages:  true,
+		CanSendPolls:          true,
+		CanSendOtherMessages:  true,
+		CanAddWebPagePreviews: true,
+		CanChangeInfo:         false,
+		CanInviteUsers:        false,
+		CanPinMessages:        false,
+	}
+}
+
+// GetGroupDefaultPermissions 通过 getChat 获取群组当前的默认成员权限
+func GetGroupDefaultPermissions(bot *tgbotapi.BotAPI, groupID int64) *tgbotapi.ChatPermissions {
+	chat, err := bot.GetChat(tgbotapi.ChatInfoConfig{
+		ChatConfig: tgbotapi.ChatConfig{
+			ChatID: groupID,
+		},
+	})
+	if err != nil || chat.Permissions == nil {
+		logrus.WithFields(logrus.Fields{
+			"群组ID": groupID,
+			"错误":   err,
+		}).Warn("⚠️ 获取群组默认权限失败，使用普通成员权限")
+		return fallbackPermissions()
+	}
+	return chat.Permissions
+}
+
+// GetMutePermissions 根据禁言模式生成受限权限（在群组默认权限基础上收紧，不会放宽群组本身的限制）
+func GetMutePermissions(bot *tgbotapi.BotAPI, groupID int64, mode string) *tgbotapi.ChatPermissions {
+	if mode == models.MuteModeReadOnly || mode == "" {
+		return &tgbotapi.ChatPermissions{}
+	}
+
+	permissions := *GetGroupDefaultPermissions(bot, groupID)
+	switch mode {
+	case models.MuteModeMedia:
+		permissions.CanSendMediaMessages = false
+		permissions.CanSendOtherMessages = false
+		permissions.CanAddWebPagePreviews = false
+	case models.MuteModeLinks:
+		// 链接文本无法通过权限限制，由机器人删除含链接的消息
+		permissions.CanAddWebPagePreviews = false
+	case models.MuteModeStickers:
+		permissions.CanSendOtherMessages = false
+	}
+	return &permissions
+}
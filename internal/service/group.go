@@ -5,6 +5,7 @@ import (
 	"admin-bot/internal/database"
 	"admin-bot/internal/models"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -164,6 +165,7 @@ func (s *GroupService) RemoveAuthorizedGroup(groupID int64) error {
 	// 完全刷新缓存，确保数据库和缓存同步
 	logrus.WithField("群组ID", groupID).Info("✅ 已删除授权群组，正在刷新缓存...")
 	go s.RefreshAuthCache()
+	cache.GetAdminCache().RemoveGroup(groupID)
 
 	return nil
 }
@@ -205,3 +207,23 @@ func (s *GroupService) UpdateGroupInfo(groupID int64, groupName, username string
 		Where("group_id = ?", groupID).
 		Updates(updates).Error
 }
+
+// SetAdminRights 设置群组的群管理员权限检查方式（见 models.AdminRights* 常量）
+func (s *GroupService) SetAdminRights(groupID int64, mode string) error {
+	if mode != models.AdminRightsStrict && mode != models.AdminRightsAny {
+		return fmt.Errorf("无效的权限检查方式：%s", mode)
+	}
+	return database.DB.Model(&models.AuthorizedGroup{}).
+		Where("group_id = ?", groupID).
+		Update("admin_rights", mode).Error
+}
+
+// SetAdminEnabled 设置群组是否启用群管理员权限（见 models.AdminEnabled* 常量）
+func (s *GroupService) SetAdminEnabled(groupID int64, mode string) error {
+	if mode != models.AdminEnabledInherit && mode != models.AdminEnabledOn && mode != models.AdminEnabledOff {
+		return fmt.Errorf("无效的群管理员权限设置：%s", mode)
+	}
+	return database.DB.Model(&models.AuthorizedGroup{}).
+		Where("group_id = ?", groupID).
+		Update("admin_enabled", mode).Error
+}
@@ -3,6 +3,9 @@ package service
 import (
 	"admin-bot/internal/database"
 	"admin-bot/internal/models"
+	"admin-bot/internal/utils"
+	"errors"
+	"time"
 )
 
 // LogService 日志服务
@@ -35,6 +38,58 @@ func (s *LogService) LogOperation(opType string, targetUserID int64, targetUsern
 	return database.DB.Create(log).Error
 }
 
+// LogModeration 记录成功的处罚操作日志（包含预设理由代码、违规消息证据和关联记录），保存后 log.ID 即为案件编号
+func (s *LogService) LogModeration(log *models.OperationLog) error {
+	log.Success = 1
+	return database.DB.Create(log).Error
+}
+
+// GetCase 根据案件编号获取操作日志
+func (s *LogService) GetCase(caseID int64) (*models.OperationLog, error) {
+	var log models.OperationLog
+	if err := database.DB.First(&log, caseID).Error; err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// MarkCaseReverted 将案件标记为已撤销（只标记一次，已撤销时返回错误）
+func (s *LogService) MarkCaseReverted(caseID, revertBy int64, revertByName string) error {
+	result := database.DB.Model(&models.OperationLog{}).
+		Where("id = ? AND reverted_at IS NULL", caseID).
+		Updates(map[string]interface{}{
+			"reverted_at":      time.Now(),
+			"reverted_by":      revertBy,
+			"reverted_by_name": utils.SafeFullName(revertByName),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("案件已被撤销")
+	}
+	return nil
+}
+
+// AddCaseNote 为案件添加备注
+func (s *LogService) AddCaseNote(caseID, authorID int64, authorName, note string) error {
+	return database.DB.Create(&models.CaseNote{
+		CaseID:     caseID,
+		AuthorID:   authorID,
+		AuthorName: utils.SafeFullName(authorName),
+		Note:       utils.SafeReason(note),
+	}).Error
+}
+
+// GetCaseNotes 获取案件备注（按时间顺序）
+func (s *LogService) GetCaseNotes(caseID int64) ([]models.CaseNote, error) {
+	var notes []models.CaseNote
+	err := database.DB.Where("case_id = ?", caseID).
+		Order("created_at ASC").
+		Find(&notes).Error
+	return notes, err
+}
+
 // GetUserLogs 获取用户相关的操作日志
 func (s *LogService) GetUserLogs(userID int64, limit int) ([]models.OperationLog, error) {
 	var logs []models.OperationLog
@@ -49,6 +104,33 @@ func (s *LogService) GetUserLogs(userID int64, limit int) ([]models.OperationLog
 	return logs, err
 }
 
+// GetUserLogsPage 分页获取用户的操作日志（按时间倒序），同时返回总条数
+func (s *LogService) GetUserLogsPage(userID int64, offset, limit int) ([]models.OperationLog, int64, error) {
+	var total int64
+	if err := database.DB.Model(&models.OperationLog{}).
+		Where("target_user_id = ?", userID).
+		Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []models.OperationLog
+	err := database.DB.Where("target_user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&logs).Error
+	return logs, total, err
+}
+
+// CountUserWarnings 统计用户收到的警告次数（不含已撤销的警告）
+func (s *LogService) CountUserWarnings(userID int64) (int64, error) {
+	var count int64
+	err := database.DB.Model(&models.OperationLog{}).
+		Where("target_user_id = ? AND operation_type = ? AND success = 1 AND reverted_at IS NULL", userID, models.OpTypeWarn).
+		Count(&count).Error
+	return count, err
+}
+
 // GetGroupLogs 获取群组相关的操作日志
 func (s *LogService) GetGroupLogs(groupID int64, limit int) ([]models.OperationLog, error) {
 	var logs []models.OperationLog
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"admin-bot/internal/database"
+	"admin-bot/internal/models"
+	"admin-bot/internal/utils"
+	"fmt"
+)
+
+// ModeratorService 群组版主服务
+type ModeratorService struct{}
+
+// NewModeratorService 创建群组版主服务
+func NewModeratorService() *ModeratorService {
+	return &ModeratorService{}
+}
+
+// IsGroupModerator 检查用户是否为指定群组的版主
+func (s *ModeratorService) IsGroupModerator(userID, groupID int64) (bool, error) {
+	var count int64
+	err := database.DB.Model(&models.GroupModerator{}).
+		Where("user_id = ? AND group_id = ?", userID, groupID).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetModeratorGroupIDs 获取用户担任版主的全部群组ID
+func (s *ModeratorService) GetModeratorGroupIDs(userID int64) ([]int64, error) {
+	var groupIDs []int64
+	err := database.DB.Model(&models.GroupModerator{}).
+		Where("user_id = ?", userID).
+		Pluck("group_id", &groupIDs).Error
+	return groupIDs, err
+}
+
+// AddModerator 添加群组版主
+func (s *ModeratorService) AddModerator(groupID int64, groupName string, userID int64, username, fullName string, addedBy int64) error {
+	isModerator, err := s.IsGroupModerator(userID, groupID)
+	if err != nil {
+		return err
+	}
+	if isModerator {
+		return fmt.Errorf("该用户已是本群版主")
+	}
+
+	return database.DB.Create(&models.GroupModerator{
+		GroupID:   groupID,
+		GroupName: utils.SafeGroupName(groupName),
+		UserID:    userID,
+		Username:  utils.SafeUsername(username),
+		FullName:  utils.SafeFullName(fullName),
+		AddedBy:   addedBy,
+	}).Error
+}
+
+// RemoveModerator 移除群组版主，返回是否有记录被删除
+func (s *ModeratorService) RemoveModerator(groupID, userID int64) (bool, error) {
+	result := database.DB.Where("group_id = ? AND user_id = ?", groupID, userID).
+		Delete(&models.GroupModerator{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetGroupModerators 获取指定群组的版主
+func (s *ModeratorService) GetGroupModerators(groupID int64) ([]models.GroupModerator, error) {
+	var moderators []models.GroupModerator
+	err := database.DB.Where("group_id = ?", groupID).Order("added_at ASC").Find(&moderators).Error
+	return moderators, err
+}
+
+// GetAllModerators 获取所有群组版主（按群组排列）
+func (s *ModeratorService) GetAllModerators() ([]models.GroupModerator, error) {
+	var moderators []models.GroupModerator
+	err := database.DB.Order("group_id ASC, added_at ASC").Find(&moderators).Error
+	return moderators, err
+}
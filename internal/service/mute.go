@@ -4,9 +4,11 @@ import (
 	"admin-bot/internal/database"
 	"admin-bot/internal/models"
 	"admin-bot/internal/utils"
+	"errors"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 // MuteService 禁言服务
@@ -17,9 +19,9 @@ func NewMuteService() *MuteService {
 	return &MuteService{}
 }
 
-// MuteUser 禁言用户
+// MuteUser 禁言用户（scope 为 models.ScopeGlobal 或 models.ScopeLocal，mode 为 models.MuteMode*）
 func (s *MuteService) MuteUser(userID int64, username, fullName string, groupID int64, groupName string,
-	operatorID int64, operatorName string, reason string, duration int) error {
+	operatorID int64, operatorName string, reason, reasonCode string, duration int, scope, mode string, evidence models.Evidence) (*models.MuteList, error) {
 
 	// 安全处理字符串，防止编码问题
 	username = utils.SafeUsername(username)
@@ -43,12 +45,40 @@ func (s *MuteService) MuteUser(userID int64, username, fullName string, groupID
 		OperatorID:   operatorID,
 		OperatorName: operatorName,
 		Reason:       reason,
+		ReasonCode:   reasonCode,
+		Scope:        scope,
+		Evidence:     evidence,
+		Mode:         mode,
 		Duration:     durationPtr,
 		ExpireAt:     expireAt,
-		Status:       1,
+		Status:       models.RecordStatusActive,
 	}
 
-	err := database.DB.Create(mute).Error
+	// 同一范围内已有生效中的记录时关闭旧记录（标记为已被替代）再保存新记录，避免重复禁言产生多条生效记录
+	// 旧记录和案件保留在历史中，撤销新记录时恢复旧记录；查询失败时直接保存新记录
+	previous, err := s.findActiveMute(userID, groupID, scope)
+	if err != nil {
+		logrus.Errorf("Failed to find active mute: %v", err)
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if previous != nil {
+			result := tx.Model(&models.MuteList{}).
+				Where("id = ? AND status = ?", previous.ID, models.RecordStatusActive).
+				Updates(map[string]interface{}{
+					"status":        models.RecordStatusReplaced,
+					"unmute_reason": "被新的禁言记录替代",
+					"unmute_at":     time.Now(),
+					"unmute_by":     operatorID,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				mute.ReplacesID = previous.ID
+			}
+		}
+		return tx.Create(mute).Error
+	})
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"用户ID": userID,
@@ -56,44 +86,201 @@ func (s *MuteService) MuteUser(userID int64, username, fullName string, groupID
 			"错误信息": err.Error(),
 		}).Error("❌ 保存禁言记录失败")
 	}
-	return err
+	return mute, err
+}
+
+// findActiveMute 查找同一作用范围内生效中的禁言记录（仅本群记录还需匹配群组），没有时返回 nil
+func (s *MuteService) findActiveMute(userID int64, groupID int64, scope string) (*models.MuteList, error) {
+	var mute models.MuteList
+	query := database.DB.Where("user_id = ? AND status = 1 AND scope = ?", userID, scope)
+	if scope == models.ScopeLocal {
+		query = query.Where("group_id = ?", groupID)
+	}
+	err := query.Order("created_at DESC").First(&mute).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &mute, nil
+}
+
+// GetActiveMute 获取用户在指定群组中生效的禁言记录（全局记录或该群的本群记录），localOnly 时只查找本群记录，没有时返回 nil
+func (s *MuteService) GetActiveMute(userID int64, groupID int64, localOnly bool) (*models.MuteList, error) {
+	var mute models.MuteList
+	query := database.DB.Where("user_id = ? AND status = 1", userID).
+		Where("expire_at IS NULL OR expire_at > ?", time.Now())
+	if localOnly {
+		query = query.Where("scope = ? AND group_id = ?", models.ScopeLocal, groupID)
+	} else {
+		query = query.Where("scope = ? OR group_id = ?", models.ScopeGlobal, groupID)
+	}
+	err := query.Order("created_at DESC").First(&mute).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &mute, nil
+}
+
+// GetActiveMutesByCase 获取同一案件的所有生效中禁言记录（群组版主的多群操作在每个群组各保存一条本群记录）
+func (s *MuteService) GetActiveMutesByCase(caseID int64) ([]models.MuteList, error) {
+	var mutes []models.MuteList
+	err := database.DB.Where("case_id = ? AND status = 1", caseID).
+		Order("id ASC").
+		Find(&mutes).Error
+	return mutes, err
+}
+
+// GetMute 根据ID获取禁言记录
+func (s *MuteService) GetMute(muteID int64) (*models.MuteList, error) {
+	var mute models.MuteList
+	if err := database.DB.First(&mute, muteID).Error; err != nil {
+		return nil, err
+	}
+	return &mute, nil
+}
+
+// UpdateMuteExpiry 修改禁言到期时间（expireAt 为空表示永久），时长按记录创建时间重新计算
+func (s *MuteService) UpdateMuteExpiry(mute *models.MuteList, expireAt *time.Time) error {
+	var durationPtr *int
+	if expireAt != nil {
+		duration := int(expireAt.Sub(mute.CreatedAt).Seconds())
+		durationPtr = &duration
+	}
+
+	err := database.DB.Model(mute).Updates(map[string]interface{}{
+		"expire_at": expireAt,
+		"duration":  durationPtr,
+	}).Error
+	if err != nil {
+		return err
+	}
+	mute.ExpireAt = expireAt
+	mute.Duration = durationPtr
+	return nil
+}
+
+// UpdateMuteReason 修改禁言理由
+func (s *MuteService) UpdateMuteReason(mute *models.MuteList, reason, reasonCode string) error {
+	reason = utils.SafeReason(reason)
+	err := database.DB.Model(mute).Updates(map[string]interface{}{
+		"reason":      reason,
+		"reason_code": reasonCode,
+	}).Error
+	if err != nil {
+		return err
+	}
+	mute.Reason = reason
+	mute.ReasonCode = reasonCode
+	return nil
+}
+
+// SetMuteCase 记录禁言对应的案件编号
+func (s *MuteService) SetMuteCase(muteID int64, caseID int64) error {
+	return database.DB.Model(&models.MuteList{}).
+		Where("id = ?", muteID).
+		Update("case_id", caseID).Error
+}
+
+// SetMuteNotification 记录禁言对应的频道通知消息
+func (s *MuteService) SetMuteNotification(muteID int64, chatID int64, messageID int) error {
+	return database.DB.Model(&models.MuteList{}).
+		Where("id = ?", muteID).
+		Updates(map[string]interface{}{
+			"notify_chat_id":    chatID,
+			"notify_message_id": messageID,
+		}).Error
 }
 
 // UnmuteUser 解除禁言
-func (s *MuteService) UnmuteUser(userID int64, reason string, unmuteBy int64) error {
+// 全局解除会解除该用户所有生效中的记录；仅本群解除只解除 groupIDs 中各群的本群记录
+func (s *MuteService) UnmuteUser(userID int64, reason string, unmuteBy int64, groupIDs []int64, scope string) error {
 	now := time.Now()
-	return database.DB.Model(&models.MuteList{}).
-		Where("user_id = ? AND status = 1", userID).
+	query := database.DB.Model(&models.MuteList{}).
+		Where("user_id = ? AND status = 1", userID)
+	if scope == models.ScopeLocal {
+		query = query.Where("scope = ? AND group_id IN ?", models.ScopeLocal, groupIDs)
+	}
+	return query.Updates(map[string]interface{}{
+		"status":        0,
+		"unmute_reason": reason,
+		"unmute_at":     now,
+		"unmute_by":     unmuteBy,
+	}).Error
+}
+
+// RestoreReplacedMute 撤销替代记录后恢复被替代的上一条禁言记录，记录已不是已被替代状态时返回错误
+func (s *MuteService) RestoreReplacedMute(muteID int64) error {
+	result := database.DB.Model(&models.MuteList{}).
+		Where("id = ? AND status = ?", muteID, models.RecordStatusReplaced).
 		Updates(map[string]interface{}{
-			"status":        0,
+			"status":        models.RecordStatusActive,
+			"unmute_reason": "",
+			"unmute_at":     nil,
+			"unmute_by":     nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("上一条记录已不是被替代状态")
+	}
+	return nil
+}
+
+// RevertMute 撤销禁言记录（标记为已撤销而不是已解除），记录已不在生效中时返回错误
+func (s *MuteService) RevertMute(muteID int64, reason string, revertBy int64) error {
+	result := database.DB.Model(&models.MuteList{}).
+		Where("id = ? AND status = ?", muteID, models.RecordStatusActive).
+		Updates(map[string]interface{}{
+			"status":        models.RecordStatusReverted,
 			"unmute_reason": reason,
-			"unmute_at":     now,
-			"unmute_by":     unmuteBy,
-		}).Error
+			"unmute_at":     time.Now(),
+			"unmute_by":     revertBy,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("记录已不在生效中")
+	}
+	return nil
 }
 
-// IsUserMuted 检查用户是否被禁言
-func (s *MuteService) IsUserMuted(userID int64) (bool, *models.MuteList, error) {
+// IsUserMuted 检查用户在指定群组中是否被禁言（全局记录或该群的本群记录），返回最新的生效中记录
+func (s *MuteService) IsUserMuted(userID int64, groupID int64) (bool, *models.MuteList, error) {
 	var mute models.MuteList
+	// 在查询中排除已到期但尚未被定时任务解除的记录，避免其遮住更早的生效中记录
 	err := database.DB.Where("user_id = ? AND status = 1", userID).
+		Where("scope = ? OR group_id = ?", models.ScopeGlobal, groupID).
+		Where("expire_at IS NULL OR expire_at > ?", time.Now()).
 		Order("created_at DESC").
 		First(&mute).Error
 
 	if err != nil {
-		if err.Error() == "record not found" {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return false, nil, nil
 		}
 		return false, nil, err
 	}
 
-	// 检查是否过期
-	if mute.IsExpired() {
-		return false, &mute, nil
-	}
-
 	return true, &mute, nil
 }
 
+// HasActiveGlobalMute 检查用户是否仍有生效中的全局禁言记录
+func (s *MuteService) HasActiveGlobalMute(userID int64) (bool, error) {
+	var count int64
+	err := database.DB.Model(&models.MuteList{}).
+		Where("user_id = ? AND status = 1 AND scope = ?", userID, models.ScopeGlobal).
+		Where("expire_at IS NULL OR expire_at > ?", time.Now()).
+		Count(&count).Error
+	return count > 0, err
+}
+
 // GetActiveMutes 获取所有生效中的禁言记录
 func (s *MuteService) GetActiveMutes() ([]models.MuteList, error) {
 	var mutes []models.MuteList
@@ -101,6 +288,22 @@ func (s *MuteService) GetActiveMutes() ([]models.MuteList, error) {
 	return mutes, err
 }
 
+// ListActiveMutes 按筛选条件分页获取生效中的禁言记录，同时返回总条数
+func (s *MuteService) ListActiveMutes(filter RecordFilter, offset, limit int) ([]models.MuteList, int64, error) {
+	var total int64
+	if err := filter.apply(database.DB.Model(&models.MuteList{})).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var records []models.MuteList
+	err := filter.apply(database.DB.Model(&models.MuteList{})).
+		Order(filter.order()).
+		Offset(offset).
+		Limit(limit).
+		Find(&records).Error
+	return records, total, err
+}
+
 // GetExpiredMutes 获取已过期但状态仍为1的记录
 func (s *MuteService) GetExpiredMutes() ([]models.MuteList, error) {
 	var mutes []models.MuteList
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"admin-bot/internal/models"
 	"admin-bot/internal/utils"
 	"fmt"
 	"time"
@@ -13,11 +14,12 @@ import (
 type NotificationService struct {
 	bot                   *tgbotapi.BotAPI
 	notificationChannelID int64
-	authorIDs             []int64
+	evidenceChatID        int64          // 证据存档聊天ID，0 表示使用通知频道
+	authorIDs             func() []int64 // 获取当前所有作者ID（作者可以通过 /config 增删）
 }
 
 // NewNotificationService 创建通知服务
-func NewNotificationService(bot *tgbotapi.BotAPI, channelID int64, authorIDs []int64) *NotificationService {
+func NewNotificationService(bot *tgbotapi.BotAPI, channelID int64, authorIDs func() []int64) *NotificationService {
 	return &NotificationService{
 		bot:                   bot,
 		notificationChannelID: channelID,
@@ -25,63 +27,182 @@ func NewNotificationService(bot *tgbotapi.BotAPI, channelID int64, authorIDs []i
 	}
 }
 
-// SendBanNotification 发送拉黑通知
-func (s *NotificationService) SendBanNotification(groupID int64, groupName, groupUsername, userName string,
-	userID int64, duration int, reason, operatorName string, operatorID int64) error {
+// 通知按钮对应的记录类型
+const (
+	RecordTypeBan  = "ban"
+	RecordTypeMute = "mute"
+)
 
-	durationStr := utils.FormatDuration(duration)
-	timestamp := utils.FormatTimestamp(time.Now())
-	message := utils.FormatBanNotification(groupName, groupUsername, userName, userID, durationStr, reason, operatorName, operatorID, timestamp)
+// recordExtendOptions 通知上的延长按钮（秒，0 表示改为永久）
+var recordExtendOptions = []int{86400, 7 * 86400, 0}
+
+// SendBanNotification 发送拉黑通知，undoable 为 true 时附带撤销按钮，onSent 在通知发送成功后回调（用于记录通知消息以便后续编辑）
+func (s *NotificationService) SendBanNotification(ban *models.Blacklist, groupUsername string, undoable bool, onSent func(chatID int64, messageID int)) error {
+	message := banNotificationText(ban, groupUsername)
+	var undoCaseID int64
+	if undoable {
+		undoCaseID = ban.CaseID
+	}
+	keyboard := recordKeyboard(RecordTypeBan, ban.ID, ban.IsActive(), ban.ExpireAt == nil, undoCaseID)
 
 	// 异步发送通知以提升响应速度
 	go func() {
-		s.sendNotificationWithCheck(message, "拉黑")
+		messageID := s.sendNotificationWithCheck(message, "拉黑", keyboard)
+		if messageID != 0 && onSent != nil {
+			onSent(s.notificationChannelID, messageID)
+		}
 	}()
 
 	return nil
 }
 
+// UpdateBanNotification 修改或解除拉黑记录后同步编辑频道通知（note 为修改说明），按钮按记录当前状态更新
+func (s *NotificationService) UpdateBanNotification(ban *models.Blacklist, groupUsername, note string) error {
+	if ban.NotifyMessageID == 0 {
+		return nil
+	}
+	text := banNotificationText(ban, groupUsername) + "\n\n✏️ " + utils.EscapeMarkdown(note)
+	return s.EditMessage(ban.NotifyChatID, ban.NotifyMessageID, text, recordKeyboard(RecordTypeBan, ban.ID, ban.IsActive(), ban.ExpireAt == nil, 0))
+}
+
+// banNotificationText 根据拉黑记录生成通知内容
+func banNotificationText(ban *models.Blacklist, groupUsername string) string {
+	return utils.FormatBanNotification(ban.GroupName, groupUsername, recordUserName(ban.FullName, ban.Username),
+		ban.UserID, utils.FormatDuration(intValue(ban.Duration)), ban.Reason, ban.OperatorName, ban.OperatorID,
+		recordTimestamp(ban.CreatedAt), ban.IsLocal(), ban.Evidence.ArchiveLink(), ban.CaseID)
+}
+
 // SendUnbanNotification 发送解除拉黑通知
 func (s *NotificationService) SendUnbanNotification(groupID int64, groupName, groupUsername, userName string,
-	userID int64, reason, operatorName string, operatorID int64) error {
+	userID int64, reason, operatorName string, operatorID int64, local bool, caseID int64) error {
 
 	timestamp := utils.FormatTimestamp(time.Now())
-	message := utils.FormatUnbanNotification(groupName, groupUsername, userName, userID, reason, operatorName, operatorID, timestamp)
+	message := utils.FormatUnbanNotification(groupName, groupUsername, userName, userID, reason, operatorName, operatorID, timestamp, local, caseID)
 
 	// 异步发送通知以提升响应速度
 	go func() {
-		s.sendNotificationWithCheck(message, "解除拉黑")
+		s.sendNotificationWithCheck(message, "解除拉黑", nil)
 	}()
 
 	return nil
 }
 
-// SendMuteNotification 发送禁言通知
-func (s *NotificationService) SendMuteNotification(groupID int64, groupName, groupUsername, userName string,
-	userID int64, duration int, reason, operatorName string, operatorID int64) error {
-
-	durationStr := utils.FormatDuration(duration)
-	timestamp := utils.FormatTimestamp(time.Now())
-	message := utils.FormatMuteNotification(groupName, groupUsername, userName, userID, durationStr, reason, operatorName, operatorID, timestamp)
+// SendMuteNotification 发送禁言通知，undoable 为 true 时附带撤销按钮，onSent 在通知发送成功后回调（用于记录通知消息以便后续编辑）
+func (s *NotificationService) SendMuteNotification(mute *models.MuteList, groupUsername string, undoable bool, onSent func(chatID int64, messageID int)) error {
+	message := muteNotificationText(mute, groupUsername)
+	var undoCaseID int64
+	if undoable {
+		undoCaseID = mute.CaseID
+	}
+	keyboard := recordKeyboard(RecordTypeMute, mute.ID, mute.IsActive(), mute.ExpireAt == nil, undoCaseID)
 
 	// 异步发送通知以提升响应速度
 	go func() {
-		s.sendNotificationWithCheck(message, "禁言")
+		messageID := s.sendNotificationWithCheck(message, "禁言", keyboard)
+		if messageID != 0 && onSent != nil {
+			onSent(s.notificationChannelID, messageID)
+		}
 	}()
 
 	return nil
 }
 
+// UpdateMuteNotification 修改或解除禁言记录后同步编辑频道通知（note 为修改说明），按钮按记录当前状态更新
+func (s *NotificationService) UpdateMuteNotification(mute *models.MuteList, groupUsername, note string) error {
+	if mute.NotifyMessageID == 0 {
+		return nil
+	}
+	text := muteNotificationText(mute, groupUsername) + "\n\n✏️ " + utils.EscapeMarkdown(note)
+	return s.EditMessage(mute.NotifyChatID, mute.NotifyMessageID, text, recordKeyboard(RecordTypeMute, mute.ID, mute.IsActive(), mute.ExpireAt == nil, 0))
+}
+
+// muteNotificationText 根据禁言记录生成通知内容
+func muteNotificationText(mute *models.MuteList, groupUsername string) string {
+	return utils.FormatMuteNotification(mute.GroupName, groupUsername, recordUserName(mute.FullName, mute.Username),
+		mute.UserID, utils.FormatDuration(intValue(mute.Duration)), models.MuteModeName(mute.Mode), mute.Reason,
+		mute.OperatorName, mute.OperatorID, recordTimestamp(mute.CreatedAt), mute.IsLocal(), mute.Evidence.ArchiveLink(), mute.CaseID)
+}
+
+// recordKeyboard 生成通知上的操作按钮（record:<类型>:<记录ID>:<操作>），记录未保存时不显示
+// 生效中的记录可以延长（已是永久时不显示）和解除，已失效的记录只保留历史按钮
+// undoCaseID 不为 0 时附带撤销该案件的按钮（undo:case:<案件编号>），编辑通知时不再显示
+func recordKeyboard(recordType string, recordID int64, active, permanent bool, undoCaseID int64) *tgbotapi.InlineKeyboardMarkup {
+	if recordID == 0 {
+		return nil
+	}
+
+	prefix := fmt.Sprintf("record:%s:%d:", recordType, recordID)
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if active && !permanent {
+		row := make([]tgbotapi.InlineKeyboardButton, 0, len(recordExtendOptions))
+		for _, seconds := range recordExtendOptions {
+			label := "⏱ +" + utils.FormatDuration(seconds)
+			if seconds == 0 {
+				label = "♾ 改为永久"
+			}
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("%sextend:%d", prefix, seconds)))
+		}
+		rows = append(rows, row)
+	}
+
+	historyButton := tgbotapi.NewInlineKeyboardButtonData("📜 历史记录", prefix+"history")
+	if active {
+		liftLabel := "🔓 解除拉黑"
+		if recordType == RecordTypeMute {
+			liftLabel = "🔈 解除禁言"
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(liftLabel, prefix+"lift"),
+			historyButton,
+		))
+	} else {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(historyButton))
+	}
+
+	if active && undoCaseID != 0 {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("↩️ 撤销", fmt.Sprintf("undo:case:%d", undoCaseID)),
+		))
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
+}
+
+// recordUserName 通知中显示的用户名称
+func recordUserName(fullName, username string) string {
+	if fullName != "" {
+		return fullName
+	}
+	return username
+}
+
+// recordTimestamp 通知中显示的操作时间（记录未保存时使用当前时间）
+func recordTimestamp(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return utils.FormatTimestamp(t)
+}
+
+// intValue 取出可空整数的值（空表示 0）
+func intValue(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
 // SendUnmuteNotification 发送解除禁言通知
 func (s *NotificationService) SendUnmuteNotification(groupID int64, groupName, groupUsername, userName string,
-	userID int64, reason, operatorName string, operatorID int64) error {
+	userID int64, reason, operatorName string, operatorID int64, local bool, caseID int64) error {
 
 	timestamp := utils.FormatTimestamp(time.Now())
-	message := utils.FormatUnmuteNotification(groupName, groupUsername, userName, userID, reason, operatorName, operatorID, timestamp)
+	message := utils.FormatUnmuteNotification(groupName, groupUsername, userName, userID, reason, operatorName, operatorID, timestamp, local, caseID)
 
 	// 异步发送通知以提升响应速度
 	go func() {
-		s.sendNotificationWithCheck(message, "解除禁言")
+		s.sendNotificationWithCheck(message, "解除禁言", nil)
 	}()
 
 	return nil
@@ -89,14 +210,29 @@ func (s *NotificationService) SendUnmuteNotification(groupID int64, groupName, g
 
 // SendKickNotification 发送踢出通知
 func (s *NotificationService) SendKickNotification(groupID int64, groupName, groupUsername, userName string,
-	userID int64, operatorName string, operatorID int64) error {
+	userID int64, operatorName string, operatorID int64, local bool, evidenceLink string, caseID int64) error {
 
 	timestamp := utils.FormatTimestamp(time.Now())
-	message := utils.FormatKickNotification(groupName, groupUsername, userName, userID, operatorName, operatorID, timestamp)
+	message := utils.FormatKickNotification(groupName, groupUsername, userName, userID, operatorName, operatorID, timestamp, local, evidenceLink, caseID)
 
 	// 异步发送通知以提升响应速度
 	go func() {
-		s.sendNotificationWithCheck(message, "踢出")
+		s.sendNotificationWithCheck(message, "踢出", nil)
+	}()
+
+	return nil
+}
+
+// SendWarnNotification 发送警告通知
+func (s *NotificationService) SendWarnNotification(groupID int64, groupName, groupUsername, userName string,
+	userID int64, reason string, warnCount int64, operatorName string, operatorID int64, evidenceLink string, caseID int64) error {
+
+	timestamp := utils.FormatTimestamp(time.Now())
+	message := utils.FormatWarnNotification(groupName, groupUsername, userName, userID, reason, warnCount, operatorName, operatorID, timestamp, evidenceLink, caseID)
+
+	// 异步发送通知以提升响应速度
+	go func() {
+		s.sendNotificationWithCheck(message, "警告", nil)
 	}()
 
 	return nil
@@ -110,7 +246,7 @@ func (s *NotificationService) SendErrorNotification(groupName, operationType, us
 	message := utils.FormatErrorNotification(groupName, operationType, userName, userID, errorMsg, operatorName, timestamp)
 
 	// 发送给所有作者
-	for _, authorID := range s.authorIDs {
+	for _, authorID := range s.authorIDs() {
 		if err := s.sendMessage(authorID, message); err != nil {
 			logrus.Errorf("Failed to send error notification to author %d: %v", authorID, err)
 		}
@@ -118,6 +254,34 @@ func (s *NotificationService) SendErrorNotification(groupName, operationType, us
 	return nil
 }
 
+// SendToAuthors 向所有作者发送消息
+func (s *NotificationService) SendToAuthors(text string) {
+	for _, authorID := range s.authorIDs() {
+		if err := s.sendMessage(authorID, text); err != nil {
+			logrus.Errorf("Failed to send message to author %d: %v", authorID, err)
+		}
+	}
+}
+
+// SendApprovalRequest 将待审批的操作发到通知频道（未配置通知频道时私聊发给所有作者），返回已发送的消息（聊天ID -> 消息ID）
+func (s *NotificationService) SendApprovalRequest(text string, keyboard tgbotapi.InlineKeyboardMarkup) map[int64]int {
+	chatIDs := []int64{s.notificationChannelID}
+	if s.notificationChannelID == 0 {
+		chatIDs = s.authorIDs()
+	}
+
+	sent := make(map[int64]int, len(chatIDs))
+	for _, chatID := range chatIDs {
+		messageID, err := s.sendMessageWithKeyboard(chatID, text, &keyboard)
+		if err != nil {
+			logrus.Errorf("Failed to send approval request to %d: %v", chatID, err)
+			continue
+		}
+		sent[chatID] = messageID
+	}
+	return sent
+}
+
 // SendTextMessage 发送文本消息
 func (s *NotificationService) SendTextMessage(chatID int64, text string) error {
 	return s.sendMessage(chatID, text)
@@ -125,22 +289,31 @@ func (s *NotificationService) SendTextMessage(chatID int64, text string) error {
 
 // sendMessage 发送消息的内部方法
 func (s *NotificationService) sendMessage(chatID int64, text string) error {
+	_, err := s.sendMessageWithKeyboard(chatID, text, nil)
+	return err
+}
+
+// sendMessageWithKeyboard 发送消息（可带按钮）并返回消息ID
+func (s *NotificationService) sendMessageWithKeyboard(chatID int64, text string, keyboard *tgbotapi.InlineKeyboardMarkup) (int, error) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = "Markdown"
 	msg.DisableWebPagePreview = true // 禁用链接预览，避免占用空间
+	if keyboard != nil {
+		msg.ReplyMarkup = keyboard
+	}
 
-	_, err := s.bot.Send(msg)
+	sent, err := s.bot.Send(msg)
 	if err != nil {
 		// 如果 Markdown 解析失败，尝试不使用 Markdown 重新发送
 		logrus.Warnf("Failed to send message with Markdown: %v, retrying without parse mode", err)
 		msg.ParseMode = ""
-		_, err = s.bot.Send(msg)
+		sent, err = s.bot.Send(msg)
 		if err != nil {
-			return fmt.Errorf("failed to send message: %w", err)
+			return 0, fmt.Errorf("failed to send message: %w", err)
 		}
 	}
 
-	return nil
+	return sent.MessageID, nil
 }
 
 // SendMessageWithButtons 发送带按钮的消息
@@ -197,31 +370,116 @@ func (s *NotificationService) GetNotificationChannelID() int64 {
 	return s.notificationChannelID
 }
 
-// sendNotificationWithCheck 发送通知并检查频道是否已配置
-func (s *NotificationService) sendNotificationWithCheck(message, operationType string) {
+// SetEvidenceChatID 设置证据存档聊天ID（0 表示使用通知频道）
+func (s *NotificationService) SetEvidenceChatID(chatID int64) {
+	s.evidenceChatID = chatID
+}
+
+// getEvidenceChatID 获取证据存档聊天ID，未单独配置时使用通知频道
+func (s *NotificationService) getEvidenceChatID() int64 {
+	if s.evidenceChatID != 0 {
+		return s.evidenceChatID
+	}
+	return s.notificationChannelID
+}
+
+// CaptureEvidence 将违规消息转发到证据存档聊天并返回证据信息（必须在删除消息之前调用）
+func (s *NotificationService) CaptureEvidence(message *tgbotapi.Message) models.Evidence {
+	text := message.Text
+	if text == "" {
+		text = message.Caption
+	}
+	evidence := models.Evidence{
+		Text:      utils.TruncateString(text, 1000),
+		MediaType: messageMediaType(message),
+	}
+	// 私聊中的消息没有可访问的链接，只保留存档
+	if !message.Chat.IsPrivate() {
+		evidence.Link = utils.FormatMessageLink(message.Chat.ID, message.Chat.UserName, message.MessageID)
+	}
+
+	chatID := s.getEvidenceChatID()
+	if chatID == 0 {
+		// 没有可用的存档聊天，只保留文本和链接
+		return evidence
+	}
+
+	// 优先转发（保留原发送者），群组开启内容保护时转发会失败，改为复制
+	sent, err := s.bot.Send(tgbotapi.NewForward(chatID, message.Chat.ID, message.MessageID))
+	if err != nil {
+		logrus.Warnf("Failed to forward evidence message, trying copy: %v", err)
+		var copied tgbotapi.MessageID
+		copied, err = s.bot.CopyMessage(tgbotapi.NewCopyMessage(chatID, message.Chat.ID, message.MessageID))
+		sent.MessageID = copied.MessageID
+	}
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"群组ID": message.Chat.ID,
+			"消息ID": message.MessageID,
+			"错误":   err.Error(),
+		}).Warn("⚠️ 证据消息存档失败，仅保存文本")
+		return evidence
+	}
+
+	evidence.ChatID = chatID
+	evidence.MessageID = sent.MessageID
+	return evidence
+}
+
+// messageMediaType 获取消息的媒体类型
+func messageMediaType(message *tgbotapi.Message) string {
+	switch {
+	case message.Photo != nil:
+		return "photo"
+	case message.Video != nil:
+		return "video"
+	case message.Animation != nil:
+		return "animation"
+	case message.Sticker != nil:
+		return "sticker"
+	case message.Voice != nil:
+		return "voice"
+	case message.VideoNote != nil:
+		return "video_note"
+	case message.Audio != nil:
+		return "audio"
+	case message.Document != nil:
+		return "document"
+	case message.Text != "":
+		return "text"
+	default:
+		return "other"
+	}
+}
+
+// sendNotificationWithCheck 发送通知并检查频道是否已配置，返回通知消息ID（未发送时为 0）
+func (s *NotificationService) sendNotificationWithCheck(message, operationType string, keyboard *tgbotapi.InlineKeyboardMarkup) int {
 	// 检查是否配置了通知频道
 	if s.notificationChannelID == 0 {
 		// 未配置通知频道，向所有作者发送提醒
 		warningMsg := fmt.Sprintf("⚠️ *未配置通知频道*\n\n操作类型：%s\n\n"+
 			"请使用 /config 命令配置通知频道以接收操作通知。\n\n"+
 			"功能仍正常执行。", operationType)
-		for _, authorID := range s.authorIDs {
+		for _, authorID := range s.authorIDs() {
 			if err := s.sendMessage(authorID, warningMsg); err != nil {
 				logrus.Errorf("Failed to send channel warning to author %d: %v", authorID, err)
 			}
 		}
 		logrus.Warnf("通知频道未配置，已提醒作者")
-		return
+		return 0
 	}
 
 	// 发送到通知频道
-	if err := s.sendMessage(s.notificationChannelID, message); err != nil {
+	messageID, err := s.sendMessageWithKeyboard(s.notificationChannelID, message, keyboard)
+	if err != nil {
 		logrus.Errorf("Failed to send notification to channel: %v", err)
 		// 发送失败时也通知所有作者
 		errorMsg := fmt.Sprintf("⚠️ *通知发送失败*\n\n操作类型：%s\n错误：%s\n\n"+
 			"请检查：\n1. 机器人是否仍是频道管理员\n2. 频道ID是否正确", operationType, err.Error())
-		for _, authorID := range s.authorIDs {
+		for _, authorID := range s.authorIDs() {
 			s.sendMessage(authorID, errorMsg)
 		}
 	}
+
+	return messageID
 }
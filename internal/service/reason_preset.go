@@ -0,0 +1,103 @@
+package service
+
+import (
+	"admin-bot/internal/database"
+	"admin-bot/internal/models"
+	"admin-bot/internal/utils"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// reasonCodePattern 理由代码格式（小写字母、数字和下划线）
+var reasonCodePattern = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+// ReasonPresetService 预设理由服务
+type ReasonPresetService struct{}
+
+// NewReasonPresetService 创建预设理由服务
+func NewReasonPresetService() *ReasonPresetService {
+	return &ReasonPresetService{}
+}
+
+// EnsureDefaults 表为空时写入默认预设理由
+func (s *ReasonPresetService) EnsureDefaults() error {
+	var count int64
+	if err := database.DB.Model(&models.ReasonPreset{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	presets := make([]models.ReasonPreset, len(models.DefaultReasonPresets))
+	copy(presets, models.DefaultReasonPresets)
+	return database.DB.Create(&presets).Error
+}
+
+// GetPresets 获取所有预设理由
+func (s *ReasonPresetService) GetPresets() ([]models.ReasonPreset, error) {
+	var presets []models.ReasonPreset
+	err := database.DB.Order("sort_order ASC, id ASC").Find(&presets).Error
+	return presets, err
+}
+
+// GetPreset 通过ID获取预设理由
+func (s *ReasonPresetService) GetPreset(id int64) (*models.ReasonPreset, error) {
+	var preset models.ReasonPreset
+	err := database.DB.Where("id = ?", id).First(&preset).Error
+	if err != nil {
+		return nil, err
+	}
+	return &preset, nil
+}
+
+// MatchPreset 查找代码或显示名称与理由完全一致的预设（不区分大小写），没有时返回 nil
+func (s *ReasonPresetService) MatchPreset(reason string) *models.ReasonPreset {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil
+	}
+
+	var preset models.ReasonPreset
+	err := database.DB.Where("code = ? OR label = ?", strings.ToLower(reason), reason).First(&preset).Error
+	if err != nil {
+		return nil
+	}
+	return &preset
+}
+
+// AddPreset 添加预设理由
+func (s *ReasonPresetService) AddPreset(code, label string) error {
+	code = strings.ToLower(strings.TrimSpace(code))
+	label = utils.SafeReason(label)
+	if !reasonCodePattern.MatchString(code) {
+		return fmt.Errorf("理由代码只能包含小写字母、数字和下划线")
+	}
+	if label == "" {
+		return fmt.Errorf("显示名称不能为空")
+	}
+
+	var count int64
+	if err := database.DB.Model(&models.ReasonPreset{}).Where("code = ?", code).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("理由代码 %s 已存在", code)
+	}
+
+	// 排在最后
+	var maxOrder int
+	database.DB.Model(&models.ReasonPreset{}).Select("COALESCE(MAX(sort_order), 0)").Scan(&maxOrder)
+
+	return database.DB.Create(&models.ReasonPreset{
+		Code:      code,
+		Label:     label,
+		SortOrder: maxOrder + 1,
+	}).Error
+}
+
+// DeletePreset 删除预设理由（已保存到记录中的理由代码不受影响）
+func (s *ReasonPresetService) DeletePreset(id int64) error {
+	return database.DB.Where("id = ?", id).Delete(&models.ReasonPreset{}).Error
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"admin-bot/internal/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 记录列表的时长筛选
+const (
+	RecordKindAll       = ""     // 全部
+	RecordKindPermanent = "perm" // 仅永久
+	RecordKindTemporary = "temp" // 仅临时
+)
+
+// RecordFilter 生效中的拉黑/禁言记录列表的筛选和排序条件
+type RecordFilter struct {
+	Kind         string // 时长筛选，见 RecordKind* 常量
+	OperatorID   int64  // 操作人，0 表示不限
+	GroupID      int64  // 来源群组，0 表示不限
+	Keyword      string // 理由关键词
+	SortByExpiry bool   // 按到期时间排序（即将到期的在前，永久的在最后），默认按操作时间倒序
+}
+
+// apply 将筛选条件应用到查询上（只查询生效中且未到期的记录）
+func (f *RecordFilter) apply(query *gorm.DB) *gorm.DB {
+	query = query.Where("status = ?", models.RecordStatusActive).
+		Where("expire_at IS NULL OR expire_at > ?", time.Now())
+
+	switch f.Kind {
+	case RecordKindPermanent:
+		query = query.Where("expire_at IS NULL")
+	case RecordKindTemporary:
+		query = query.Where("expire_at IS NOT NULL")
+	}
+	if f.OperatorID != 0 {
+		query = query.Where("operator_id = ?", f.OperatorID)
+	}
+	if f.GroupID != 0 {
+		query = query.Where("group_id = ?", f.GroupID)
+	}
+	if f.Keyword != "" {
+		query = query.Where("reason LIKE ?", "%"+escapeLike(f.Keyword)+"%")
+	}
+	return query
+}
+
+// order 排序条件
+func (f *RecordFilter) order() string {
+	if f.SortByExpiry {
+		return "expire_at IS NULL, expire_at ASC, id DESC"
+	}
+	return "created_at DESC, id DESC"
+}
+
+// escapeLike 转义 LIKE 查询中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"admin-bot/internal/database"
+	"admin-bot/internal/models"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RoleService 角色权限服务
+type RoleService struct{}
+
+// NewRoleService 创建角色权限服务
+func NewRoleService() *RoleService {
+	return &RoleService{}
+}
+
+// EnsureDefaults 写入默认角色权限（只写入表中还没有任何记录的权限，新增的命令升级后也有默认配置）
+func (s *RoleService) EnsureDefaults() error {
+	var existing []string
+	if err := database.DB.Model(&models.RolePermission{}).Distinct().Pluck("permission", &existing).Error; err != nil {
+		return err
+	}
+	configured := make(map[string]bool, len(existing))
+	for _, perm := range existing {
+		configured[perm] = true
+	}
+
+	var perms []models.RolePermission
+	for _, perm := range models.DefaultRolePermissions {
+		if !configured[perm.Permission] {
+			perms = append(perms, perm)
+		}
+	}
+	if len(perms) == 0 {
+		return nil
+	}
+	return database.DB.Create(&perms).Error
+}
+
+// GetUserRole 获取用户被分配的角色，没有分配时返回空字符串
+func (s *RoleService) GetUserRole(userID int64) (string, error) {
+	var userRole models.UserRole
+	err := database.DB.Where("user_id = ?", userID).First(&userRole).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	return userRole.Role, nil
+}
+
+// SetUserRole 为用户分配角色（已有角色时覆盖）
+func (s *RoleService) SetUserRole(userID int64, role, fullName string, assignedBy int64) error {
+	if !models.IsValidRole(role) {
+		return fmt.Errorf("无效的角色：%s", role)
+	}
+	return database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "full_name", "assigned_by", "assigned_at"}),
+	}).Create(&models.UserRole{
+		UserID:     userID,
+		Role:       role,
+		FullName:   fullName,
+		AssignedBy: assignedBy,
+	}).Error
+}
+
+// RemoveUserRole 取消用户的角色分配（之后按默认角色处理）
+func (s *RoleService) RemoveUserRole(userID int64) error {
+	return database.DB.Where("user_id = ?", userID).Delete(&models.UserRole{}).Error
+}
+
+// ListUserRoles 获取所有角色分配
+func (s *RoleService) ListUserRoles() ([]models.UserRole, error) {
+	var roles []models.UserRole
+	err := database.DB.Order("role ASC, assigned_at ASC").Find(&roles).Error
+	return roles, err
+}
+
+// GetRolePermissions 获取角色的全部权限
+func (s *RoleService) GetRolePermissions(role string) ([]models.RolePermission, error) {
+	var perms []models.RolePermission
+	err := database.DB.Where("role = ?", role).Order("id ASC").Find(&perms).Error
+	return perms, err
+}
+
+// GetPermission 获取角色的某项权限，角色没有该权限时返回 nil
+func (s *RoleService) GetPermission(role, permission string) (*models.RolePermission, error) {
+	var perm models.RolePermission
+	err := database.DB.Where("role = ? AND permission = ?", role, permission).First(&perm).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &perm, nil
+}
+
+// SetPermission 授予角色某项权限并设置最长时长（秒，0 表示不限制）
+func (s *RoleService) SetPermission(role, permission string, maxDuration int) error {
+	if role == models.RoleOwner {
+		return fmt.Errorf("所有者拥有全部权限，不需要配置")
+	}
+	if !models.IsValidRole(role) {
+		return fmt.Errorf("无效的角色：%s", role)
+	}
+	if !models.IsValidPermission(permission) {
+		return fmt.Errorf("无效的权限：%s", permission)
+	}
+	return database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "role"}, {Name: "permission"}},
+		DoUpdates: clause.AssignmentColumns([]string{"max_duration", "updated_at"}),
+	}).Create(&models.RolePermission{
+		Role:        role,
+		Permission:  permission,
+		MaxDuration: maxDuration,
+	}).Error
+}
+
+// RevokePermission 收回角色的某项权限
+func (s *RoleService) RevokePermission(role, permission string) error {
+	if role == models.RoleOwner {
+		return fmt.Errorf("所有者拥有全部权限，不需要配置")
+	}
+	if !models.IsValidRole(role) {
+		return fmt.Errorf("无效的角色：%s", role)
+	}
+	if !models.IsValidPermission(permission) {
+		return fmt.Errorf("无效的权限：%s", permission)
+	}
+	return database.DB.Where("role = ? AND permission = ?", role, permission).
+		Delete(&models.RolePermission{}).Error
+}
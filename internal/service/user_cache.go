@@ -83,3 +83,14 @@ func (s *UserCacheService) GetUserByID(userID int64) (*models.UserCache, error)
 
 	return &userCache, nil
 }
+
+// GetUserNames 获取用户使用过的所有用户名和名字（最近使用的在前）
+func (s *UserCacheService) GetUserNames(userID int64) ([]models.UserCache, error) {
+	db := database.GetDB()
+
+	var names []models.UserCache
+	err := db.Where("user_id = ?", userID).
+		Order("updated_at DESC").
+		Find(&names).Error
+	return names, err
+}
@@ -45,29 +45,48 @@ func EscapeMarkdown(text string) string {
 	return replacer.Replace(text)
 }
 
+// formatCaseLine 格式化案件编号行（0 表示没有案件编号）
+func formatCaseLine(caseID int64) string {
+	if caseID == 0 {
+		return ""
+	}
+	return fmt.Sprintf("*案件*：`#%d`\n", caseID)
+}
+
 // FormatBanNotification 格式化拉黑通知
-func FormatBanNotification(groupName, groupUsername, userName string, userID int64, duration, reason, operatorName string, operatorID int64, timestamp string) string {
+func FormatBanNotification(groupName, groupUsername, userName string, userID int64, duration, reason, operatorName string, operatorID int64, timestamp string, local bool, evidenceLink string, caseID int64) string {
 	var sb strings.Builder
 	sb.WriteString("🚫 *拉黑通知*\n\n")
+	sb.WriteString(formatCaseLine(caseID))
 	sb.WriteString(fmt.Sprintf("*群组*：%s\n", FormatGroupName(groupName, groupUsername)))
 	sb.WriteString(fmt.Sprintf("*用户*：%s\n", FormatUserMention(userID, userName)))
 	sb.WriteString(fmt.Sprintf("*ID*：`%d`\n", userID))
+	if local {
+		sb.WriteString("*范围*：仅本群\n")
+	}
 	sb.WriteString(fmt.Sprintf("*时长*：%s\n", EscapeMarkdown(duration)))
 	if reason != "" {
 		sb.WriteString(fmt.Sprintf("*理由*：%s\n", EscapeMarkdown(reason)))
 	}
+	if evidenceLink != "" {
+		sb.WriteString(fmt.Sprintf("*证据*：[查看消息](%s)\n", evidenceLink))
+	}
 	sb.WriteString(fmt.Sprintf("*操作时间*：`%s`\n", timestamp))
 	sb.WriteString(fmt.Sprintf("*操作人*：%s", FormatUserMention(operatorID, operatorName)))
 	return sb.String()
 }
 
 // FormatUnbanNotification 格式化解除拉黑通知
-func FormatUnbanNotification(groupName, groupUsername, userName string, userID int64, reason, operatorName string, operatorID int64, timestamp string) string {
+func FormatUnbanNotification(groupName, groupUsername, userName string, userID int64, reason, operatorName string, operatorID int64, timestamp string, local bool, caseID int64) string {
 	var sb strings.Builder
 	sb.WriteString("🔓 *解除拉黑通知*\n\n")
+	sb.WriteString(formatCaseLine(caseID))
 	sb.WriteString(fmt.Sprintf("*群组*：%s\n", FormatGroupName(groupName, groupUsername)))
 	sb.WriteString(fmt.Sprintf("*用户*：%s\n", FormatUserMention(userID, userName)))
 	sb.WriteString(fmt.Sprintf("*ID*：`%d`\n", userID))
+	if local {
+		sb.WriteString("*范围*：仅本群\n")
+	}
 	if reason != "" {
 		sb.WriteString(fmt.Sprintf("*理由*：%s\n", EscapeMarkdown(reason)))
 	}
@@ -77,28 +96,40 @@ func FormatUnbanNotification(groupName, groupUsername, userName string, userID i
 }
 
 // FormatMuteNotification 格式化禁言通知
-func FormatMuteNotification(groupName, groupUsername, userName string, userID int64, duration, reason, operatorName string, operatorID int64, timestamp string) string {
+func FormatMuteNotification(groupName, groupUsername, userName string, userID int64, duration, modeName, reason, operatorName string, operatorID int64, timestamp string, local bool, evidenceLink string, caseID int64) string {
 	var sb strings.Builder
 	sb.WriteString("🔇 *禁言通知*\n\n")
+	sb.WriteString(formatCaseLine(caseID))
 	sb.WriteString(fmt.Sprintf("*群组*：%s\n", FormatGroupName(groupName, groupUsername)))
 	sb.WriteString(fmt.Sprintf("*用户*：%s\n", FormatUserMention(userID, userName)))
 	sb.WriteString(fmt.Sprintf("*ID*：`%d`\n", userID))
+	if local {
+		sb.WriteString("*范围*：仅本群\n")
+	}
 	sb.WriteString(fmt.Sprintf("*时长*：%s\n", EscapeMarkdown(duration)))
+	sb.WriteString(fmt.Sprintf("*模式*：%s\n", EscapeMarkdown(modeName)))
 	if reason != "" {
 		sb.WriteString(fmt.Sprintf("*理由*：%s\n", EscapeMarkdown(reason)))
 	}
+	if evidenceLink != "" {
+		sb.WriteString(fmt.Sprintf("*证据*：[查看消息](%s)\n", evidenceLink))
+	}
 	sb.WriteString(fmt.Sprintf("*操作时间*：`%s`\n", timestamp))
 	sb.WriteString(fmt.Sprintf("*操作人*：%s", FormatUserMention(operatorID, operatorName)))
 	return sb.String()
 }
 
 // FormatUnmuteNotification 格式化解除禁言通知
-func FormatUnmuteNotification(groupName, groupUsername, userName string, userID int64, reason, operatorName string, operatorID int64, timestamp string) string {
+func FormatUnmuteNotification(groupName, groupUsername, userName string, userID int64, reason, operatorName string, operatorID int64, timestamp string, local bool, caseID int64) string {
 	var sb strings.Builder
 	sb.WriteString("🔈 *解除禁言通知*\n\n")
+	sb.WriteString(formatCaseLine(caseID))
 	sb.WriteString(fmt.Sprintf("*群组*：%s\n", FormatGroupName(groupName, groupUsername)))
 	sb.WriteString(fmt.Sprintf("*用户*：%s\n", FormatUserMention(userID, userName)))
 	sb.WriteString(fmt.Sprintf("*ID*：`%d`\n", userID))
+	if local {
+		sb.WriteString("*范围*：仅本群\n")
+	}
 	if reason != "" {
 		sb.WriteString(fmt.Sprintf("*理由*：%s\n", EscapeMarkdown(reason)))
 	}
@@ -108,17 +139,53 @@ func FormatUnmuteNotification(groupName, groupUsername, userName string, userID
 }
 
 // FormatKickNotification 格式化踢出通知
-func FormatKickNotification(groupName, groupUsername, userName string, userID int64, operatorName string, operatorID int64, timestamp string) string {
+func FormatKickNotification(groupName, groupUsername, userName string, userID int64, operatorName string, operatorID int64, timestamp string, local bool, evidenceLink string, caseID int64) string {
 	var sb strings.Builder
 	sb.WriteString("👢 *踢出通知*\n\n")
+	sb.WriteString(formatCaseLine(caseID))
 	sb.WriteString(fmt.Sprintf("*群组*：%s\n", FormatGroupName(groupName, groupUsername)))
 	sb.WriteString(fmt.Sprintf("*用户*：%s\n", FormatUserMention(userID, userName)))
 	sb.WriteString(fmt.Sprintf("*ID*：`%d`\n", userID))
+	if local {
+		sb.WriteString("*范围*：仅本群\n")
+	}
+	if evidenceLink != "" {
+		sb.WriteString(fmt.Sprintf("*证据*：[查看消息](%s)\n", evidenceLink))
+	}
 	sb.WriteString(fmt.Sprintf("*操作时间*：`%s`\n", timestamp))
 	sb.WriteString(fmt.Sprintf("*操作人*：%s", FormatUserMention(operatorID, operatorName)))
 	return sb.String()
 }
 
+// FormatWarnNotification 格式化警告通知
+func FormatWarnNotification(groupName, groupUsername, userName string, userID int64, reason string, warnCount int64, operatorName string, operatorID int64, timestamp string, evidenceLink string, caseID int64) string {
+	var sb strings.Builder
+	sb.WriteString("⚠️ *警告通知*\n\n")
+	sb.WriteString(formatCaseLine(caseID))
+	sb.WriteString(fmt.Sprintf("*群组*：%s\n", FormatGroupName(groupName, groupUsername)))
+	sb.WriteString(fmt.Sprintf("*用户*：%s\n", FormatUserMention(userID, userName)))
+	sb.WriteString(fmt.Sprintf("*ID*：`%d`\n", userID))
+	if reason != "" {
+		sb.WriteString(fmt.Sprintf("*理由*：%s\n", EscapeMarkdown(reason)))
+	}
+	sb.WriteString(fmt.Sprintf("*累计警告*：%d 次\n", warnCount))
+	if evidenceLink != "" {
+		sb.WriteString(fmt.Sprintf("*证据*：[查看消息](%s)\n", evidenceLink))
+	}
+	sb.WriteString(fmt.Sprintf("*操作时间*：`%s`\n", timestamp))
+	sb.WriteString(fmt.Sprintf("*操作人*：%s", FormatUserMention(operatorID, operatorName)))
+	return sb.String()
+}
+
+// FormatMessageLink 生成群组消息链接（公开群组使用用户名，私密超级群组使用 /c/ 格式）
+func FormatMessageLink(chatID int64, chatUsername string, messageID int) string {
+	if chatUsername != "" {
+		return fmt.Sprintf("https://t.me/%s/%d", chatUsername, messageID)
+	}
+	id := strings.TrimPrefix(fmt.Sprintf("%d", chatID), "-100")
+	return fmt.Sprintf("https://t.me/c/%s/%d", id, messageID)
+}
+
 // FormatErrorNotification 格式化错误通知
 func FormatErrorNotification(groupName, operationType, userName string, userID int64, errorMsg, operatorName, timestamp string) string {
 	var sb strings.Builder
@@ -2,71 +2,147 @@ package utils
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// ParseDuration 解析时间字符串，如 "10s", "5m", "2h", "1d"
+// Telegram 限制时长的有效范围（超出范围的 until_date 会被视为永久）
+const (
+	MinDurationSeconds = 30
+	MaxDurationSeconds = 366 * 86400
+)
+
+// durationUnits 时长单位对应的秒数（mo 为月，按 30 天计算）
+var durationUnits = map[string]int{
+	"s":  1,
+	"m":  60,
+	"h":  3600,
+	"d":  86400,
+	"w":  7 * 86400,
+	"mo": 30 * 86400,
+}
+
+// durationPattern 完整时长格式，如 "10m"、"1d12h30m"、"2w"、"1mo"
+var durationPattern = regexp.MustCompile(`^(\d+(mo|[smhdw]))+$`)
+
+// durationPartPattern 时长中的单个部分
+var durationPartPattern = regexp.MustCompile(`(\d+)(mo|[smhdw])`)
+
+// permanentKeywords 表示永久的关键字
+var permanentKeywords = map[string]bool{
+	"perm":      true,
+	"permanent": true,
+	"forever":   true,
+	"永久":        true,
+}
+
+// IsDurationString 判断是否为时长字符串（支持组合单位）
+func IsDurationString(s string) bool {
+	return durationPattern.MatchString(strings.ToLower(s))
+}
+
+// IsPermanentKeyword 判断是否为表示永久的关键字（perm/永久）
+func IsPermanentKeyword(s string) bool {
+	return permanentKeywords[strings.ToLower(s)]
+}
+
+// ParseDuration 解析时间字符串，如 "10s", "5m", "2h", "1d", "1w", "1mo"，以及组合形式 "1d12h30m"
 func ParseDuration(duration string) (int, error) {
 	if duration == "" {
 		return 0, nil
 	}
 
-	duration = strings.TrimSpace(duration)
-	if len(duration) < 2 {
-		return 0, fmt.Errorf("invalid duration format: %s", duration)
+	duration = strings.ToLower(strings.TrimSpace(duration))
+	if !durationPattern.MatchString(duration) {
+		return 0, fmt.Errorf("invalid duration format: %s (use s/m/h/d/w/mo, e.g. 1d12h)", duration)
 	}
 
-	// 提取数字和单位
-	unit := duration[len(duration)-1:]
-	valueStr := duration[:len(duration)-1]
-
-	value, err := strconv.Atoi(valueStr)
-	if err != nil {
-		return 0, fmt.Errorf("invalid duration value: %s", valueStr)
+	// 逐段累加为秒（相乘前检查上限，避免溢出后绕过检查）
+	const limit = MaxDurationSeconds * 10
+	seconds := 0
+	for _, part := range durationPartPattern.FindAllStringSubmatch(duration, -1) {
+		value, err := strconv.Atoi(part[1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration value: %s", part[1])
+		}
+		unit := durationUnits[part[2]]
+		if value > (limit-seconds)/unit {
+			return 0, fmt.Errorf("duration too long: %s", duration)
+		}
+		seconds += value * unit
 	}
 
-	if value < 0 {
-		return 0, fmt.Errorf("duration value cannot be negative")
+	return seconds, nil
+}
+
+// ParseUntil 解析绝对结束时间（"2006-01-02 15:04" 或 "2006-01-02"），返回距现在的秒数
+func ParseUntil(value string, loc *time.Location) (int, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"} {
+		t, err := time.ParseInLocation(layout, value, loc)
+		if err != nil {
+			continue
+		}
+		seconds := int(time.Until(t).Seconds())
+		if seconds <= 0 {
+			return 0, fmt.Errorf("end time is in the past: %s", value)
+		}
+		return seconds, nil
 	}
+	return 0, fmt.Errorf("invalid end time: %s (use 2006-01-02 15:04)", value)
+}
 
-	// 转换为秒
-	var seconds int
-	switch strings.ToLower(unit) {
-	case "s":
-		seconds = value
-	case "m":
-		seconds = value * 60
-	case "h":
-		seconds = value * 3600
-	case "d":
-		seconds = value * 86400
-	default:
-		return 0, fmt.Errorf("invalid duration unit: %s (use s/m/h/d)", unit)
+// LoadLocation 加载时区，失败时使用本地时区
+func LoadLocation(name string) *time.Location {
+	if name == "" {
+		return time.Local
 	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
 
-	return seconds, nil
+// IsValidDuration 检查时长是否在 Telegram 支持的范围内（0 表示永久）
+func IsValidDuration(seconds int) bool {
+	return seconds == 0 || (seconds >= MinDurationSeconds && seconds <= MaxDurationSeconds)
 }
 
-// FormatDuration 格式化秒数为可读字符串
+// UntilDate 将时长转换为 Telegram 的 until_date（Unix 时间戳，0 表示永久）
+func UntilDate(seconds int) int64 {
+	if seconds == 0 {
+		return 0
+	}
+	return time.Now().Add(time.Duration(seconds) * time.Second).Unix()
+}
+
+// FormatDuration 格式化秒数为可读字符串，如 "1 天 12 小时"
 func FormatDuration(seconds int) string {
 	if seconds == 0 {
 		return "永久"
 	}
 
-	if seconds < 60 {
-		return fmt.Sprintf("%d 秒", seconds)
-	} else if seconds < 3600 {
-		minutes := seconds / 60
-		return fmt.Sprintf("%d 分钟", minutes)
-	} else if seconds < 86400 {
-		hours := seconds / 3600
-		return fmt.Sprintf("%d 小时", hours)
-	} else {
-		days := seconds / 86400
-		return fmt.Sprintf("%d 天", days)
+	units := []struct {
+		seconds int
+		name    string
+	}{
+		{86400, "天"},
+		{3600, "小时"},
+		{60, "分钟"},
+		{1, "秒"},
+	}
+
+	parts := make([]string, 0, len(units))
+	for _, unit := range units {
+		if seconds >= unit.seconds {
+			parts = append(parts, fmt.Sprintf("%d %s", seconds/unit.seconds, unit.name))
+			seconds %= unit.seconds
+		}
 	}
+	return strings.Join(parts, " ")
 }
 
 // FormatRemainingTime 格式化剩余时间
@@ -77,6 +153,10 @@ func FormatRemainingTime(expireAt time.Time) string {
 	}
 
 	seconds := int(remaining.Seconds())
+	if seconds >= 60 {
+		// 剩余时间精确到分钟即可
+		seconds -= seconds % 60
+	}
 	return FormatDuration(seconds)
 }
 
@@ -94,3 +174,20 @@ func FormatTimestamp(t time.Time) string {
 	return t.Format("2006-01-02 15:04:05")
 }
 
+
+// ExtendExpireTime 在当前到期时间上增加 seconds 秒（负数为缩短），已到期时从现在开始计算
+func ExtendExpireTime(expireAt time.Time, seconds int) time.Time {
+	base := expireAt
+	if now := time.Now(); base.Before(now) {
+		base = now
+	}
+	return base.Add(time.Duration(seconds) * time.Second)
+}
+
+// FormatExpireAt 格式化到期时间（空表示永久）
+func FormatExpireAt(expireAt *time.Time) string {
+	if expireAt == nil {
+		return "永久"
+	}
+	return FormatTimestamp(*expireAt)
+}
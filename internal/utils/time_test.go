@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{name: "空字符串为永久", input: "", want: 0},
+		{name: "秒", input: "30s", want: 30},
+		{name: "分钟", input: "10m", want: 600},
+		{name: "小时", input: "2h", want: 7200},
+		{name: "天", input: "1d", want: 86400},
+		{name: "周", input: "2w", want: 14 * 86400},
+		{name: "月按 30 天计算", input: "1mo", want: 30 * 86400},
+		{name: "组合单位", input: "1d12h30m", want: 86400 + 12*3600 + 30*60},
+		{name: "大写和首尾空白", input: " 1D ", want: 86400},
+		{name: "上限以内", input: "3660d", want: MaxDurationSeconds * 10},
+		{name: "缺少单位", input: "10", wantErr: true},
+		{name: "未知单位", input: "1y", wantErr: true},
+		{name: "单位在前", input: "d1", wantErr: true},
+		{name: "超过上限", input: "3661d", wantErr: true},
+		{name: "组合后超过上限", input: "3660d1s", wantErr: true},
+		{name: "相乘溢出", input: "4000000000000000mo", wantErr: true},
+		{name: "数值溢出", input: "9223372036854775807s", wantErr: true},
+		{name: "数值超出 int", input: "99999999999999999999s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDuration(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseDuration(%q) = %d，期望返回错误", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDuration(%q) 返回错误: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDuration(%q) = %d，期望 %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUntil(t *testing.T) {
+	loc := time.UTC
+	future := time.Now().In(loc).AddDate(0, 0, 10)
+
+	tests := []struct {
+		name    string
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "只有日期", input: future.Format("2006-01-02"),
+			want: time.Date(future.Year(), future.Month(), future.Day(), 0, 0, 0, 0, loc)},
+		{name: "日期和时刻", input: future.Format("2006-01-02") + " 18:00",
+			want: time.Date(future.Year(), future.Month(), future.Day(), 18, 0, 0, 0, loc)},
+		{name: "T 分隔", input: future.Format("2006-01-02") + "T18:00",
+			want: time.Date(future.Year(), future.Month(), future.Day(), 18, 0, 0, 0, loc)},
+		{name: "过去的时间", input: "2000-01-01", wantErr: true},
+		{name: "格式错误", input: "tomorrow", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseUntil(tt.input, loc)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseUntil(%q) = %d，期望返回错误", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseUntil(%q) 返回错误: %v", tt.input, err)
+			}
+			want := int(time.Until(tt.want).Seconds())
+			if diff := want - got; diff < -2 || diff > 2 {
+				t.Errorf("ParseUntil(%q) = %d，期望约 %d", tt.input, got, want)
+			}
+		})
+	}
+}
+
+func TestIsValidDuration(t *testing.T) {
+	tests := []struct {
+		seconds int
+		want    bool
+	}{
+		{0, true},
+		{29, false},
+		{MinDurationSeconds, true},
+		{MaxDurationSeconds, true},
+		{MaxDurationSeconds + 1, false},
+	}
+
+	for _, tt := range tests {
+		if got := IsValidDuration(tt.seconds); got != tt.want {
+			t.Errorf("IsValidDuration(%d) = %v，期望 %v", tt.seconds, got, tt.want)
+		}
+	}
+}